@@ -0,0 +1,160 @@
+package irpc
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// AccessRecord describes one served call.
+type AccessRecord struct {
+	Time          time.Time     `json:"time"`
+	Key           string        `json:"key"`
+	Caller        string        `json:"caller,omitempty"`
+	CorrelationID string        `json:"correlation_id,omitempty"`
+	Code          string        `json:"code"`
+	Error         string        `json:"error,omitempty"`
+	Duration      time.Duration `json:"duration_ns"`
+	// RequestSize and ResponseSize are the sizes in bytes of values that
+	// have one: strings, byte slices and types with a Size() int method,
+	// such as most protobuf messages. They are -1 otherwise.
+	RequestSize  int `json:"request_size"`
+	ResponseSize int `json:"response_size"`
+	// Owner and Contact are those of the ServiceInfo of Key, for failed
+	// calls only, so that on-call engineers know where to route them.
+	Owner   string `json:"owner,omitempty"`
+	Contact string `json:"contact,omitempty"`
+}
+
+// AccessLogger receives a record for every call served by AccessLog.
+// LogAccess runs on the caller's goroutine and must be safe for concurrent
+// use.
+type AccessLogger interface {
+	LogAccess(ctx context.Context, rec AccessRecord)
+}
+
+// AccessLoggerFunc adapts a function to an AccessLogger.
+type AccessLoggerFunc func(ctx context.Context, rec AccessRecord)
+
+func (f AccessLoggerFunc) LogAccess(ctx context.Context, rec AccessRecord) {
+	f(ctx, rec)
+}
+
+// AccessLog returns middleware recording every call it wraps with l:
+//
+//	registry.Use(irpc.AccessLog(irpc.NewJSONAccessLogger(os.Stdout)))
+func AccessLog(l AccessLogger) Middleware {
+	return func(key string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, req any) (any, error) {
+			start := time.Now()
+			res, err := next(ctx, req)
+			l.LogAccess(ctx, newAccessRecord(ctx, key, start, time.Since(start), req, res, err))
+			return res, err
+		}
+	}
+}
+
+func newAccessRecord(ctx context.Context, key string, start time.Time, elapsed time.Duration, req, res any, err error) AccessRecord {
+	rec := AccessRecord{
+		Time:          start,
+		Key:           key,
+		Caller:        CallerFromContext(ctx),
+		CorrelationID: CorrelationIDFromContext(ctx),
+		Code:          CodeOf(err).String(),
+		Duration:      elapsed,
+		RequestSize:   sizeOf(req),
+		ResponseSize:  sizeOf(res),
+	}
+	if err != nil {
+		rec.Error = err.Error()
+		owner := ServiceInfoFromContext(ctx)
+		if owner == (ServiceInfo{}) {
+			owner, _ = OwnerOf(err)
+		}
+		rec.Owner, rec.Contact = owner.Owner, owner.Contact
+	}
+	return rec
+}
+
+func sizeOf(v any) int {
+	switch v := v.(type) {
+	case nil:
+		return 0
+	case string:
+		return len(v)
+	case []byte:
+		return len(v)
+	case interface{ Size() int }:
+		return v.Size()
+	}
+	return -1
+}
+
+type jsonAccessLogger struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONAccessLogger returns an AccessLogger writing one JSON object per
+// call to w, ready to be shipped by a log collector.
+func NewJSONAccessLogger(w io.Writer) AccessLogger {
+	return &jsonAccessLogger{enc: json.NewEncoder(w)}
+}
+
+func (l *jsonAccessLogger) LogAccess(_ context.Context, rec AccessRecord) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_ = l.enc.Encode(rec)
+}
+
+// NewSlogAccessLogger returns an AccessLogger logging calls to logger at
+// the level of the record.
+func NewSlogAccessLogger(logger *slog.Logger) AccessLogger {
+	return AccessLoggerFunc(func(ctx context.Context, rec AccessRecord) {
+		logger.LogAttrs(ctx, rec.Level(), "irpc: call", rec.Attrs()...)
+	})
+}
+
+// Level returns the level rec should be logged at: debug for successful
+// calls, error for failures of the handler itself (Unknown, Internal,
+// DataLoss and Unimplemented) and warn for the others.
+func (rec AccessRecord) Level() slog.Level {
+	switch rec.Code {
+	case OK.String():
+		return slog.LevelDebug
+	case Unknown.String(), Internal.String(), DataLoss.String(), Unimplemented.String():
+		return slog.LevelError
+	}
+	return slog.LevelWarn
+}
+
+// Attrs returns the fields of rec as slog attributes, for AccessLoggers
+// forwarding to structured loggers.
+func (rec AccessRecord) Attrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("key", rec.Key),
+		slog.String("code", rec.Code),
+		slog.Duration("duration", rec.Duration),
+		slog.Int("request_size", rec.RequestSize),
+		slog.Int("response_size", rec.ResponseSize),
+	}
+	if rec.Caller != "" {
+		attrs = append(attrs, slog.String("caller", rec.Caller))
+	}
+	if rec.CorrelationID != "" {
+		attrs = append(attrs, slog.String("correlation_id", rec.CorrelationID))
+	}
+	if rec.Error != "" {
+		attrs = append(attrs, slog.String("error", rec.Error))
+	}
+	if rec.Owner != "" {
+		attrs = append(attrs, slog.String("owner", rec.Owner))
+	}
+	if rec.Contact != "" {
+		attrs = append(attrs, slog.String("contact", rec.Contact))
+	}
+	return attrs
+}
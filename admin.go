@@ -0,0 +1,231 @@
+package irpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// AdminUserHeader is the request header naming the user of an
+// AdminHandler request in SettingsHistory. The authenticating mux or
+// proxy in front of the handler is expected to set it.
+const AdminUserHeader = "X-Admin-User"
+
+// AdminHandler returns an http.Handler for runtime administration of the
+// registry. It must only be mounted on an internal, authenticated mux.
+//
+//	GET  /status?service=S               run the health checks, of S only if given, as CheckHealth does
+//	GET  /disabled                       list disabled keys and patterns
+//	POST /disable?key=K&message=M        disable a key
+//	POST /disable?pattern=P&message=M    disable every key matching P
+//	POST /enable?key=K                   re-enable a key
+//	POST /enable?pattern=P               remove a disable pattern
+//	GET  /maintenance                    list services in maintenance mode
+//	POST /maintenance?service=S&message=M  put a service into maintenance mode
+//	DELETE /maintenance?service=S        take a service out of maintenance mode
+//	GET  /limits                         show rate limits, bulkheads and the concurrency limit
+//	POST /limits/rate?pattern=P&rate=R&burst=B  set a rate limit (rate=0&burst=0 removes it)
+//	POST /limits/bulkhead?service=S&max=N       set a bulkhead (max=0 removes it)
+//	POST /limits/concurrency?limit=N&queue=Q    set the registry-wide concurrency limit
+//	GET  /settings                       show the settings in effect
+//	PUT  /settings                       replace them with the Settings in the JSON body
+//	GET  /settings/history               list the recent changes to the settings
+//	GET  /profile?pattern=P&seconds=N    CPU profile labelling keys matching P
+//	GET  /subscriptions                  list active subscriptions
+//	DELETE /subscriptions?id=ID          close a subscription
+func (r *Registry) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /status", func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, http.StatusOK, r.CheckHealth(req.Context(), req.URL.Query()["service"]...))
+	})
+
+	mux.HandleFunc("GET /disabled", func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"keys":     r.DisabledKeys(),
+			"patterns": r.DisabledPatterns(),
+		})
+	})
+
+	mux.HandleFunc("POST /disable", func(w http.ResponseWriter, req *http.Request) {
+		q := req.URL.Query()
+		msg := q.Get("message")
+
+		switch {
+		case q.Get("key") != "":
+			r.Disable(q.Get("key"), msg)
+		case q.Get("pattern") != "":
+			if err := r.DisableMatching(q.Get("pattern"), msg); err != nil {
+				r.writeError(w, "", http.StatusBadRequest, err)
+				return
+			}
+		default:
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "key or pattern is required"})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("POST /enable", func(w http.ResponseWriter, req *http.Request) {
+		q := req.URL.Query()
+
+		switch {
+		case q.Get("key") != "":
+			r.Enable(q.Get("key"))
+		case q.Get("pattern") != "":
+			r.EnableMatching(q.Get("pattern"))
+		default:
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "key or pattern is required"})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("GET /maintenance", func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"services": r.MaintenanceServices()})
+	})
+
+	mux.HandleFunc("POST /maintenance", func(w http.ResponseWriter, req *http.Request) {
+		q := req.URL.Query()
+		if q.Get("service") == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "service is required"})
+			return
+		}
+		r.SetMaintenance(q.Get("service"), UnavailableResponder(q.Get("message")))
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("DELETE /maintenance", func(w http.ResponseWriter, req *http.Request) {
+		q := req.URL.Query()
+		if q.Get("service") == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "service is required"})
+			return
+		}
+		r.ClearMaintenance(q.Get("service"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("GET /limits", func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, http.StatusOK, r.Limits())
+	})
+
+	mux.HandleFunc("POST /limits/rate", func(w http.ResponseWriter, req *http.Request) {
+		q := req.URL.Query()
+		rate, err1 := strconv.ParseFloat(q.Get("rate"), 64)
+		burst, err2 := strconv.Atoi(q.Get("burst"))
+		if q.Get("pattern") == "" || err1 != nil || err2 != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "pattern, rate and burst are required"})
+			return
+		}
+		if err := r.SetRateLimit(q.Get("pattern"), RateLimit{Rate: rate, Burst: burst}); err != nil {
+			r.writeError(w, "", http.StatusBadRequest, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("POST /limits/bulkhead", func(w http.ResponseWriter, req *http.Request) {
+		q := req.URL.Query()
+		max, err := strconv.Atoi(q.Get("max"))
+		if q.Get("service") == "" || err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "service and max are required"})
+			return
+		}
+		r.SetBulkhead(q.Get("service"), max)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("POST /limits/concurrency", func(w http.ResponseWriter, req *http.Request) {
+		q := req.URL.Query()
+		limit, err1 := strconv.Atoi(q.Get("limit"))
+		queue, err2 := strconv.Atoi(q.Get("queue"))
+		if err1 != nil || err2 != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "limit and queue are required"})
+			return
+		}
+		r.SetMaxConcurrency(limit, queue)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("GET /settings", func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, http.StatusOK, r.Settings())
+	})
+
+	mux.HandleFunc("PUT /settings", func(w http.ResponseWriter, req *http.Request) {
+		data, err := io.ReadAll(req.Body)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		s, err := decodeSettings(data, nil)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		by := req.Header.Get(AdminUserHeader)
+		if by == "" {
+			by = req.RemoteAddr
+		}
+		if err := r.UpdateSettings(by, func(cur *Settings) { *cur = s }); err != nil {
+			r.writeError(w, "", http.StatusBadRequest, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	mux.HandleFunc("GET /settings/history", func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, http.StatusOK, r.SettingsHistory())
+	})
+
+	mux.HandleFunc("GET /profile", func(w http.ResponseWriter, req *http.Request) {
+		q := req.URL.Query()
+		seconds, err := strconv.Atoi(q.Get("seconds"))
+		if q.Get("pattern") == "" || err != nil || seconds <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "pattern and seconds are required"})
+			return
+		}
+		var buf bytes.Buffer
+		if err := r.ProfileCPU(req.Context(), q.Get("pattern"), time.Duration(seconds)*time.Second, &buf); err != nil {
+			r.writeError(w, "", http.StatusConflict, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", `attachment; filename="irpc-cpu.pprof"`)
+		_, _ = w.Write(buf.Bytes())
+	})
+
+	mux.HandleFunc("GET /subscriptions", func(w http.ResponseWriter, req *http.Request) {
+		writeJSON(w, http.StatusOK, r.Subscriptions())
+	})
+
+	mux.HandleFunc("DELETE /subscriptions", func(w http.ResponseWriter, req *http.Request) {
+		if !r.CloseSubscription(req.URL.Query().Get("id")) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "no such subscription"})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return mux
+}
+
+// writeError writes err as the JSON error of a request about key, with the
+// status HTTPStatus maps it to if the registry produced it and with status
+// otherwise.
+func (r *Registry) writeError(w http.ResponseWriter, key string, status int, err error) {
+	var e *Error
+	if errors.As(err, &e) {
+		status = r.HTTPStatus(key, err)
+	}
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,119 @@
+package irpc
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+// Aggregator combines the results of all implementations of a key into the
+// result of a single Call. results delivers each implementation's Result as
+// it completes and is closed after n results. Returning before draining it
+// cancels the context of the calls still running.
+type Aggregator func(ctx context.Context, n int, results <-chan Result) (any, error)
+
+// FirstSuccess returns an Aggregator answering with the first successful
+// result. If every implementation fails, the last error is returned.
+func FirstSuccess() Aggregator {
+	return func(ctx context.Context, n int, results <-chan Result) (any, error) {
+		var err error
+		for res := range results {
+			if res.Err == nil {
+				return res.Res, nil
+			}
+			err = res.Err
+		}
+		return nil, err
+	}
+}
+
+// Majority returns an Aggregator answering with the first result that more
+// than half of the implementations agree on. equal compares two results; a
+// nil equal uses reflect.DeepEqual. Failed results never form a majority.
+func Majority(equal func(a, b any) bool) Aggregator {
+	if equal == nil {
+		equal = reflect.DeepEqual
+	}
+	return func(ctx context.Context, n int, results <-chan Result) (any, error) {
+		type vote struct {
+			res   any
+			count int
+		}
+		var votes []vote
+		var lastErr error
+
+		for res := range results {
+			if res.Err != nil {
+				lastErr = res.Err
+				continue
+			}
+
+			i := 0
+			for ; i < len(votes); i++ {
+				if equal(votes[i].res, res.Res) {
+					break
+				}
+			}
+			if i == len(votes) {
+				votes = append(votes, vote{res: res.Res})
+			}
+			votes[i].count++
+
+			if votes[i].count*2 > n {
+				return votes[i].res, nil
+			}
+		}
+
+		return nil, &Error{Code: Aborted, Message: "no majority among implementations", Err: lastErr}
+	}
+}
+
+// Reduce returns an Aggregator that waits for every implementation and lets
+// fn combine their results, in completion order.
+func Reduce(fn func(results []Result) (any, error)) Aggregator {
+	return func(ctx context.Context, n int, results <-chan Result) (any, error) {
+		all := make([]Result, 0, n)
+		for res := range results {
+			all = append(all, res)
+		}
+		return fn(all)
+	}
+}
+
+// SetAggregator makes every Call of key fan out to all its implementations
+// and answer with the result combined by agg, e.g. for redundancy across
+// alternative data sources behind one contract. A nil agg restores
+// single-implementation dispatch.
+func (r *Registry) SetAggregator(key string, agg Aggregator) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.ensureEntry(key).aggregate = agg
+}
+
+func (r *Registry) callAggregate(ctx context.Context, d *dispatch, calls Chain, req any) (any, error) {
+	shared := d.share(ctx)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan Result, len(d.impls))
+	var wg sync.WaitGroup
+	for _, im := range d.impls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := r.invoke(ctx, shared, im, calls, req)
+			results <- Result{Impl: im.name, Res: res, Err: err}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	res, err := d.aggregate(ctx, len(d.impls), results)
+	if d.streamRes {
+		discardResults(results, len(d.impls))
+	}
+	return res, err
+}
@@ -0,0 +1,200 @@
+package irpc
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AlertRule sets the error rate and latency thresholds of keys matching
+// Pattern (path.Match syntax), evaluated over a sliding Window.
+type AlertRule struct {
+	Pattern string
+	// Window is the sliding window the rates are computed over. Zero means
+	// one minute.
+	Window time.Duration
+	// MinCalls is the number of calls in the window below which the key is
+	// not evaluated, so a single failure does not fire an alert.
+	MinCalls int
+	// MaxErrorRate is the highest acceptable fraction of failed calls, in
+	// (0, 1]. Zero disables the check.
+	MaxErrorRate float64
+	// MaxLatency is the highest acceptable mean latency. Zero disables the
+	// check.
+	MaxLatency time.Duration
+}
+
+// Alert reports that a key crossed the thresholds of a rule, or went back
+// under them.
+type Alert struct {
+	Key  string
+	Rule AlertRule
+	// Firing is true when the thresholds were crossed and false when the
+	// key recovered.
+	Firing    bool
+	Reason    string
+	At        time.Time
+	Calls     int
+	ErrorRate float64
+	Latency   time.Duration
+}
+
+type alertRule struct {
+	rule AlertRule
+	fn   func(Alert)
+
+	mu     sync.Mutex
+	states map[string]*alertState
+}
+
+type alertState struct {
+	start          time.Time
+	calls, prev    int
+	errors, prevE  int
+	latency, prevL time.Duration
+	firing         bool
+	reason         string
+}
+
+func (s *alertState) advance(now time.Time, window time.Duration) {
+	switch elapsed := now.Sub(s.start); {
+	case elapsed >= 2*window:
+		s.start, s.calls, s.prev, s.errors, s.prevE, s.latency, s.prevL = now, 0, 0, 0, 0, 0, 0
+	case elapsed >= window:
+		s.start = s.start.Add(window)
+		s.prev, s.prevE, s.prevL = s.calls, s.errors, s.latency
+		s.calls, s.errors, s.latency = 0, 0, 0
+	}
+}
+
+// OnAlert calls fn when a key matching rule.Pattern crosses the rule's
+// thresholds, and again when it goes back under them. Keys are evaluated
+// on every call, so fn runs synchronously on the goroutine of the call
+// that changed the state, and a key that stops being called keeps its
+// state.
+//
+//	registry.OnAlert(irpc.AlertRule{Pattern: "Billing.*", MinCalls: 20, MaxErrorRate: 0.05},
+//		func(a irpc.Alert) { pager.Notify(a.Key, a.Reason) })
+func (r *Registry) OnAlert(rule AlertRule, fn func(Alert)) error {
+	if _, err := path.Match(rule.Pattern, ""); err != nil {
+		return err
+	}
+	if rule.MaxErrorRate < 0 || rule.MaxErrorRate > 1 {
+		return fmt.Errorf("irpc: alert error rate must be in [0, 1]")
+	}
+	if rule.Window <= 0 {
+		rule.Window = time.Minute
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	alerts := append([]*alertRule(nil), r.alerts...)
+	r.alerts = append(alerts, &alertRule{rule: rule, fn: fn, states: make(map[string]*alertState)})
+	return nil
+}
+
+// ClearAlerts removes every rule set with OnAlert.
+func (r *Registry) ClearAlerts() {
+	r.mu.Lock()
+	r.alerts = nil
+	r.mu.Unlock()
+}
+
+func recordAlerts(alerts []*alertRule, key string, elapsed time.Duration, err error) {
+	for _, a := range alerts {
+		if ok, _ := path.Match(a.rule.Pattern, key); ok {
+			a.record(key, elapsed, err)
+		}
+	}
+}
+
+func (a *alertRule) record(key string, elapsed time.Duration, err error) {
+	now := time.Now()
+	window := a.rule.Window
+
+	a.mu.Lock()
+	s := a.states[key]
+	if s == nil {
+		s = &alertState{start: now}
+		a.states[key] = s
+	}
+	s.advance(now, window)
+	s.calls++
+	s.latency += elapsed
+	if err != nil {
+		s.errors++
+	}
+
+	overlap := 1 - float64(now.Sub(s.start))/float64(window)
+	calls := float64(s.prev)*overlap + float64(s.calls)
+	errors := float64(s.prevE)*overlap + float64(s.errors)
+	latency := time.Duration(float64(s.prevL)*overlap) + s.latency
+
+	alert := Alert{Key: key, Rule: a.rule, Calls: int(calls)}
+	if calls > 0 {
+		alert.ErrorRate = errors / calls
+		alert.Latency = time.Duration(float64(latency) / calls)
+	}
+	var reasons []string
+	if alert.Calls >= a.rule.MinCalls {
+		if a.rule.MaxErrorRate > 0 && alert.ErrorRate > a.rule.MaxErrorRate {
+			reasons = append(reasons, fmt.Sprintf("error rate %.1f%% above %.1f%%", alert.ErrorRate*100, a.rule.MaxErrorRate*100))
+		}
+		if a.rule.MaxLatency > 0 && alert.Latency > a.rule.MaxLatency {
+			reasons = append(reasons, fmt.Sprintf("mean latency %s above %s", alert.Latency, a.rule.MaxLatency))
+		}
+	}
+	alert.Firing = len(reasons) > 0
+	changed := alert.Firing != s.firing
+	s.firing = alert.Firing
+	s.reason = strings.Join(reasons, ", ")
+	a.mu.Unlock()
+
+	if !changed {
+		return
+	}
+	if alert.Firing {
+		alert.Reason = strings.Join(reasons, ", ")
+	} else {
+		alert.Reason = "recovered"
+	}
+	alert.At = now
+	a.fn(alert)
+}
+
+// Status returns the Status of the key of a: Degraded while it fires,
+// Serving once it recovers.
+func (a Alert) Status() Status {
+	s := Status{State: Serving, CheckedAt: a.At, Details: map[string]any{
+		"calls":      a.Calls,
+		"error_rate": a.ErrorRate,
+		"latency_ns": a.Latency,
+	}}
+	s.Message = a.Reason
+	if a.Firing {
+		s.State = Degraded
+	}
+	return s
+}
+
+// firingAlerts returns the reasons of the alerts firing for each key.
+func (r *Registry) firingAlerts() map[string][]string {
+	r.mu.RLock()
+	alerts := r.alerts
+	r.mu.RUnlock()
+
+	out := make(map[string][]string)
+	for _, a := range alerts {
+		a.mu.Lock()
+		for key, s := range a.states {
+			if s.firing {
+				out[key] = append(out[key], s.reason)
+			}
+		}
+		a.mu.Unlock()
+	}
+	return out
+}
@@ -0,0 +1,231 @@
+package irpc
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Priority orders queued async calls: when the executor is saturated,
+// higher priorities are dequeued first and FIFO order is kept within a
+// priority.
+type Priority int
+
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+
+	numPriorities = int(PriorityHigh) + 1
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	}
+	return "unknown"
+}
+
+func (p Priority) clamp() Priority {
+	if p < PriorityLow {
+		return PriorityLow
+	}
+	if p > PriorityHigh {
+		return PriorityHigh
+	}
+	return p
+}
+
+// AsyncOption configures a single CallAsync or Notify.
+type AsyncOption func(*asyncTask)
+
+// WithPriority sets the priority of an async call. The default is
+// PriorityNormal.
+func WithPriority(p Priority) AsyncOption {
+	return func(t *asyncTask) {
+		t.priority = p.clamp()
+	}
+}
+
+// WithDeadline sets when the result of an async call stops being useful.
+// Queued calls run earliest deadline first within their priority, and are
+// expired with DeadlineExceeded, without running, once they can no longer
+// finish in time; a call that runs gets the deadline on its ctx. CallAsync
+// uses the deadline of its ctx unless this one is earlier; Notify only
+// uses this one, since it is not bound to its ctx.
+func WithDeadline(d time.Time) AsyncOption {
+	return func(t *asyncTask) {
+		if t.deadline.IsZero() || d.Before(t.deadline) {
+			t.deadline = d
+		}
+	}
+}
+
+type asyncTask struct {
+	ctx      context.Context
+	cancel   context.CancelCauseFunc
+	state    atomic.Int32
+	canceled atomic.Bool
+	key      string
+	req      any
+	priority Priority
+	done     chan Result
+	created  time.Time
+	deadline time.Time
+	retry    *AsyncRetryPolicy
+	attempts []Attempt
+	handle   *AsyncHandle
+
+	// payload is the encoded request of durable calls, and letter the dead
+	// letter being re-driven, if any. ack runs once a durable call has
+	// succeeded or become a dead letter; extend pushes its visibility
+	// timeout back by the given redelivery delay.
+	payload []byte
+	letter  *DeadLetter
+	ack     func()
+	extend  func(time.Duration)
+}
+
+// CallAsync queues a call on the registry's executor and returns a
+// channel that receives its result once it has run.
+func (r *Registry) CallAsync(ctx context.Context, key string, req any, opts ...AsyncOption) <-chan Result {
+	t := newAsyncTask(ctx, key, req, opts)
+	t.done = make(chan Result, 1)
+	if d, ok := ctx.Deadline(); ok {
+		WithDeadline(d)(t)
+	}
+
+	if err := r.submit(t); err != nil {
+		t.cancel(nil)
+		t.done <- Result{Err: err}
+	}
+	return t.done
+}
+
+// Notify queues a fire-and-forget call on the registry's executor.
+// The call keeps the values of ctx, such as metadata, but is not cancelled
+// with it. The returned error only reports whether the call was queued.
+func (r *Registry) Notify(ctx context.Context, key string, req any, opts ...AsyncOption) error {
+	t := newAsyncTask(context.WithoutCancel(ctx), key, req, opts)
+	err := r.submit(t)
+	if err != nil {
+		t.cancel(nil)
+	}
+	return err
+}
+
+func newAsyncTask(ctx context.Context, key string, req any, opts []AsyncOption) *asyncTask {
+	t := &asyncTask{key: key, req: req, priority: PriorityNormal, created: time.Now()}
+	t.ctx, t.cancel = context.WithCancelCause(withoutUnitOfWork(ctx))
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// AsyncStats returns the statistics of the executor running async calls.
+func (r *Registry) AsyncStats() ExecutorStats {
+	return r.executor().Stats()
+}
+
+func (r *Registry) executor() Executor {
+	r.asyncOnce.Do(func() {
+		exec := r.config.Executor
+		owned := exec == nil
+		if owned {
+			exec = NewPoolExecutor(PoolConfig{
+				Size: r.config.AsyncWorkers,
+				// Expire queued calls that would not finish in time.
+				Estimate: func(key string) time.Duration { return r.Quantile(key, 0.5) },
+			})
+		}
+		r.async = exec
+
+		r.mu.Lock()
+		closed := r.closed
+		if owned && !closed {
+			r.asyncOwned = true
+			r.onShutdown(exec.Shutdown)
+		}
+		r.mu.Unlock()
+
+		if owned && closed {
+			_ = exec.Shutdown(context.Background())
+		}
+	})
+	return r.async
+}
+
+func (r *Registry) submit(t *asyncTask) error {
+	if t.handle != nil {
+		t.handle.r = r
+	}
+	release := r.pending.add(t.key)
+	t.state.Store(int32(asyncQueued))
+	r.asyncStats.track(t, asyncQueued)
+	task := Task{
+		Key:      t.key,
+		Priority: t.priority,
+		Run: func() {
+			defer release()
+			if !t.state.CompareAndSwap(int32(asyncQueued), int32(asyncRunning)) {
+				return // canceled while queued
+			}
+			r.asyncStats.track(t, asyncRunning)
+			r.runTask(t)
+		},
+		Ctx:      t.ctx,
+		Deadline: t.deadline,
+		Reject: func(err error) {
+			release()
+			r.settle(t, nil, err)
+		},
+	}
+	err := r.executor().Submit(task)
+	if err != nil {
+		release()
+		r.asyncStats.forget(t)
+	}
+	return err
+}
+
+func (r *Registry) runTask(t *asyncTask) {
+	ctx := t.ctx
+	if !t.deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, t.deadline)
+		defer cancel()
+	}
+	res, err := r.Call(ctx, t.key, t.req)
+	r.settle(t, res, err)
+}
+
+// settle reports the outcome of t, unless it failed and is redelivered or
+// was already settled. Failed notifications become dead letters, unless
+// they were canceled.
+func (r *Registry) settle(t *asyncTask, res any, err error) {
+	if err != nil && r.redeliver(t, err) {
+		return
+	}
+	if asyncState(t.state.Swap(int32(asyncSettled))) == asyncSettled {
+		return
+	}
+	defer t.cancel(nil)
+
+	canceled := t.canceled.Load()
+	r.asyncStats.settled(t, err, canceled)
+	stored := false
+	if t.done != nil {
+		t.done <- Result{Res: res, Err: err}
+	} else if err != nil && !canceled {
+		stored = r.deadLetter(t, err)
+	}
+	if t.ack != nil && (err == nil || stored || canceled) {
+		t.ack()
+	}
+}
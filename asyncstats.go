@@ -0,0 +1,210 @@
+package irpc
+
+import (
+	"path"
+	"sync"
+	"time"
+)
+
+// AsyncKeyStats describes the async calls of a key, made with CallAsync,
+// Notify or NotifyDurable.
+type AsyncKeyStats struct {
+	// Queued, Running and Waiting count the calls waiting for a worker,
+	// being served, and waiting to be redelivered.
+	Queued  int `json:"queued"`
+	Running int `json:"running"`
+	Waiting int `json:"waiting"`
+	// Oldest is the age of the oldest of those calls, measured from when
+	// it was first queued.
+	Oldest time.Duration `json:"oldest_ns"`
+	// Completed, Failed and Canceled count the calls that settled,
+	// Retries the redeliveries scheduled and DeadLetters the calls
+	// recorded as dead letters.
+	Completed   uint64 `json:"completed"`
+	Failed      uint64 `json:"failed"`
+	Canceled    uint64 `json:"canceled"`
+	Retries     uint64 `json:"retries"`
+	DeadLetters uint64 `json:"dead_letters"`
+	// Rate is the number of calls settled per second over the last
+	// minute.
+	Rate float64 `json:"rate"`
+}
+
+// Depth returns the number of calls not settled yet.
+func (s AsyncKeyStats) Depth() int {
+	return s.Queued + s.Running + s.Waiting
+}
+
+type asyncState int
+
+const (
+	asyncQueued asyncState = iota
+	asyncRunning
+	asyncWaiting
+	asyncSettled
+)
+
+// rateWindow is the number of one-second buckets AsyncKeyStats.Rate is
+// computed over.
+const rateWindow = 60
+
+type asyncKeyStats struct {
+	tasks       map[*asyncTask]asyncState
+	completed   uint64
+	failed      uint64
+	canceled    uint64
+	retries     uint64
+	deadLetters uint64
+
+	// buckets counts settled calls per second, indexed by Unix time
+	// modulo rateWindow; seconds records which second each one holds.
+	buckets [rateWindow]uint64
+	seconds [rateWindow]int64
+}
+
+type asyncCollector struct {
+	mu   sync.Mutex
+	keys map[string]*asyncKeyStats
+}
+
+// key returns the stats of key, creating them if needed. c.mu must be held.
+func (c *asyncCollector) key(key string) *asyncKeyStats {
+	if c.keys == nil {
+		c.keys = make(map[string]*asyncKeyStats)
+	}
+	s := c.keys[key]
+	if s == nil {
+		s = &asyncKeyStats{tasks: make(map[*asyncTask]asyncState)}
+		c.keys[key] = s
+	}
+	return s
+}
+
+// track records that t is now in state.
+func (c *asyncCollector) track(t *asyncTask, state asyncState) {
+	c.mu.Lock()
+	s := c.key(t.key)
+	s.tasks[t] = state
+	if state == asyncWaiting {
+		s.retries++
+	}
+	c.mu.Unlock()
+}
+
+// forget drops t without counting it, e.g. because it could not be queued
+// and the caller got the error.
+func (c *asyncCollector) forget(t *asyncTask) {
+	c.mu.Lock()
+	delete(c.key(t.key).tasks, t)
+	c.mu.Unlock()
+}
+
+// settled drops t and counts its outcome.
+func (c *asyncCollector) settled(t *asyncTask, err error, canceled bool) {
+	now := time.Now().Unix()
+
+	c.mu.Lock()
+	s := c.key(t.key)
+	delete(s.tasks, t)
+	switch {
+	case err == nil:
+		s.completed++
+	case canceled:
+		s.canceled++
+	default:
+		s.failed++
+	}
+	i := now % rateWindow
+	if s.seconds[i] != now {
+		s.seconds[i], s.buckets[i] = now, 0
+	}
+	s.buckets[i]++
+	c.mu.Unlock()
+}
+
+// matching returns the tracked tasks of the keys matching pattern.
+func (c *asyncCollector) matching(pattern string) []*asyncTask {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var out []*asyncTask
+	for key, s := range c.keys {
+		if ok, _ := path.Match(pattern, key); !ok {
+			continue
+		}
+		for t := range s.tasks {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func (c *asyncCollector) deadLettered(key string) {
+	c.mu.Lock()
+	c.key(key).deadLetters++
+	c.mu.Unlock()
+}
+
+func (c *asyncCollector) snapshot() map[string]AsyncKeyStats {
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	out := make(map[string]AsyncKeyStats, len(c.keys))
+	for key, s := range c.keys {
+		out[key] = s.snapshot(now)
+	}
+	return out
+}
+
+func (c *asyncCollector) snapshotKey(key string) (AsyncKeyStats, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	s := c.keys[key]
+	if s == nil {
+		return AsyncKeyStats{}, false
+	}
+	return s.snapshot(time.Now()), true
+}
+
+// snapshot returns the stats of s at now. The collector's mu must be held.
+func (s *asyncKeyStats) snapshot(now time.Time) AsyncKeyStats {
+	st := AsyncKeyStats{
+		Completed:   s.completed,
+		Failed:      s.failed,
+		Canceled:    s.canceled,
+		Retries:     s.retries,
+		DeadLetters: s.deadLetters,
+	}
+	for t, state := range s.tasks {
+		switch state {
+		case asyncQueued:
+			st.Queued++
+		case asyncRunning:
+			st.Running++
+		case asyncWaiting:
+			st.Waiting++
+		}
+		if age := now.Sub(t.created); age > st.Oldest {
+			st.Oldest = age
+		}
+	}
+
+	sec := now.Unix()
+	var settled uint64
+	for i, n := range s.buckets {
+		if sec-s.seconds[i] < rateWindow {
+			settled += n
+		}
+	}
+	st.Rate = float64(settled) / rateWindow
+	return st
+}
+
+// AsyncStatsByKey returns the async call statistics of every key that had
+// async calls. Stats reports them too, in KeyStats.Async.
+func (r *Registry) AsyncStatsByKey() map[string]AsyncKeyStats {
+	return r.asyncStats.snapshot()
+}
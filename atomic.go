@@ -0,0 +1,224 @@
+package irpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// TwoPhase is implemented by handlers that take part in the two-phase
+// protocol of Atomic. Prepare validates the request and reserves whatever
+// it needs without making it visible; Commit makes the prepared work
+// permanent and Abort releases it. prepared is the value Prepare returned.
+type TwoPhase interface {
+	Prepare(ctx context.Context, req any) (prepared any, err error)
+	Commit(ctx context.Context, req any, prepared any) (any, error)
+	Abort(ctx context.Context, req any, prepared any) error
+}
+
+// Phase is the phase of the two-phase protocol a call made by Atomic is
+// in.
+type Phase string
+
+const (
+	PhasePrepare Phase = "prepare"
+	PhaseCommit  Phase = "commit"
+	PhaseAbort   Phase = "abort"
+)
+
+type phaseKey struct{}
+
+// phaseCall is the phase of a call to key made by Atomic. The handler of
+// key reports the prepared value in it rather than as its response, so
+// that transformers and response checks only see the response of Commit.
+type phaseCall struct {
+	key      string
+	phase    Phase
+	prepared any
+}
+
+// PhaseFromContext returns the phase of the call served with ctx if Atomic
+// made it as part of the two-phase protocol. The phases are dispatched as
+// calls to the key itself, so middleware recording or replaying the
+// responses of a key must tell them from plain calls.
+func PhaseFromContext(ctx context.Context) (Phase, bool) {
+	if c, ok := ctx.Value(phaseKey{}).(*phaseCall); ok && c != nil {
+		return c.phase, true
+	}
+	return "", false
+}
+
+// RegisterTwoPhase registers p for key. Plain calls to key run Prepare and
+// Commit back to back; calls staged in an Atomic group follow the
+// two-phase protocol, every phase being a call to key that goes through
+// its dispatch as any other: disabling, maintenance, limits, middleware,
+// stats and the panic policy apply to it.
+func (r *Registry) RegisterTwoPhase(key string, p TwoPhase) {
+	r.Register(key, func(ctx context.Context, req any) (any, error) {
+		c, _ := ctx.Value(phaseKey{}).(*phaseCall)
+		if c == nil || c.key != key {
+			prepared, err := p.Prepare(ctx, req)
+			if err != nil {
+				return nil, err
+			}
+			return p.Commit(ctx, req, prepared)
+		}
+
+		// Calls made by the phase itself are plain calls.
+		ctx = context.WithValue(ctx, phaseKey{}, (*phaseCall)(nil))
+		switch c.phase {
+		case PhasePrepare:
+			prepared, err := p.Prepare(ctx, req)
+			c.prepared = prepared
+			return nil, err
+		case PhaseCommit:
+			return p.Commit(ctx, req, c.prepared)
+		default:
+			return nil, p.Abort(ctx, req, c.prepared)
+		}
+	})
+
+	r.mu.Lock()
+	if r.twoPhase == nil {
+		r.twoPhase = make(map[string]TwoPhase)
+	}
+	r.twoPhase[key] = p
+	r.mu.Unlock()
+}
+
+// Pending is a call staged in a Tx. Its result is available once Atomic
+// has returned.
+type Pending struct {
+	key           string
+	req           any
+	compensateKey string
+	compensateReq any
+
+	twoPhase bool
+	prepared any
+	executed bool
+
+	res any
+	err error
+}
+
+// Result returns the outcome of the staged call.
+func (p *Pending) Result() (any, error) {
+	return p.res, p.err
+}
+
+// Tx stages the calls of an Atomic group.
+type Tx struct {
+	calls []*Pending
+}
+
+// Call stages a call to key with req.
+func (tx *Tx) Call(key string, req any) *Pending {
+	p := &Pending{key: key, req: req}
+	tx.calls = append(tx.calls, p)
+	return p
+}
+
+// CallWithCompensation stages a call to a key that does not implement
+// TwoPhase, together with the call undoing it if the group fails after it
+// ran.
+func (tx *Tx) CallWithCompensation(key string, req any, compensateKey string, compensateReq any) *Pending {
+	p := tx.Call(key, req)
+	p.compensateKey, p.compensateReq = compensateKey, compensateReq
+	return p
+}
+
+// ErrAborted is returned by Atomic, wrapped, when the group was rolled back.
+var ErrAborted = errors.New("irpc: atomic group aborted")
+
+// Atomic runs fn to stage a group of calls and executes them with
+// all-or-nothing semantics as far as the handlers allow:
+//
+//  1. every staged call to a TwoPhase key is prepared;
+//  2. the other staged calls run in order;
+//  3. the prepared calls are committed.
+//
+// If a prepare or a plain call fails, prepared calls are aborted and plain
+// calls that already ran are compensated in reverse order, and the error
+// wraps ErrAborted. If fn returns an error nothing is executed. Failures
+// during commit cannot be rolled back and are returned as is.
+func (r *Registry) Atomic(ctx context.Context, fn func(tx *Tx) error) error {
+	tx := &Tx{}
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	r.mu.RLock()
+	for _, p := range tx.calls {
+		p.twoPhase = r.twoPhase[p.key] != nil
+	}
+	r.mu.RUnlock()
+
+	for _, p := range tx.calls {
+		if !p.twoPhase {
+			continue
+		}
+		if _, err := r.callPhase(ctx, p, PhasePrepare); err != nil {
+			p.err = err
+			return r.rollback(ctx, tx, p, err)
+		}
+		p.executed = true
+	}
+
+	for _, p := range tx.calls {
+		if p.twoPhase {
+			continue
+		}
+		p.res, p.err = r.Call(ctx, p.key, p.req)
+		if p.err != nil {
+			return r.rollback(ctx, tx, p, p.err)
+		}
+		p.executed = true
+	}
+
+	var errs []error
+	for _, p := range tx.calls {
+		if !p.twoPhase {
+			continue
+		}
+		p.res, p.err = r.callPhase(ctx, p, PhaseCommit)
+		if p.err != nil {
+			errs = append(errs, fmt.Errorf("irpc: commit %s: %w", p.key, p.err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Registry) rollback(ctx context.Context, tx *Tx, failed *Pending, cause error) error {
+	ctx = context.WithoutCancel(ctx)
+	errs := []error{fmt.Errorf("%w: %s: %w", ErrAborted, failed.key, cause)}
+
+	for i := len(tx.calls) - 1; i >= 0; i-- {
+		p := tx.calls[i]
+		if !p.executed {
+			continue
+		}
+
+		switch {
+		case p.twoPhase:
+			if _, err := r.callPhase(ctx, p, PhaseAbort); err != nil {
+				errs = append(errs, fmt.Errorf("irpc: abort %s: %w", p.key, err))
+			}
+		case p.compensateKey != "":
+			if _, err := r.Call(ctx, p.compensateKey, p.compensateReq); err != nil {
+				errs = append(errs, fmt.Errorf("irpc: compensate %s: %w", p.key, err))
+			}
+		}
+		p.err = ErrAborted
+	}
+	return errors.Join(errs...)
+}
+
+// callPhase calls the key of p in phase. The value prepared in it is kept
+// in p.
+func (r *Registry) callPhase(ctx context.Context, p *Pending, phase Phase) (any, error) {
+	c := &phaseCall{key: p.key, phase: phase, prepared: p.prepared}
+	res, err := r.Call(context.WithValue(ctx, phaseKey{}, c), p.key, p.req)
+	p.prepared = c.prepared
+	return res, err
+}
@@ -0,0 +1,146 @@
+package irpc
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// ledger is a TwoPhase participant recording the phases it ran.
+type ledger struct {
+	mu     sync.Mutex
+	phases []string
+	fail   bool
+}
+
+func (l *ledger) record(phase string) {
+	l.mu.Lock()
+	l.phases = append(l.phases, phase)
+	l.mu.Unlock()
+}
+
+func (l *ledger) Prepare(ctx context.Context, req any) (any, error) {
+	if l.fail {
+		return nil, errors.New("insufficient funds")
+	}
+	l.record("prepare")
+	return "hold", nil
+}
+
+func (l *ledger) Commit(ctx context.Context, req any, prepared any) (any, error) {
+	l.record("commit " + prepared.(string))
+	return "done", nil
+}
+
+func (l *ledger) Abort(ctx context.Context, req any, prepared any) error {
+	l.record("abort " + prepared.(string))
+	return nil
+}
+
+func TestAtomicPhasesDispatched(t *testing.T) {
+	r := NewRegistry(Config{})
+	l := &ledger{}
+	r.RegisterTwoPhase("Ledger.Post", l)
+
+	var mu sync.Mutex
+	var seen []string
+	r.Use(func(key string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, req any) (any, error) {
+			phase, _ := PhaseFromContext(ctx)
+			mu.Lock()
+			seen = append(seen, key+" "+string(phase))
+			mu.Unlock()
+			return next(ctx, req)
+		}
+	})
+
+	var p *Pending
+	err := r.Atomic(context.Background(), func(tx *Tx) error {
+		p = tx.Call("Ledger.Post", 10)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res, err := p.Result(); err != nil || res != "done" {
+		t.Fatalf("Result() = %v, %v, want done", res, err)
+	}
+	if want := []string{"prepare", "commit hold"}; !slices.Equal(l.phases, want) {
+		t.Errorf("phases = %v, want %v", l.phases, want)
+	}
+	if want := []string{"Ledger.Post prepare", "Ledger.Post commit"}; !slices.Equal(seen, want) {
+		t.Errorf("middleware saw %v, want %v", seen, want)
+	}
+	if calls := r.Stats()["Ledger.Post"].Calls; calls != 2 {
+		t.Errorf("Calls = %d, want 2", calls)
+	}
+
+	// A plain call runs both phases, and middleware sees no phase.
+	seen = nil
+	if _, err := r.Call(context.Background(), "Ledger.Post", 10); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"Ledger.Post "}; !slices.Equal(seen, want) {
+		t.Errorf("middleware saw %v, want %v", seen, want)
+	}
+}
+
+func TestAtomicAbortDispatched(t *testing.T) {
+	r := NewRegistry(Config{})
+	ok, failing := &ledger{}, &ledger{fail: true}
+	r.RegisterTwoPhase("Ledger.Post", ok)
+	r.RegisterTwoPhase("Ledger.Refuse", failing)
+
+	err := r.Atomic(context.Background(), func(tx *Tx) error {
+		tx.Call("Ledger.Post", 10)
+		tx.Call("Ledger.Refuse", 10)
+		return nil
+	})
+	if !errors.Is(err, ErrAborted) {
+		t.Fatalf("Atomic() = %v, want ErrAborted", err)
+	}
+	if want := []string{"prepare", "abort hold"}; !slices.Equal(ok.phases, want) {
+		t.Errorf("phases = %v, want %v", ok.phases, want)
+	}
+}
+
+func TestAtomicDisabledParticipant(t *testing.T) {
+	r := NewRegistry(Config{})
+	l := &ledger{}
+	r.RegisterTwoPhase("Ledger.Post", l)
+	r.Disable("Ledger.Post", "closed for audit")
+
+	err := r.Atomic(context.Background(), func(tx *Tx) error {
+		tx.Call("Ledger.Post", 10)
+		return nil
+	})
+	if !errors.Is(err, ErrAborted) || !strings.Contains(err.Error(), "closed for audit") {
+		t.Fatalf("Atomic() = %v, want it aborted by the disabled key", err)
+	}
+	if len(l.phases) != 0 {
+		t.Errorf("disabled participant ran %v", l.phases)
+	}
+}
+
+func TestAtomicPhasePanic(t *testing.T) {
+	r := NewRegistry(Config{})
+	r.RegisterTwoPhase("Ledger.Post", &panicking{})
+	r.SetPanicPolicy("Ledger", PanicPolicy{Mode: PanicRecover})
+
+	err := r.Atomic(context.Background(), func(tx *Tx) error {
+		tx.Call("Ledger.Post", 10)
+		return nil
+	})
+	if !errors.Is(err, ErrAborted) || CodeOf(err) != Internal {
+		t.Fatalf("Atomic() = %v, want an aborted Internal error", err)
+	}
+}
+
+type panicking struct{ ledger }
+
+func (*panicking) Prepare(ctx context.Context, req any) (any, error) {
+	panic("boom")
+}
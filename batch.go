@@ -0,0 +1,39 @@
+package irpc
+
+import (
+	"context"
+	"sync"
+)
+
+// BatchCall is one call of a CallBatch.
+type BatchCall struct {
+	Key string
+	Req any
+}
+
+// CallBatch makes every call concurrently and returns the responses in
+// the order of calls. If any call fails the error is a *MultiError
+// identifying the failed calls; the responses of the others are still
+// returned.
+func (r *Registry) CallBatch(ctx context.Context, calls ...BatchCall) ([]any, error) {
+	responses := make([]any, len(calls))
+	errs := make([]error, len(calls))
+
+	var wg sync.WaitGroup
+	for i, c := range calls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses[i], errs[i] = r.Call(ctx, c.Key, c.Req)
+		}()
+	}
+	wg.Wait()
+
+	m := &MultiError{Total: len(calls)}
+	for i, err := range errs {
+		if err != nil {
+			m.add(i, calls[i].Key, "", err)
+		}
+	}
+	return responses, m.orNil()
+}
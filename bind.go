@@ -0,0 +1,223 @@
+package irpc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// Bind fills the func fields of the struct client points to with funcs
+// calling the keys of service, giving typed call sites without a
+// hand-written wrapper:
+//
+//	var exams struct {
+//		FindExamById func(context.Context, ExamRequest) (*ExamResponse, error)
+//		FindAllExams func(context.Context) ([]*ExamResponse, error)
+//	}
+//	err := registry.Bind("Exam", &exams)
+//	res, err := exams.FindExamById(ctx, ExamRequest{Id: "EX-1"})
+//
+// A field calls service + "." + its name, or the method named by an
+// `irpc:"Name"` tag; `irpc:"-"` skips it. Fields must take a
+// context.Context and an optional request, and return an optional
+// response and an error. Signatures are checked once, by Bind.
+//
+// Fields of type func(context.Context) error, or of a signature registered
+// with RegisterBinding, as irpcgen does for the methods of the contracts it
+// generates, get typed funcs like those of Caller. Other fields get funcs
+// made with reflect.MakeFunc, which go through reflection on every call.
+func (r *Registry) Bind(service string, client any) error {
+	v := reflect.ValueOf(client)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("irpc: Bind needs a pointer to struct, got %T", client)
+	}
+	v = v.Elem()
+
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if !f.IsExported() || f.Type.Kind() != reflect.Func {
+			continue
+		}
+		method := f.Name
+		if tag, ok := f.Tag.Lookup("irpc"); ok {
+			if tag == "-" {
+				continue
+			}
+			method = tag
+		}
+
+		key := service + "." + method
+		if err := checkCallSignature(f.Type); err != nil {
+			return fmt.Errorf("irpc: Bind %s: field %s: %w", key, f.Name, err)
+		}
+		if fn, ok := r.typedBinding(key, f.Type); ok {
+			v.Field(i).Set(fn)
+			continue
+		}
+		v.Field(i).Set(reflect.MakeFunc(f.Type, r.boundCall(key, f.Type)))
+	}
+	return nil
+}
+
+var bindings sync.Map // reflect.Type -> func(*Registry, string) reflect.Value
+
+// RegisterBinding registers the signature func(context.Context, Req) (Res,
+// error) with Bind, which then fills fields of that signature with a typed
+// func, as Caller returns, instead of one made with reflect.MakeFunc. It
+// is called by the init function of code generated with cmd/irpcgen, for
+// every method of the contracts.
+func RegisterBinding[Req, Res any]() {
+	bindings.Store(reflect.TypeFor[func(context.Context, Req) (Res, error)](), func(r *Registry, key string) reflect.Value {
+		return reflect.ValueOf(caller[Req, Res](r, key))
+	})
+}
+
+// typedBinding returns a typed func of type t calling key, if the
+// signature of t is func(context.Context) error or was registered with
+// RegisterBinding.
+func (r *Registry) typedBinding(key string, t reflect.Type) (reflect.Value, bool) {
+	in := make([]reflect.Type, t.NumIn())
+	for i := range in {
+		in[i] = t.In(i)
+	}
+	out := make([]reflect.Type, t.NumOut())
+	for i := range out {
+		out[i] = t.Out(i)
+	}
+	sig := reflect.FuncOf(in, out, false)
+
+	if sig == reflect.TypeFor[func(context.Context) error]() {
+		var cache atomic.Pointer[dispatch]
+		fn := func(ctx context.Context) error {
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			_, err := r.callCached(ctx, key, &cache, nil)
+			return err
+		}
+		return reflect.ValueOf(fn).Convert(t), true
+	}
+	if factory, ok := bindings.Load(sig); ok {
+		return factory.(func(*Registry, string) reflect.Value)(r, key).Convert(t), true
+	}
+	return reflect.Value{}, false
+}
+
+// checkCallSignature checks that t is func(ctx[, req]) ([res, ]error).
+func checkCallSignature(t reflect.Type) error {
+	if t.NumIn() < 1 || t.NumIn() > 2 || t.In(0) != contextType {
+		return fmt.Errorf("want func(context.Context[, Req]) ([Res, ]error), got %s", t)
+	}
+	if t.IsVariadic() {
+		return fmt.Errorf("variadic %s is not supported", t)
+	}
+	if t.NumOut() < 1 || t.NumOut() > 2 || t.Out(t.NumOut()-1) != errorType {
+		return fmt.Errorf("want func(context.Context[, Req]) ([Res, ]error), got %s", t)
+	}
+	return nil
+}
+
+func (r *Registry) boundCall(key string, t reflect.Type) func([]reflect.Value) []reflect.Value {
+	hasReq := t.NumIn() == 2
+	var resType reflect.Type
+	if t.NumOut() == 2 {
+		resType = t.Out(0)
+	}
+
+	results := func(res reflect.Value, err error) []reflect.Value {
+		errVal := reflect.Zero(errorType)
+		if err != nil {
+			errVal = reflect.ValueOf(&err).Elem()
+		}
+		if resType == nil {
+			return []reflect.Value{errVal}
+		}
+		return []reflect.Value{res, errVal}
+	}
+
+	return func(args []reflect.Value) []reflect.Value {
+		ctx, _ := args[0].Interface().(context.Context)
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		var req any
+		if hasReq {
+			req = args[1].Interface()
+		}
+
+		res, err := r.Call(ctx, key, req)
+		if resType == nil {
+			return results(reflect.Value{}, err)
+		}
+		zero := reflect.Zero(resType)
+		if err != nil || res == nil {
+			return results(zero, err)
+		}
+
+		rv := reflect.ValueOf(unviewAs(res, resType))
+		if !rv.Type().AssignableTo(resType) {
+			return results(zero, Errorf(Internal, "%s returned %T, want %s", key, res, resType))
+		}
+		out := reflect.New(resType).Elem()
+		out.Set(rv)
+		return results(out, nil)
+	}
+}
+
+// Caller returns a typed func calling key through r:
+//
+//	findExam := irpc.Caller[ExamRequest, *ExamResponse](registry, "Exam.FindExamById")
+//	res, err := findExam(ctx, ExamRequest{Id: "EX-1"})
+//
+// If key is already registered by a contract, Req and Res are checked once
+// against its declared types and Caller panics on a mismatch. Otherwise a
+// response that is not a Res fails the call with Internal. The caller
+// keeps the dispatch of key between calls and resolves it again once the
+// registry changes, so disabling, routing and middleware changes still
+// apply to it.
+func Caller[Req, Res any](r *Registry, key string) func(context.Context, Req) (Res, error) {
+	reqType, resType := reflect.TypeFor[Req](), reflect.TypeFor[Res]()
+
+	r.mu.RLock()
+	e := r.entries[key]
+	var declReq, declRes reflect.Type
+	if e != nil {
+		declReq, declRes = e.reqType, e.resType
+	}
+	r.mu.RUnlock()
+
+	if declReq != nil && !reqType.AssignableTo(declReq) {
+		panic(fmt.Sprintf("irpc: Caller %s: request type %s, want %s", key, reqType, declReq))
+	}
+	if declRes != nil && !declRes.AssignableTo(resType) && !isViewOf(resType, declRes) {
+		panic(fmt.Sprintf("irpc: Caller %s: response type %s, want %s", key, resType, declRes))
+	}
+
+	return caller[Req, Res](r, key)
+}
+
+// caller is Caller without the checks of the types.
+func caller[Req, Res any](r *Registry, key string) func(context.Context, Req) (Res, error) {
+	resType := reflect.TypeFor[Res]()
+	var cache atomic.Pointer[dispatch]
+	return func(ctx context.Context, req Req) (Res, error) {
+		var zero Res
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		res, err := r.callCached(ctx, key, &cache, req)
+		if err != nil || res == nil {
+			return zero, err
+		}
+		typed, ok := res.(Res)
+		if !ok {
+			typed, ok = unviewAs(res, resType).(Res)
+		}
+		if !ok {
+			return zero, Errorf(Internal, "%s returned %T, want %s", key, res, resType)
+		}
+		return typed, nil
+	}
+}
@@ -0,0 +1,100 @@
+package irpc
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCallerAllocs(t *testing.T) {
+	r := newEchoRegistry()
+	ctx := context.Background()
+	req := &echoReq{}
+
+	echo := Caller[*echoReq, *echoRes](r, "TypedEcho.Echo")
+	if _, err := echo(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if got := testing.AllocsPerRun(1000, func() { echo(ctx, req) }); got > 1 {
+		t.Errorf("%v allocations per call, want at most 1", got)
+	}
+}
+
+func TestCallerFollowsRegistryChanges(t *testing.T) {
+	r := newEchoRegistry()
+	ctx := context.Background()
+	echo := Caller[*echoReq, *echoRes](r, "TypedEcho.Echo")
+
+	if _, err := echo(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	r.Disable("TypedEcho.Echo", "")
+	if _, err := echo(ctx, nil); CodeOf(err) != Unavailable {
+		t.Fatalf("disabled key: got %v, want Unavailable", err)
+	}
+	r.Enable("TypedEcho.Echo")
+
+	wrapped := false
+	r.Use(func(key string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, req any) (any, error) {
+			wrapped = true
+			return next(ctx, req)
+		}
+	})
+	if _, err := echo(ctx, nil); err != nil || !wrapped {
+		t.Fatalf("got %v, middleware ran: %v", err, wrapped)
+	}
+}
+
+func BenchmarkCaller(b *testing.B) {
+	r := newEchoRegistry()
+	ctx := context.Background()
+	req := &echoReq{}
+	echo := Caller[*echoReq, *echoRes](r, "TypedEcho.Echo")
+
+	b.ReportAllocs()
+	for b.Loop() {
+		echo(ctx, req)
+	}
+}
+
+type echoFunc func(context.Context, *echoReq) (*echoRes, error)
+
+func TestBindTypedFields(t *testing.T) {
+	RegisterBinding[*echoReq, *echoRes]()
+	r := newEchoRegistry()
+	r.Register("TypedEcho.Ping", func(ctx context.Context, req any) (any, error) {
+		return nil, nil
+	})
+	r.Register("TypedEcho.Double", func(ctx context.Context, req any) (any, error) {
+		return 2 * req.(int), nil
+	})
+
+	var client struct {
+		Echo   func(context.Context, *echoReq) (*echoRes, error)
+		Named  echoFunc `irpc:"Echo"`
+		Ping   func(context.Context) error
+		Double func(context.Context, int) (int, error)
+	}
+	if err := r.Bind("TypedEcho", &client); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	req := &echoReq{}
+
+	if res, err := client.Echo(ctx, req); err != nil || res != echoResult {
+		t.Fatalf("Echo: got %v, %v", res, err)
+	}
+	if res, err := client.Named(ctx, req); err != nil || res != echoResult {
+		t.Fatalf("Named: got %v, %v", res, err)
+	}
+	if err := client.Ping(ctx); err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	if res, err := client.Double(ctx, 21); err != nil || res != 42 {
+		t.Fatalf("Double, made with MakeFunc: got %v, %v", res, err)
+	}
+
+	if got := testing.AllocsPerRun(1000, func() { client.Echo(ctx, req) }); got > 1 {
+		t.Errorf("Echo: %v allocations per call, want at most 1", got)
+	}
+}
@@ -0,0 +1,225 @@
+package irpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"reflect"
+	"sync"
+)
+
+var (
+	blobType    = reflect.TypeOf(Blob{})
+	blobPtrType = reflect.TypeOf((*Blob)(nil))
+)
+
+// Blob is a file passed between modules: its metadata and a stream of its
+// contents. Contract methods taking or returning a Blob get the lifetime
+// management of io.Reader streams: a returned Body is closed when the
+// caller closes it, when the call's context is done or on Shutdown.
+//
+//	type ReportContract interface {
+//		Download(ctx context.Context, req DownloadReq) (irpc.Blob, error)
+//	}
+//
+//	res, _ := registry.Call(ctx, "Report.Download", req)
+//	data, err := res.(irpc.Blob).Bytes() // checks Size and Checksum
+type Blob struct {
+	Name        string `json:"name"`
+	ContentType string `json:"content_type,omitempty"`
+	// Size is the length of Body in bytes, or -1 if unknown.
+	Size int64 `json:"size"`
+	// Checksum is "sha256:" followed by the hex SHA-256 of Body, if known.
+	Checksum string            `json:"checksum,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Body     io.ReadCloser     `json:"-"`
+}
+
+// NewBlob returns a Blob of unknown size streaming body.
+func NewBlob(name, contentType string, body io.Reader) Blob {
+	rc, ok := body.(io.ReadCloser)
+	if !ok {
+		rc = io.NopCloser(body)
+	}
+	return Blob{Name: name, ContentType: contentType, Size: -1, Body: rc}
+}
+
+// BytesBlob returns a Blob of data with its Size and Checksum set.
+func BytesBlob(name, contentType string, data []byte) Blob {
+	return Blob{
+		Name:        name,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		Checksum:    Checksum(data),
+		Body:        io.NopCloser(bytes.NewReader(data)),
+	}
+}
+
+// Checksum returns the checksum of data in the format of Blob.Checksum.
+func Checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Progress is called as a blob is transferred with the number of bytes
+// done so far and the total, or -1 if unknown.
+type Progress func(done, total int64)
+
+// Open returns a reader of b.Body that calls progress, if not nil, after
+// every read. At the end of the body it fails with DataLoss if fewer or
+// more than b.Size bytes were read or the contents do not match
+// b.Checksum. Closing it closes the body.
+func (b Blob) Open(progress Progress) io.ReadCloser {
+	if b.Body == nil {
+		return io.NopCloser(bytes.NewReader(nil))
+	}
+	return &blobReader{blob: b, hash: sha256.New(), progress: progress}
+}
+
+// Bytes reads and closes b's body, verifying it like Open.
+func (b Blob) Bytes() ([]byte, error) {
+	r := b.Open(nil)
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+type blobReader struct {
+	blob     Blob
+	hash     hash.Hash
+	progress Progress
+	done     int64
+}
+
+func (r *blobReader) Read(p []byte) (int, error) {
+	n, err := r.blob.Body.Read(p)
+	r.hash.Write(p[:n])
+	r.done += int64(n)
+	if r.progress != nil && n > 0 {
+		r.progress(r.done, r.blob.Size)
+	}
+	if err == io.EOF {
+		if verr := r.verify(); verr != nil {
+			return n, verr
+		}
+	}
+	return n, err
+}
+
+func (r *blobReader) verify() error {
+	if r.blob.Size >= 0 && r.done != r.blob.Size {
+		return Errorf(DataLoss, "blob %s: read %d bytes, want %d", r.blob.Name, r.done, r.blob.Size)
+	}
+	if r.blob.Checksum != "" {
+		if got := "sha256:" + hex.EncodeToString(r.hash.Sum(nil)); got != r.blob.Checksum {
+			return Errorf(DataLoss, "blob %s: checksum %s, want %s", r.blob.Name, got, r.blob.Checksum)
+		}
+	}
+	return nil
+}
+
+func (r *blobReader) Close() error {
+	return r.blob.Body.Close()
+}
+
+// BlobChunk is one piece of a Blob sent by SendBlob, for contract methods
+// that receive files in bounded messages. The last chunk carries the
+// checksum of the whole blob.
+type BlobChunk struct {
+	// ID identifies the transfer; every chunk of a blob has the same ID.
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	ContentType string            `json:"content_type,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Offset      int64             `json:"offset"`
+	Data        []byte            `json:"data"`
+	Last        bool              `json:"last,omitempty"`
+	Checksum    string            `json:"checksum,omitempty"`
+}
+
+// DefaultChunkSize is the chunk size SendBlob uses when given zero.
+const DefaultChunkSize = 256 << 10
+
+// SendBlob streams b to key in chunks of chunkSize bytes, calling key once
+// per BlobChunk in order and stopping at the first error. It verifies b
+// while reading like Open, reports progress if not nil and closes b's body.
+//
+//	err := irpc.SendBlob(ctx, registry, "Archive.Upload", blob, 0, nil)
+func SendBlob(ctx context.Context, r *Registry, key string, b Blob, chunkSize int, progress Progress) error {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+	body := b.Open(progress)
+	defer body.Close()
+
+	sum := sha256.New()
+	chunk := BlobChunk{ID: newID(), Name: b.Name, ContentType: b.ContentType, Metadata: b.Metadata}
+	for {
+		// Handlers may keep the data, so every chunk gets its own buffer.
+		buf := make([]byte, chunkSize)
+		n, err := io.ReadFull(body, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		chunk.Data = buf[:n]
+		sum.Write(chunk.Data)
+		if err != nil {
+			chunk.Last = true
+			chunk.Checksum = "sha256:" + hex.EncodeToString(sum.Sum(nil))
+		}
+		if _, cerr := r.Call(ctx, key, chunk); cerr != nil {
+			return cerr
+		}
+		if chunk.Last {
+			return nil
+		}
+		chunk.Offset += int64(n)
+	}
+}
+
+// BlobWriter reassembles the chunks sent by SendBlob into an io.Writer,
+// checking that they arrive in order and that the result matches the
+// checksum of the last chunk. Keep one per BlobChunk.ID.
+type BlobWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	hash    hash.Hash
+	written int64
+	done    bool
+}
+
+// NewBlobWriter returns a BlobWriter writing to w.
+func NewBlobWriter(w io.Writer) *BlobWriter {
+	return &BlobWriter{w: w, hash: sha256.New()}
+}
+
+// WriteChunk writes c and reports whether it was the last one. Chunks out
+// of order fail with OutOfRange, and a last chunk whose checksum does not
+// match what was written fails with DataLoss.
+func (w *BlobWriter) WriteChunk(c BlobChunk) (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.done {
+		return true, Errorf(FailedPrecondition, "blob %s: chunk after the last one", c.Name)
+	}
+	if c.Offset != w.written {
+		return false, Errorf(OutOfRange, "blob %s: chunk at offset %d, want %d", c.Name, c.Offset, w.written)
+	}
+	if _, err := w.w.Write(c.Data); err != nil {
+		return false, err
+	}
+	w.hash.Write(c.Data)
+	w.written += int64(len(c.Data))
+	if !c.Last {
+		return false, nil
+	}
+
+	w.done = true
+	if got := "sha256:" + hex.EncodeToString(w.hash.Sum(nil)); c.Checksum != "" && got != c.Checksum {
+		return true, Errorf(DataLoss, "blob %s: checksum %s, want %s", c.Name, got, c.Checksum)
+	}
+	return true, nil
+}
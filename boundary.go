@@ -0,0 +1,71 @@
+package irpc
+
+import (
+	"context"
+	"reflect"
+)
+
+// irpcPkgPath identifies the context keys defined by this package, which
+// always cross a context boundary.
+var irpcPkgPath = reflect.TypeOf(metadataKey{}).PkgPath()
+
+type contextBoundary struct {
+	allowed map[any]bool
+	report  func(key string, ctxKey any)
+}
+
+// boundaryCtx hides every context value that is not allowlisted. Deadline,
+// cancellation and the registry's own values pass through.
+type boundaryCtx struct {
+	context.Context
+	boundary *contextBoundary
+	key      string
+}
+
+func (c *boundaryCtx) Value(k any) any {
+	if c.boundary.allowed[k] {
+		return c.Context.Value(k)
+	}
+	if t := reflect.TypeOf(k); t != nil && t.PkgPath() == irpcPkgPath {
+		return c.Context.Value(k)
+	}
+
+	if c.boundary.report != nil && c.Context.Value(k) != nil {
+		c.boundary.report(c.key, k)
+	}
+	return nil
+}
+
+// EnforceContextBoundary makes every Call strip the context values that
+// are not in allowed (plus irpc's own metadata, call chain and unit of
+// work), so that modules have to pass data explicitly through requests or
+// metadata instead of hidden context coupling. Deadlines and cancellation
+// still propagate.
+//
+// If report is not nil, it is called whenever a handler looks up a value
+// that exists in the caller's context but was stripped, which finds the
+// hidden dependencies to fix before splitting a service out.
+func (r *Registry) EnforceContextBoundary(report func(key string, ctxKey any), allowed ...any) {
+	b := &contextBoundary{allowed: make(map[any]bool, len(allowed)), report: report}
+	for _, k := range allowed {
+		b.allowed[k] = true
+	}
+
+	r.mu.Lock()
+	r.boundary = b
+	r.mu.Unlock()
+}
+
+// DisableContextBoundary stops stripping context values.
+func (r *Registry) DisableContextBoundary() {
+	r.mu.Lock()
+	r.boundary = nil
+	r.mu.Unlock()
+}
+
+func (b *contextBoundary) wrap(ctx context.Context, key string) context.Context {
+	if b == nil {
+		return ctx
+	}
+	return &boundaryCtx{Context: ctx, boundary: b, key: key}
+}
@@ -0,0 +1,134 @@
+package irpc
+
+import (
+	"context"
+	"sync"
+)
+
+// Message is an irpc call travelling over a MessageBus.
+type Message struct {
+	Key      string
+	Payload  []byte
+	Metadata Metadata
+}
+
+// MessageBus is the transport of a Bridge, typically an adapter around a
+// Kafka producer and consumer group. Subscribe blocks, delivering messages
+// to handle until ctx is done; a message whose handle returns an error is
+// considered unprocessed.
+type MessageBus interface {
+	Publish(ctx context.Context, topic string, msg Message) error
+	Subscribe(ctx context.Context, topic string, handle func(ctx context.Context, msg Message) error) error
+}
+
+// Bridge maps contract methods onto a MessageBus, so that a method can
+// move from synchronous in-process handling to asynchronous processing
+// without touching its callers.
+type Bridge struct {
+	registry *Registry
+	bus      MessageBus
+}
+
+// NewBridge returns a Bridge between registry and bus.
+func NewBridge(registry *Registry, bus MessageBus) *Bridge {
+	return &Bridge{registry: registry, bus: bus}
+}
+
+// fromBusKey marks the context of a call consumed from the bus with the
+// key of its message, so that only that call, and not the calls its handler
+// makes, runs locally.
+type fromBusKey struct{}
+
+// Produce makes calls to key publish their request to topic instead of
+// running the local handler. Such calls return a nil response as soon as
+// the message is published.
+func (b *Bridge) Produce(key, topic string) error {
+	return b.registry.UseFor(key, func(key string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, req any) (any, error) {
+			if ctx.Value(fromBusKey{}) == key {
+				return next(ctx, req)
+			}
+
+			payload, md, err := b.registry.encodeRequest(ctx, key, req)
+			if err != nil {
+				return nil, err
+			}
+			msg := Message{Key: key, Payload: payload, Metadata: md}
+			if err := b.bus.Publish(ctx, topic, msg); err != nil {
+				return nil, &Error{Code: Unavailable, Key: key, Message: "publish to " + topic, Err: err}
+			}
+			return nil, nil
+		}
+	})
+}
+
+// Consume subscribes to topic and dispatches every message to the local
+// handler of its key. It blocks until ctx is done or the bus fails.
+func (b *Bridge) Consume(ctx context.Context, topic string) error {
+	return b.bus.Subscribe(ctx, topic, func(ctx context.Context, msg Message) error {
+		req, err := b.registry.decodeRequest(msg.Key, msg.Metadata, msg.Payload)
+		if err != nil {
+			return err
+		}
+
+		ctx = context.WithValue(WithMetadata(ctx, msg.Metadata), fromBusKey{}, msg.Key)
+		_, err = b.registry.Call(ctx, msg.Key, req)
+		return err
+	})
+}
+
+// MemoryBus is an in-process MessageBus for tests and development. Every
+// subscriber of a topic receives every message published to it.
+type MemoryBus struct {
+	mu     sync.Mutex
+	topics map[string][]chan Message
+}
+
+// NewMemoryBus returns an empty MemoryBus.
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{topics: make(map[string][]chan Message)}
+}
+
+func (m *MemoryBus) Publish(ctx context.Context, topic string, msg Message) error {
+	m.mu.Lock()
+	subs := m.topics[topic]
+	m.mu.Unlock()
+
+	for _, ch := range subs {
+		select {
+		case ch <- msg:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (m *MemoryBus) Subscribe(ctx context.Context, topic string, handle func(ctx context.Context, msg Message) error) error {
+	ch := make(chan Message, 64)
+
+	m.mu.Lock()
+	m.topics[topic] = append(m.topics[topic], ch)
+	m.mu.Unlock()
+
+	defer func() {
+		m.mu.Lock()
+		subs := m.topics[topic]
+		for i, c := range subs {
+			if c == ch {
+				m.topics[topic] = append(subs[:i:i], subs[i+1:]...)
+				break
+			}
+		}
+		m.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case msg := <-ch:
+			_ = handle(ctx, msg)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package irpc
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+)
+
+func TestBridgeNestedProduce(t *testing.T) {
+	r := NewRegistry(Config{})
+	bus := NewMemoryBus()
+	b := NewBridge(r, bus)
+
+	var placed, sentLocally, published atomic.Int32
+	r.Register("Order.Place", func(ctx context.Context, req any) (any, error) {
+		placed.Add(1)
+		return r.Call(ctx, "Mail.Send", "order placed")
+	})
+	r.Register("Mail.Send", func(ctx context.Context, req any) (any, error) {
+		sentLocally.Add(1)
+		return nil, nil
+	})
+	for key, topic := range map[string]string{"Order.Place": "orders", "Mail.Send": "mail"} {
+		if err := b.Produce(key, topic); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.Consume(ctx, "orders")
+	go bus.Subscribe(ctx, "mail", func(ctx context.Context, msg Message) error {
+		published.Add(1)
+		return nil
+	})
+	waitFor(t, "the subscriptions", func() bool {
+		bus.mu.Lock()
+		defer bus.mu.Unlock()
+		return len(bus.topics["orders"]) == 1 && len(bus.topics["mail"]) == 1
+	})
+
+	if _, err := r.Call(context.Background(), "Order.Place", "order 1"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the mail to be published", func() bool { return published.Load() == 1 })
+	if n := placed.Load(); n != 1 {
+		t.Errorf("Order.Place ran %d times, want once", n)
+	}
+	if n := sentLocally.Load(); n != 0 {
+		t.Errorf("Mail.Send ran locally %d times, want it published", n)
+	}
+}
@@ -0,0 +1,55 @@
+package irpc
+
+import (
+	"context"
+	"sync"
+)
+
+// Result is the outcome of one implementation's handling of a call.
+type Result struct {
+	Impl string
+	Res  any
+	Err  error
+}
+
+// Broadcast invokes every implementation registered for key concurrently
+// and returns their results in registration order. It is meant for
+// notification-style operations such as cache invalidation across modules.
+//
+// If the key cannot be dispatched at all the error is an *Error and no
+// results are returned. If some implementations fail, all results are
+// returned along with a *MultiError indexed by implementation position.
+// While the key's service is in maintenance mode the responder produces a
+// single result.
+func (r *Registry) Broadcast(ctx context.Context, key string, req any) ([]Result, error) {
+	d := r.resolve(key)
+	ctx, calls := d.enter(ctx)
+
+	if err := d.check(calls); err != nil {
+		return nil, err
+	}
+	if d.responder != nil {
+		res, err := d.responder(ctx, key, req)
+		return []Result{{Res: res, Err: err}}, err
+	}
+
+	results := make([]Result, len(d.impls))
+	var wg sync.WaitGroup
+	for i, im := range d.impls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res, err := r.invoke(ctx, &d, im, calls, req)
+			results[i] = Result{Impl: im.name, Res: res, Err: err}
+		}()
+	}
+	wg.Wait()
+
+	m := &MultiError{Total: len(results)}
+	for i, res := range results {
+		if res.Err != nil {
+			m.add(i, key, res.Impl, res.Err)
+		}
+	}
+	return results, m.orNil()
+}
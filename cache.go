@@ -0,0 +1,358 @@
+package irpc
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sync"
+	"time"
+)
+
+// CacheConfig configures a Cache.
+type CacheConfig struct {
+	// TTL is how long a response is fresh.
+	TTL time.Duration
+	// Stale is how long after TTL a response may still be served while it
+	// is refreshed in the background (stale-while-revalidate). Zero
+	// disables serving stale responses.
+	Stale time.Duration
+	// Extract identifies requests, like the extractor of NewMemo. Nil
+	// formats the request with %#v.
+	Extract func(req any) string
+}
+
+// CacheStats counts the lookups of a Cache.
+type CacheStats struct {
+	Hits          uint64 `json:"hits"`
+	StaleHits     uint64 `json:"stale_hits"`
+	Misses        uint64 `json:"misses"`
+	Refreshes     uint64 `json:"refreshes"`
+	RefreshErrors uint64 `json:"refresh_errors"`
+}
+
+// StaleHitRate returns the fraction of lookups answered with a stale
+// response.
+func (s CacheStats) StaleHitRate() float64 {
+	total := s.Hits + s.StaleHits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.StaleHits) / float64(total)
+}
+
+type cacheEntry struct {
+	req        any
+	res        any
+	stored     time.Time
+	refreshing bool
+}
+
+// Cache is a TTL cache of responses for read-heavy methods such as config
+// or catalog lookups. Errors are never cached, and concurrent misses for
+// the same entry share one execution. Cached responses are shared between
+// callers, which must not modify them. Responses older than TTL+Stale are
+// dropped as new ones are cached.
+type Cache struct {
+	config CacheConfig
+
+	mu       sync.Mutex
+	entries  map[string]map[string]*cacheEntry
+	inflight map[string]map[string]*cacheFlight
+	swept    time.Time
+	stats    CacheStats
+}
+
+// cacheFlight is a miss being served. Invalidating its response removes
+// it from the in-flight misses, so that it is not stored once it lands
+// and later callers do not share it.
+type cacheFlight struct {
+	*batch
+	req any
+}
+
+// NewCache returns an empty Cache.
+func NewCache(config CacheConfig) *Cache {
+	if config.Extract == nil {
+		config.Extract = func(req any) string { return fmt.Sprintf("%#v", req) }
+	}
+	return &Cache{
+		config:   config,
+		entries:  make(map[string]map[string]*cacheEntry),
+		inflight: make(map[string]map[string]*cacheFlight),
+	}
+}
+
+// Middleware returns the middleware caching the keys it is attached to.
+//
+//	cache := irpc.NewCache(irpc.CacheConfig{TTL: time.Minute, Stale: 10 * time.Minute})
+//	registry.UseFor("Catalog.*", cache.Middleware())
+func (c *Cache) Middleware() Middleware {
+	return func(key string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, req any) (any, error) {
+			id := c.config.Extract(req)
+			now := time.Now()
+
+			c.mu.Lock()
+			if e := c.entries[key][id]; e != nil {
+				age := now.Sub(e.stored)
+				if age < c.config.TTL {
+					c.stats.Hits++
+					c.mu.Unlock()
+					return e.res, nil
+				}
+				if age < c.config.TTL+c.config.Stale {
+					c.stats.StaleHits++
+					if !e.refreshing {
+						e.refreshing = true
+						c.stats.Refreshes++
+						go c.refresh(context.WithoutCancel(ctx), key, id, e, next)
+					}
+					c.mu.Unlock()
+					return e.res, nil
+				}
+			}
+
+			f, shared := c.inflight[key][id]
+			if shared {
+				c.mu.Unlock()
+				return f.wait(ctx)
+			}
+			f = &cacheFlight{batch: newBatch(), req: req}
+			if c.inflight[key] == nil {
+				c.inflight[key] = make(map[string]*cacheFlight)
+			}
+			c.inflight[key][id] = f
+			c.stats.Misses++
+			c.mu.Unlock()
+
+			// A panic is passed on to the registry once the callers
+			// waiting on f have been given it as an error.
+			defer close(f.done)
+			defer func() {
+				if v := recover(); v != nil {
+					f.res, f.err = nil, panicError(key, v)
+					c.mu.Lock()
+					c.land(key, id, f)
+					c.mu.Unlock()
+					panic(v)
+				}
+			}()
+
+			f.res, f.err = next(ctx, req)
+
+			c.mu.Lock()
+			// A response invalidated while it was computed may predate
+			// the write that invalidated it, so it is not stored.
+			if c.land(key, id, f) && f.err == nil {
+				c.store(key, id, req, f.res)
+			}
+			c.mu.Unlock()
+			return f.res, f.err
+		}
+	}
+}
+
+// refresh runs in the background, outside the registry's panic handling: a
+// panic of the handler is recovered and counted as a refresh error.
+func (c *Cache) refresh(ctx context.Context, key, id string, e *cacheEntry, next HandlerFunc) {
+	defer func() {
+		if v := recover(); v != nil {
+			c.mu.Lock()
+			c.stats.RefreshErrors++
+			e.refreshing = false
+			c.mu.Unlock()
+		}
+	}()
+	res, err := next(ctx, e.req)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		c.stats.RefreshErrors++
+		e.refreshing = false
+		return
+	}
+	if c.entries[key][id] == e {
+		c.store(key, id, e.req, res)
+	}
+}
+
+// land removes f, the miss of key for id, from the in-flight misses and
+// reports whether it was still there, i.e. not invalidated. The caller
+// must hold c.mu.
+func (c *Cache) land(key, id string, f *cacheFlight) bool {
+	if c.inflight[key][id] != f {
+		return false
+	}
+	delete(c.inflight[key], id)
+	return true
+}
+
+// store caches res. The caller must hold c.mu.
+func (c *Cache) store(key, id string, req, res any) {
+	now := time.Now()
+	c.sweep(now)
+
+	byReq := c.entries[key]
+	if byReq == nil {
+		byReq = make(map[string]*cacheEntry)
+		c.entries[key] = byReq
+	}
+	byReq[id] = &cacheEntry{req: req, res: res, stored: now}
+}
+
+// sweep drops the responses that can no longer be served, at most once per
+// TTL+Stale so that storing stays cheap on average. The caller must hold
+// c.mu.
+func (c *Cache) sweep(now time.Time) {
+	maxAge := c.config.TTL + c.config.Stale
+	if now.Sub(c.swept) < maxAge {
+		return
+	}
+	c.swept = now
+	for key, byReq := range c.entries {
+		for id, e := range byReq {
+			if now.Sub(e.stored) >= maxAge {
+				delete(byReq, id)
+			}
+		}
+		if len(byReq) == 0 {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// Invalidate drops the cached response of key for the request identified
+// by id, as returned by Extract.
+func (c *Cache) Invalidate(key, id string) {
+	c.mu.Lock()
+	delete(c.entries[key], id)
+	delete(c.inflight[key], id)
+	c.mu.Unlock()
+}
+
+// InvalidateKey drops every cached response of key.
+func (c *Cache) InvalidateKey(key string) {
+	c.mu.Lock()
+	delete(c.entries, key)
+	delete(c.inflight, key)
+	c.mu.Unlock()
+}
+
+// Reset drops every cached response.
+func (c *Cache) Reset() {
+	c.mu.Lock()
+	c.entries = make(map[string]map[string]*cacheEntry)
+	c.inflight = make(map[string]map[string]*cacheFlight)
+	c.mu.Unlock()
+}
+
+// Len returns the number of cached responses, stale ones included, and
+// expired ones not swept yet.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := 0
+	for _, byReq := range c.entries {
+		n += len(byReq)
+	}
+	return n
+}
+
+// Stats returns the lookup counters of the cache.
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// InvalidateMatching drops the cached responses of keys matching pattern,
+// using path.Match syntax, whose request satisfies match. A nil match
+// drops all of them. It returns the number of responses dropped. Matching
+// responses still being computed are not cached once they are.
+func (c *Cache) InvalidateMatching(pattern string, match func(req any) bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := 0
+	for key, byReq := range c.entries {
+		if ok, _ := path.Match(pattern, key); !ok {
+			continue
+		}
+		for id, e := range byReq {
+			if match == nil || match(e.req) {
+				delete(byReq, id)
+				n++
+			}
+		}
+	}
+	for key, flights := range c.inflight {
+		if ok, _ := path.Match(pattern, key); !ok {
+			continue
+		}
+		for id, f := range flights {
+			if match == nil || match(f.req) {
+				delete(flights, id)
+			}
+		}
+	}
+	return n
+}
+
+// UseCache attaches c to the keys matching pattern, like UseFor, and makes
+// it subject to InvalidateCache and Invalidates.
+func (r *Registry) UseCache(pattern string, c *Cache) error {
+	if err := r.UseFor(pattern, c.Middleware()); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.caches = append(r.caches, c)
+	r.mu.Unlock()
+	return nil
+}
+
+// InvalidateCache drops, from every cache attached with UseCache, the
+// responses of keys matching keyPattern whose request satisfies
+// reqMatcher (all of them if it is nil). It returns the number of
+// responses dropped.
+func (r *Registry) InvalidateCache(keyPattern string, reqMatcher func(req any) bool) int {
+	r.mu.RLock()
+	caches := r.caches
+	r.mu.RUnlock()
+
+	n := 0
+	for _, c := range caches {
+		n += c.InvalidateMatching(keyPattern, reqMatcher)
+	}
+	return n
+}
+
+// Invalidates declares that a successful call to the write method
+// writeKey invalidates the cached responses of the read methods matching
+// readPattern. match relates the write request to cached read requests;
+// nil invalidates every cached response of readPattern.
+//
+//	registry.Invalidates("Exam.UpdateExam", "Exam.FindExamById", func(write, read any) bool {
+//		return write.(UpdateExamReq).Id == read.(ExamRequest).Id
+//	})
+func (r *Registry) Invalidates(writeKey, readPattern string, match func(writeReq, readReq any) bool) error {
+	if _, err := path.Match(readPattern, ""); err != nil {
+		return err
+	}
+
+	return r.UseFor(writeKey, func(key string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, req any) (any, error) {
+			res, err := next(ctx, req)
+			if err == nil {
+				var reqMatcher func(any) bool
+				if match != nil {
+					reqMatcher = func(read any) bool { return match(req, read) }
+				}
+				r.InvalidateCache(readPattern, reqMatcher)
+			}
+			return res, err
+		}
+	})
+}
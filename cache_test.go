@@ -0,0 +1,123 @@
+package irpc
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCacheMissPanic(t *testing.T) {
+	c := NewCache(CacheConfig{TTL: time.Minute})
+	panics := true
+	h := c.Middleware()("Catalog.Get", func(context.Context, any) (any, error) {
+		if panics {
+			panic("boom")
+		}
+		return "item", nil
+	})
+
+	func() {
+		defer func() {
+			if v := recover(); v != "boom" {
+				t.Fatalf("recovered %v, want the handler's panic", v)
+			}
+		}()
+		h(context.Background(), "id")
+	}()
+
+	panics = false
+	if res, err := h(context.Background(), "id"); err != nil || res != "item" {
+		t.Fatalf("got %v, %v after the panic, want a fresh execution", res, err)
+	}
+}
+
+func TestCacheRefreshPanic(t *testing.T) {
+	c := NewCache(CacheConfig{TTL: time.Millisecond, Stale: time.Hour})
+	refreshed := make(chan struct{}, 1)
+	calls := 0
+	h := c.Middleware()("Catalog.Get", func(context.Context, any) (any, error) {
+		calls++
+		if calls > 1 {
+			defer func() { refreshed <- struct{}{} }()
+			panic("boom")
+		}
+		return "item", nil
+	})
+
+	if _, err := h(context.Background(), "id"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if res, err := h(context.Background(), "id"); err != nil || res != "item" {
+		t.Fatalf("got %v, %v, want the stale response", res, err)
+	}
+	<-refreshed
+
+	deadline := time.Now().Add(time.Second)
+	for c.Stats().RefreshErrors == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("panicking refresh was not counted as a refresh error")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCacheInvalidatedWhileInFlight(t *testing.T) {
+	r := NewRegistry(Config{})
+	c := NewCache(CacheConfig{TTL: time.Minute})
+	value := "old"
+	reading, wrote := make(chan struct{}), make(chan struct{})
+	r.Register("Catalog.Get", func(ctx context.Context, req any) (any, error) {
+		v := value
+		if v == "old" {
+			close(reading)
+			<-wrote
+		}
+		return v, nil
+	})
+	r.Register("Catalog.Set", func(ctx context.Context, req any) (any, error) {
+		value = req.(string)
+		return nil, nil
+	})
+	if err := r.UseCache("Catalog.Get", c); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Invalidates("Catalog.Set", "Catalog.Get", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		r.Call(context.Background(), "Catalog.Get", "id")
+	}()
+	<-reading
+	if _, err := r.Call(context.Background(), "Catalog.Set", "new"); err != nil {
+		t.Fatal(err)
+	}
+	close(wrote)
+	<-done
+
+	if res, err := r.Call(context.Background(), "Catalog.Get", "id"); err != nil || res != "new" {
+		t.Fatalf("got %v, %v after the write, want new", res, err)
+	}
+}
+
+func TestCacheSweepsExpiredEntries(t *testing.T) {
+	c := NewCache(CacheConfig{TTL: 20 * time.Millisecond, Stale: 20 * time.Millisecond})
+	h := c.Middleware()("Catalog.Get", func(ctx context.Context, req any) (any, error) {
+		return req, nil
+	})
+
+	for _, id := range []string{"a", "b", "c"} {
+		h(context.Background(), id)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if n := c.Len(); n != 3 {
+		t.Fatalf("Len() = %d before a sweep, want 3", n)
+	}
+	h(context.Background(), "d")
+	if n := c.Len(); n != 1 {
+		t.Fatalf("Len() = %d, want the expired entries swept", n)
+	}
+}
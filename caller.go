@@ -0,0 +1,29 @@
+package irpc
+
+import "context"
+
+// CallerKey is the metadata key naming the caller of an outermost call.
+const CallerKey = "x-caller"
+
+// WithCaller returns a copy of ctx identifying the caller of the calls
+// made with it, e.g. "report-batch" for a background job. Calls made from
+// inside a handler are attributed to the handler's service instead.
+func WithCaller(ctx context.Context, caller string) context.Context {
+	return WithMetadataValue(ctx, CallerKey, caller)
+}
+
+// CallerFromContext returns the identity of the caller of the call being
+// served by ctx: the service of the calling handler for nested calls, the
+// name set with WithCaller for outermost ones, or "" if it is unknown.
+func CallerFromContext(ctx context.Context) string {
+	return callerOf(ctx, ChainFromContext(ctx))
+}
+
+func callerOf(ctx context.Context, calls Chain) string {
+	if len(calls) > 1 {
+		service, _ := SplitKey(calls[len(calls)-2])
+		return service
+	}
+	caller, _ := MetadataValue(ctx, CallerKey)
+	return caller
+}
@@ -0,0 +1,136 @@
+package irpc
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Edge is an observed caller -> callee relation between two keys.
+type Edge struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Calls  uint64 `json:"calls"`
+	Errors uint64 `json:"errors"`
+}
+
+// CallGraph is the set of edges observed between keys at runtime. Only
+// nested calls, i.e. calls made from inside another handler, form edges.
+type CallGraph struct {
+	Edges []Edge `json:"edges"`
+}
+
+type edgeKey struct {
+	from, to string
+}
+
+type edgeCounts struct {
+	calls  atomic.Uint64
+	errors atomic.Uint64
+}
+
+type callGraph struct {
+	mu    sync.RWMutex
+	edges map[edgeKey]*edgeCounts
+}
+
+func newCallGraph() *callGraph {
+	return &callGraph{edges: make(map[edgeKey]*edgeCounts)}
+}
+
+func (g *callGraph) record(from, to string, err error) {
+	k := edgeKey{from, to}
+
+	g.mu.RLock()
+	c := g.edges[k]
+	g.mu.RUnlock()
+
+	if c == nil {
+		g.mu.Lock()
+		if c = g.edges[k]; c == nil {
+			c = &edgeCounts{}
+			g.edges[k] = c
+		}
+		g.mu.Unlock()
+	}
+
+	c.calls.Add(1)
+	if err != nil {
+		c.errors.Add(1)
+	}
+}
+
+// CallGraph returns the caller -> callee edges observed so far, sorted by
+// caller and callee.
+func (r *Registry) CallGraph() CallGraph {
+	r.graph.mu.RLock()
+	edges := make([]Edge, 0, len(r.graph.edges))
+	for k, c := range r.graph.edges {
+		edges = append(edges, Edge{From: k.from, To: k.to, Calls: c.calls.Load(), Errors: c.errors.Load()})
+	}
+	r.graph.mu.RUnlock()
+
+	sortEdges(edges)
+	return CallGraph{Edges: edges}
+}
+
+// Services collapses the graph to service level, merging the edges of all
+// keys that belong to the same service. Calls within a service are dropped.
+func (g CallGraph) Services() CallGraph {
+	merged := make(map[edgeKey]*Edge)
+	for _, e := range g.Edges {
+		from, _ := SplitKey(e.From)
+		to, _ := SplitKey(e.To)
+		if from == to {
+			continue
+		}
+
+		k := edgeKey{from, to}
+		m := merged[k]
+		if m == nil {
+			m = &Edge{From: from, To: to}
+			merged[k] = m
+		}
+		m.Calls += e.Calls
+		m.Errors += e.Errors
+	}
+
+	edges := make([]Edge, 0, len(merged))
+	for _, e := range merged {
+		edges = append(edges, *e)
+	}
+	sortEdges(edges)
+	return CallGraph{Edges: edges}
+}
+
+// WriteDOT writes the graph in Graphviz DOT format. Edges are labelled
+// with their call count, and with their error count when non-zero.
+func (g CallGraph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph irpc {\n")
+	b.WriteString("\trankdir=LR;\n")
+	b.WriteString("\tnode [shape=box];\n")
+	for _, e := range g.Edges {
+		label := fmt.Sprintf("%d", e.Calls)
+		if e.Errors > 0 {
+			label += fmt.Sprintf(" (%d errors)", e.Errors)
+		}
+		fmt.Fprintf(&b, "\t%q -> %q [label=%q];\n", e.From, e.To, label)
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func sortEdges(edges []Edge) {
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].To < edges[j].To
+	})
+}
@@ -0,0 +1,98 @@
+package irpc
+
+import "path"
+
+// AsyncHandle controls a call queued with CallAsync, Notify or
+// NotifyDurable. Pass it with WithHandle:
+//
+//	var h irpc.AsyncHandle
+//	registry.Notify(ctx, "Search.Reindex", req, irpc.WithHandle(&h))
+//	...
+//	h.Cancel()
+type AsyncHandle struct {
+	r *Registry
+	t *asyncTask
+}
+
+// WithHandle makes the async call fill in h, which can then cancel it.
+func WithHandle(h *AsyncHandle) AsyncOption {
+	return func(t *asyncTask) {
+		h.t = t
+		t.handle = h
+	}
+}
+
+// Key returns the key of the call, or "" if h was never filled in.
+func (h *AsyncHandle) Key() string {
+	if h.t == nil {
+		return ""
+	}
+	return h.t.key
+}
+
+// Cancel cancels the call and reports whether it had not settled yet. A
+// call still queued or waiting to be redelivered never runs, and settles
+// right away with Canceled; a running call has its ctx canceled, which
+// its handler may or may not honour. Canceled notifications do not become
+// dead letters, and canceled durable calls are removed from the deferred
+// store.
+func (h *AsyncHandle) Cancel() bool {
+	if h.t == nil || h.r == nil {
+		return false
+	}
+	return h.r.cancelTask(h.t)
+}
+
+// CancelPending cancels every async call to a key matching pattern, in the
+// syntax of path.Match, that has not settled yet, as AsyncHandle.Cancel
+// does, and returns how many it canceled. It is meant for incidents, or
+// for shutdowns that should not wait for queued work.
+//
+//	n, err := registry.CancelPending("Search.*")
+func (r *Registry) CancelPending(pattern string) (int, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, t := range r.asyncStats.matching(pattern) {
+		if r.cancelTask(t) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *Registry) cancelTask(t *asyncTask) bool {
+	err := &Error{Code: Canceled, Key: t.key, Message: "async call canceled"}
+	for {
+		switch state := asyncState(t.state.Load()); state {
+		case asyncSettled:
+			return false
+
+		case asyncQueued:
+			// The executor still holds the task, but its Run is a no-op
+			// once the state has moved on.
+			if !t.state.CompareAndSwap(int32(asyncQueued), int32(asyncRunning)) {
+				continue
+			}
+			t.canceled.Store(true)
+			t.cancel(err)
+			r.settle(t, nil, err)
+			return true
+
+		case asyncWaiting:
+			t.canceled.Store(true)
+			t.cancel(err)
+			if r.redeliveries.remove(t) {
+				r.settle(t, nil, err)
+			}
+			return true
+
+		case asyncRunning:
+			t.canceled.Store(true)
+			t.cancel(err)
+			return true
+		}
+	}
+}
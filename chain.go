@@ -0,0 +1,76 @@
+package irpc
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Chain is the sequence of keys traversed by a logical request, outermost
+// first, e.g. Web.Checkout -> Billing.Charge -> Exam.FindExamById.
+type Chain []string
+
+func (c Chain) String() string {
+	return strings.Join(c, " -> ")
+}
+
+type chainKey struct{}
+
+// chainCtx carries the call chain. It is its own context value and keeps
+// short chains inline, so that extending the chain costs one allocation.
+// self holds the chainCtx as a context.Context, for contract handlers to
+// pass to reflect without boxing it again.
+type chainCtx struct {
+	context.Context
+	chain Chain
+	buf   [4]string
+	owner *ServiceInfo
+	self  context.Context
+}
+
+func (c *chainCtx) Value(key any) any {
+	if key == (chainKey{}) {
+		return c
+	}
+	return c.Context.Value(key)
+}
+
+// ChainFromContext returns the call chain of ctx. Inside a handler the last
+// element is the key being served.
+func ChainFromContext(ctx context.Context) Chain {
+	if c, ok := ctx.Value(chainKey{}).(*chainCtx); ok {
+		return c.chain
+	}
+	return nil
+}
+
+func withChain(ctx context.Context, key string, owner *ServiceInfo) (context.Context, Chain) {
+	parent := ChainFromContext(ctx)
+	c := &chainCtx{Context: ctx, owner: owner}
+	c.self = c
+	if len(parent) < len(c.buf) {
+		n := copy(c.buf[:], parent)
+		c.buf[n] = key
+		c.chain = c.buf[: n+1 : n+1]
+	} else {
+		c.chain = append(parent[:len(parent):len(parent)], key)
+	}
+	return c, c.chain
+}
+
+// SlowCall describes a call that took longer than the slow-call threshold.
+type SlowCall struct {
+	Key      string
+	Chain    Chain
+	Duration time.Duration
+	Err      error
+}
+
+// OnSlowCall registers fn to be invoked after every call that takes at
+// least threshold. fn runs synchronously on the caller's goroutine.
+func (r *Registry) OnSlowCall(threshold time.Duration, fn func(SlowCall)) {
+	r.mu.Lock()
+	r.slowThreshold = threshold
+	r.onSlowCall = fn
+	r.mu.Unlock()
+}
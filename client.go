@@ -0,0 +1,36 @@
+package irpc
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+var clientFactories sync.Map // reflect.Type -> func(*Registry, string) any
+
+// RegisterClientFactory registers the constructor of the client of the
+// contract interface T. It is called by the init function of clients
+// generated with cmd/irpcgen.
+func RegisterClientFactory[T any](factory func(r *Registry, service string) T) {
+	clientFactories.Store(reflect.TypeFor[T](), func(r *Registry, service string) any {
+		return factory(r, service)
+	})
+}
+
+// NewClient returns an implementation of the contract interface T whose
+// methods call the keys of service through r.
+//
+// Go cannot implement interfaces at run time, so the implementation is
+// generated ahead of time by cmd/irpcgen, e.g. with
+//
+//	//go:generate go run github.com/khunfloat/irpc/cmd/irpcgen -type ExamContract
+//
+// NewClient panics if no client was generated for T.
+func NewClient[T any](r *Registry, service string) T {
+	t := reflect.TypeFor[T]()
+	factory, ok := clientFactories.Load(t)
+	if !ok {
+		panic(fmt.Sprintf("irpc: no client generated for %s; run irpcgen -type %s", t, t.Name()))
+	}
+	return factory.(func(*Registry, string) any)(r, service).(T)
+}
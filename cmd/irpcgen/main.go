@@ -0,0 +1,502 @@
+// Command irpcgen generates registry-backed clients for irpc contract
+// interfaces, so that callers depend on the contract instead of on keys.
+//
+// Usage:
+//
+//	//go:generate go run github.com/khunfloat/irpc/cmd/irpcgen -type ExamContract
+//
+// For every -type it writes a struct implementing the interface whose
+// methods call serviceName + "." + MethodName and a constructor, named
+// NewExamClient for ExamContract, registered for irpc.NewClient. It also
+// writes typed handlers for the methods, registered with
+// irpc.RegisterHandlerFactory, so that RegisterContract calls the methods
+// of implementations without reflection, and registers the signatures of
+// the methods with irpc.RegisterBinding for Registry.Bind.
+//
+// For every struct listed in -views it writes a read-only view, named
+// ExamResView for ExamRes, with a getter per exported field, registered
+// for irpc.ReturnViews. Getters return the fields of basic types as they
+// are, those of a type that has a view as that view, and the others as
+// deep copies:
+//
+//	//go:generate go run github.com/khunfloat/irpc/cmd/irpcgen -type ExamContract -views ExamRes
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("irpcgen: ")
+
+	typeNames := flag.String("type", "", "comma-separated list of contract interface names")
+	viewNames := flag.String("views", "", "comma-separated list of struct names to generate read-only views of")
+	output := flag.String("output", "", "output file name; default <type>_irpc.go")
+	dir := flag.String("dir", ".", "directory of the package declaring the contracts")
+	flag.Parse()
+
+	if *typeNames == "" && *viewNames == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	pkg, err := loadPackage(*dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	types, views := split(*typeNames), split(*viewNames)
+	src, err := generate(pkg, types, views)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	name := *output
+	if name == "" {
+		name = strings.ToLower(append(types, views...)[0]) + "_irpc.go"
+	}
+	if err := os.WriteFile(filepath.Join(*dir, name), src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func split(list string) []string {
+	if list == "" {
+		return nil
+	}
+	return strings.Split(list, ",")
+}
+
+type contractPackage struct {
+	name    string
+	fset    *token.FileSet
+	ifaces  map[string]*ast.InterfaceType
+	structs map[string]*ast.StructType
+	// imports maps an import name to its path, per declared type.
+	imports map[string]map[string]string
+}
+
+func loadPackage(dir string) (*contractPackage, error) {
+	fset := token.NewFileSet()
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	pkg := &contractPackage{
+		fset:    fset,
+		ifaces:  make(map[string]*ast.InterfaceType),
+		structs: make(map[string]*ast.StructType),
+		imports: make(map[string]map[string]string),
+	}
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, file, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		pkg.name = f.Name.Name
+
+		imports := make(map[string]string)
+		for _, imp := range f.Imports {
+			path, _ := strconv.Unquote(imp.Path.Value)
+			name := path[strings.LastIndex(path, "/")+1:]
+			if imp.Name != nil {
+				name = imp.Name.Name
+			}
+			imports[name] = path
+		}
+
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				switch t := ts.Type.(type) {
+				case *ast.InterfaceType:
+					pkg.ifaces[ts.Name.Name] = t
+				case *ast.StructType:
+					pkg.structs[ts.Name.Name] = t
+				default:
+					continue
+				}
+				pkg.imports[ts.Name.Name] = imports
+			}
+		}
+	}
+	if pkg.name == "" {
+		return nil, fmt.Errorf("no Go files in %s", dir)
+	}
+	return pkg, nil
+}
+
+type method struct {
+	name    string
+	req     string // empty if the method takes no request
+	res     string // empty if the method only returns an error
+	imports map[string]string
+}
+
+// methods returns the methods of the interface name, including the ones
+// promoted from interfaces embedded from the same package.
+func (p *contractPackage) methods(name string, seen map[string]bool) ([]method, error) {
+	it, ok := p.ifaces[name]
+	if !ok {
+		return nil, fmt.Errorf("interface %s not found in package %s", name, p.name)
+	}
+	if seen[name] {
+		return nil, nil
+	}
+	seen[name] = true
+
+	var out []method
+	for _, field := range it.Methods.List {
+		switch t := field.Type.(type) {
+		case *ast.Ident:
+			embedded, err := p.methods(t.Name, seen)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, embedded...)
+		case *ast.FuncType:
+			for _, n := range field.Names {
+				m, err := p.method(name, n.Name, t)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, m)
+			}
+		default:
+			return nil, fmt.Errorf("%s: unsupported embedded interface %s", name, p.expr(field.Type))
+		}
+	}
+	return out, nil
+}
+
+func (p *contractPackage) method(iface, name string, ft *ast.FuncType) (method, error) {
+	m := method{name: name, imports: p.imports[iface]}
+	params := flatten(ft.Params)
+	results := flatten(ft.Results)
+
+	if len(params) == 0 || len(params) > 2 || p.expr(params[0]) != "context.Context" {
+		return m, fmt.Errorf("%s.%s: want (context.Context[, Req]) parameters", iface, name)
+	}
+	if len(params) == 2 {
+		m.req = p.expr(params[1])
+	}
+
+	switch {
+	case len(results) == 1 && p.expr(results[0]) == "error":
+	case len(results) == 2 && p.expr(results[1]) == "error":
+		m.res = p.expr(results[0])
+	default:
+		return m, fmt.Errorf("%s.%s: want ([Res, ]error) results", iface, name)
+	}
+	return m, nil
+}
+
+// flatten returns one type expression per parameter of fl.
+func flatten(fl *ast.FieldList) []ast.Expr {
+	if fl == nil {
+		return nil
+	}
+	var out []ast.Expr
+	for _, f := range fl.List {
+		n := len(f.Names)
+		if n == 0 {
+			n = 1
+		}
+		for range n {
+			out = append(out, f.Type)
+		}
+	}
+	return out
+}
+
+func (p *contractPackage) expr(e ast.Expr) string {
+	var buf bytes.Buffer
+	_ = printer.Fprint(&buf, p.fset, e)
+	return buf.String()
+}
+
+func generate(pkg *contractPackage, types, views []string) ([]byte, error) {
+	var body bytes.Buffer
+	imports := map[string]string{
+		"context": "context",
+		"irpc":    "github.com/khunfloat/irpc",
+	}
+
+	for _, typ := range types {
+		methods, err := pkg.methods(typ, make(map[string]bool))
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range methods {
+			for name, path := range m.imports {
+				if strings.Contains(m.req+" "+m.res, name+".") {
+					imports[name] = path
+				}
+			}
+		}
+		writeClient(&body, typ, methods)
+	}
+
+	viewed := make(map[string]bool)
+	for _, name := range views {
+		viewed[name] = true
+	}
+	for _, name := range views {
+		fields, err := pkg.fields(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range fields {
+			for imp, path := range pkg.imports[name] {
+				if strings.Contains(f.typ, imp+".") {
+					imports[imp] = path
+				}
+			}
+		}
+		writeView(&body, name, fields, viewed)
+	}
+	if len(types) == 0 {
+		delete(imports, "context")
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by irpcgen. DO NOT EDIT.\n\npackage %s\n\nimport (\n", pkg.name)
+	names := make([]string, 0, len(imports))
+	for name := range imports {
+		names = append(names, name)
+	}
+	// Standard library first, then the rest, as goimports groups them.
+	std := func(path string) bool { return !strings.Contains(strings.Split(path, "/")[0], ".") }
+	sort.Slice(names, func(i, j int) bool {
+		a, b := imports[names[i]], imports[names[j]]
+		if std(a) != std(b) {
+			return std(a)
+		}
+		return a < b
+	})
+	for i, name := range names {
+		path := imports[name]
+		if i > 0 && std(imports[names[i-1]]) && !std(path) {
+			out.WriteString("\n")
+		}
+		if path[strings.LastIndex(path, "/")+1:] == name {
+			fmt.Fprintf(&out, "\t%q\n", path)
+		} else {
+			fmt.Fprintf(&out, "\t%s %q\n", name, path)
+		}
+	}
+	out.WriteString(")\n")
+	out.Write(body.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("format generated code: %w\n%s", err, out.Bytes())
+	}
+	return src, nil
+}
+
+func writeClient(w *bytes.Buffer, typ string, methods []method) {
+	// ExamContract and ExamClient both get NewExamClient and examClient.
+	client := strings.TrimSuffix(strings.TrimSuffix(typ, "Contract"), "Client") + "Client"
+	impl := string(unicode.ToLower(rune(client[0]))) + client[1:]
+	handlers := strings.TrimSuffix(impl, "Client") + "Handlers"
+
+	fmt.Fprintf(w, `
+// %[2]s implements %[1]s by calling the registry.
+type %[2]s struct {
+	registry *irpc.Registry
+	service  string
+}
+
+// New%[3]s returns an implementation of %[1]s whose methods call the keys
+// of service through r.
+func New%[3]s(r *irpc.Registry, service string) %[1]s {
+	return &%[2]s{registry: r, service: service}
+}
+
+func init() {
+	irpc.RegisterClientFactory(New%[3]s)
+	irpc.RegisterHandlerFactory(%[4]s)
+`, typ, impl, client, handlers)
+	for _, m := range methods {
+		if m.req != "" && m.res != "" {
+			fmt.Fprintf(w, "\tirpc.RegisterBinding[%s, %s]()\n", m.req, m.res)
+		}
+	}
+	w.WriteString("}\n")
+
+	for _, m := range methods {
+		params, req := "ctx context.Context", "nil"
+		if m.req != "" {
+			params, req = "ctx context.Context, req "+m.req, "req"
+		}
+
+		if m.res == "" {
+			fmt.Fprintf(w, `
+func (c *%s) %s(%s) error {
+	_, err := c.registry.Call(ctx, c.service+".%s", %s)
+	return err
+}
+`, impl, m.name, params, m.name, req)
+			continue
+		}
+
+		fmt.Fprintf(w, `
+func (c *%[1]s) %[2]s(%[3]s) (%[4]s, error) {
+	var zero %[4]s
+	res, err := c.registry.Call(ctx, c.service+".%[2]s", %[5]s)
+	if err != nil || res == nil {
+		return zero, err
+	}
+	out, ok := irpc.Unview[%[4]s](res).(%[4]s)
+	if !ok {
+		return zero, irpc.Errorf(irpc.Internal, "%%s.%[2]s returned %%T, want %[4]s", c.service, res)
+	}
+	return out, nil
+}
+`, impl, m.name, params, m.res, req)
+	}
+
+	writeHandlers(w, typ, handlers, methods)
+}
+
+// writeHandlers writes the function returning the typed handlers of the
+// methods of an implementation of typ. Requests are checked and nil ones
+// passed as the zero value, as for the handlers RegisterContract builds
+// with reflection.
+func writeHandlers(w *bytes.Buffer, typ, name string, methods []method) {
+	fmt.Fprintf(w, `
+// %[1]s returns the handlers calling the methods of impl, by method name.
+func %[1]s(impl %[2]s) map[string]irpc.HandlerFunc {
+	return map[string]irpc.HandlerFunc{
+`, name, typ)
+
+	for _, m := range methods {
+		fmt.Fprintf(w, "\t\t%q: func(ctx context.Context, req any) (any, error) {\n", m.name)
+		args := "ctx"
+		if m.req != "" {
+			fmt.Fprintf(w, `			r, ok := req.(%[1]s)
+			if !ok && req != nil {
+				return nil, irpc.Errorf(irpc.InvalidArgument, "request is %%T, want %[1]s", req)
+			}
+`, m.req)
+			args = "ctx, r"
+		}
+		if m.res == "" {
+			fmt.Fprintf(w, "\t\t\treturn nil, impl.%s(%s)\n", m.name, args)
+		} else {
+			fmt.Fprintf(w, "\t\t\treturn impl.%s(%s)\n", m.name, args)
+		}
+		w.WriteString("\t\t},\n")
+	}
+	w.WriteString("\t}\n}\n")
+}
+
+type field struct {
+	name string
+	typ  string
+}
+
+// fields returns the exported fields of the struct name. Embedded fields
+// are named after their type.
+func (p *contractPackage) fields(name string) ([]field, error) {
+	st, ok := p.structs[name]
+	if !ok {
+		return nil, fmt.Errorf("struct %s not found in package %s", name, p.name)
+	}
+
+	var out []field
+	for _, f := range st.Fields.List {
+		typ := p.expr(f.Type)
+		names := make([]string, 0, len(f.Names))
+		for _, n := range f.Names {
+			names = append(names, n.Name)
+		}
+		if len(names) == 0 {
+			embedded := strings.TrimPrefix(typ, "*")
+			names = append(names, embedded[strings.LastIndex(embedded, ".")+1:])
+		}
+		for _, n := range names {
+			if !ast.IsExported(n) {
+				continue
+			}
+			if n == "Unview" {
+				return nil, fmt.Errorf("%s: field Unview clashes with irpc.View", name)
+			}
+			out = append(out, field{name: n, typ: typ})
+		}
+	}
+	return out, nil
+}
+
+// writeView writes the read-only view of the struct typ. Fields whose type
+// has a view too are returned as views, fields of basic types as they are,
+// and the others as deep copies.
+func writeView(w *bytes.Buffer, typ string, fields []field, viewed map[string]bool) {
+	fmt.Fprintf(w, `
+// %[1]sView is a read-only view of %[1]s.
+type %[1]sView struct {
+	v %[1]s
+}
+
+// New%[1]sView returns a read-only view of v.
+func New%[1]sView(v %[1]s) %[1]sView {
+	return %[1]sView{v: v}
+}
+
+func init() {
+	irpc.RegisterViewFactory(New%[1]sView)
+}
+`, typ)
+
+	for _, f := range fields {
+		switch {
+		case viewed[f.typ]:
+			fmt.Fprintf(w, "\nfunc (v %[1]sView) %[2]s() %[3]sView {\n\treturn New%[3]sView(v.v.%[2]s)\n}\n", typ, f.name, f.typ)
+		case basicTypes[f.typ]:
+			fmt.Fprintf(w, "\nfunc (v %[1]sView) %[2]s() %[3]s {\n\treturn v.v.%[2]s\n}\n", typ, f.name, f.typ)
+		default:
+			fmt.Fprintf(w, "\nfunc (v %[1]sView) %[2]s() %[3]s {\n\treturn irpc.DeepCopy(v.v.%[2]s)\n}\n", typ, f.name, f.typ)
+		}
+	}
+
+	fmt.Fprintf(w, `
+// Unview returns a deep copy of the viewed %[1]s that the caller owns.
+func (v %[1]sView) Unview() any {
+	return irpc.DeepCopy(v.v)
+}
+`, typ)
+}
+
+// basicTypes are the field types that hold no references, which views
+// return without copying them.
+var basicTypes = map[string]bool{
+	"bool": true, "string": true, "byte": true, "rune": true,
+	"int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true, "uintptr": true,
+	"float32": true, "float64": true, "complex64": true, "complex128": true,
+	"time.Time": true, "time.Duration": true,
+}
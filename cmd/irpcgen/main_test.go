@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const contractSrc = `package exam
+
+import (
+	"context"
+	"time"
+)
+
+type ExamReq struct{ Id string }
+
+type Child struct{ Tags []string }
+
+type ExamRes struct {
+	Id     string
+	At     time.Time
+	Tags   []string
+	Scores map[string][]int
+	Owner  *Child
+	Kid    Child
+	Kids   []*Child
+}
+
+type ExamContract interface {
+	Find(ctx context.Context, req ExamReq) (*ExamRes, error)
+	Ping(ctx context.Context) error
+}
+`
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "exam.go"), []byte(contractSrc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	pkg, err := loadPackage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := generate(pkg, []string{"ExamContract"}, []string{"ExamRes", "Child"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(src)
+
+	for _, want := range []string{
+		"irpc.RegisterClientFactory(NewExamClient)",
+		"irpc.RegisterHandlerFactory(examHandlers)",
+		"irpc.RegisterBinding[ExamReq, *ExamRes]()",
+		`"Find": func(ctx context.Context, req any) (any, error) {`,
+		"return nil, impl.Ping(ctx)",
+		"func (v ExamResView) Id() string {\n\treturn v.v.Id\n}",
+		"func (v ExamResView) At() time.Time {\n\treturn v.v.At\n}",
+		"func (v ExamResView) Tags() []string {\n\treturn irpc.DeepCopy(v.v.Tags)\n}",
+		"func (v ExamResView) Scores() map[string][]int {\n\treturn irpc.DeepCopy(v.v.Scores)\n}",
+		"func (v ExamResView) Owner() *Child {\n\treturn irpc.DeepCopy(v.v.Owner)\n}",
+		"func (v ExamResView) Kid() ChildView {\n\treturn NewChildView(v.v.Kid)\n}",
+		"func (v ExamResView) Kids() []*Child {\n\treturn irpc.DeepCopy(v.v.Kids)\n}",
+		"func (v ExamResView) Unview() any {\n\treturn irpc.DeepCopy(v.v)\n}",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("generated code lacks %q", want)
+		}
+	}
+	if t.Failed() {
+		t.Log(out)
+	}
+}
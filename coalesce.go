@@ -0,0 +1,173 @@
+package irpc
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// batch is a group of calls coalesced into a single handler execution.
+// Every caller in the batch receives the same result.
+type batch struct {
+	done chan struct{}
+	res  any
+	err  error
+}
+
+func newBatch() *batch {
+	return &batch{done: make(chan struct{})}
+}
+
+func (b *batch) wait(ctx context.Context) (any, error) {
+	select {
+	case <-b.done:
+		return b.res, b.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// pendingCall is the latest call of a batch: the one that gets executed.
+type pendingCall struct {
+	ctx  context.Context
+	key  string
+	req  any
+	next HandlerFunc
+}
+
+// run executes p for every caller of b. It runs on a timer goroutine,
+// outside the registry's panic handling, so a panic is recovered here and
+// delivered to the callers as an Internal error.
+func (p pendingCall) run(b *batch) {
+	defer close(b.done)
+	defer func() {
+		if v := recover(); v != nil {
+			b.res, b.err = nil, panicError(p.key, v)
+		}
+	}()
+	b.res, b.err = p.next(context.WithoutCancel(p.ctx), p.req)
+}
+
+// panicError is the Internal error of a handler of key that panicked with
+// v, for middleware recovering panics off the caller's goroutine.
+func panicError(key string, v any) error {
+	return &Error{
+		Code:    Internal,
+		Key:     key,
+		Message: fmt.Sprintf("panic: %v", v),
+		Details: []any{PanicInfo{Value: v, Stack: string(debug.Stack())}},
+	}
+}
+
+type debouncer struct {
+	mu      sync.Mutex
+	batch   *batch
+	pending pendingCall
+	timer   *time.Timer
+}
+
+// Debounce returns middleware that coalesces bursts of calls to a key: the
+// handler only runs once no new call has arrived for wait, with the latest
+// request, and every caller of the burst receives that result. Callers
+// whose ctx is done stop waiting but do not cancel the execution.
+//
+//	registry.UseFor("Stats.Recompute", irpc.Debounce(500*time.Millisecond))
+func Debounce(wait time.Duration) Middleware {
+	var mu sync.Mutex
+	states := make(map[string]*debouncer)
+
+	return func(key string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, req any) (any, error) {
+			mu.Lock()
+			d := states[key]
+			if d == nil {
+				d = &debouncer{}
+				states[key] = d
+			}
+			mu.Unlock()
+
+			d.mu.Lock()
+			if d.batch == nil {
+				d.batch = newBatch()
+			}
+			b := d.batch
+			d.pending = pendingCall{ctx: ctx, key: key, req: req, next: next}
+			if d.timer == nil {
+				d.timer = time.AfterFunc(wait, d.fire)
+			} else {
+				d.timer.Reset(wait)
+			}
+			d.mu.Unlock()
+
+			return b.wait(ctx)
+		}
+	}
+}
+
+func (d *debouncer) fire() {
+	d.mu.Lock()
+	b, p := d.batch, d.pending
+	d.batch, d.pending = nil, pendingCall{}
+	d.mu.Unlock()
+
+	if b != nil {
+		p.run(b)
+	}
+}
+
+type throttler struct {
+	mu      sync.Mutex
+	last    time.Time
+	batch   *batch
+	pending pendingCall
+}
+
+// Throttle returns middleware that runs the handler of a key at most once
+// per interval. A call arriving when the key has not run for interval runs
+// immediately; calls arriving sooner are coalesced into one trailing
+// execution with the latest request, whose result they all receive.
+func Throttle(interval time.Duration) Middleware {
+	var mu sync.Mutex
+	states := make(map[string]*throttler)
+
+	return func(key string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, req any) (any, error) {
+			mu.Lock()
+			t := states[key]
+			if t == nil {
+				t = &throttler{}
+				states[key] = t
+			}
+			mu.Unlock()
+
+			t.mu.Lock()
+			if t.batch == nil && time.Since(t.last) >= interval {
+				t.last = time.Now()
+				t.mu.Unlock()
+				return next(ctx, req)
+			}
+
+			if t.batch == nil {
+				t.batch = newBatch()
+				time.AfterFunc(time.Until(t.last.Add(interval)), t.fire)
+			}
+			b := t.batch
+			t.pending = pendingCall{ctx: ctx, key: key, req: req, next: next}
+			t.mu.Unlock()
+
+			return b.wait(ctx)
+		}
+	}
+}
+
+func (t *throttler) fire() {
+	t.mu.Lock()
+	b, p := t.batch, t.pending
+	t.batch, t.pending = nil, pendingCall{}
+	t.last = time.Now()
+	t.mu.Unlock()
+
+	p.run(b)
+}
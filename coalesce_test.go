@@ -0,0 +1,55 @@
+package irpc
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDebouncePanicReachesEveryWaiter(t *testing.T) {
+	mw := Debounce(10 * time.Millisecond)
+	h := mw("Stats.Recompute", func(context.Context, any) (any, error) {
+		panic("boom")
+	})
+
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = h(context.Background(), i)
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if CodeOf(err) != Internal {
+			t.Fatalf("caller %d: got %v, want an Internal error", i, err)
+		}
+		if p, ok := ErrorDetail[PanicInfo](err); !ok || p.Value != "boom" {
+			t.Fatalf("caller %d: got PanicInfo %+v, %v", i, p, ok)
+		}
+	}
+}
+
+func TestThrottlePanicInTrailingCall(t *testing.T) {
+	mw := Throttle(10 * time.Millisecond)
+	calls := 0
+	h := mw("Stats.Recompute", func(context.Context, any) (any, error) {
+		calls++
+		if calls > 1 {
+			panic("boom")
+		}
+		return "ok", nil
+	})
+
+	if _, err := h(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	_, err := h(context.Background(), nil)
+	if CodeOf(err) != Internal {
+		t.Fatalf("got %v, want an Internal error", err)
+	}
+}
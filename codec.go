@@ -0,0 +1,67 @@
+package irpc
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+)
+
+// Codec serializes requests and responses whenever they have to leave
+// memory, e.g. to be persisted.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// JSONCodec is the default Codec, based on encoding/json.
+type JSONCodec struct{}
+
+func (JSONCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+func (r *Registry) codec() Codec {
+	if r.config.Codec != nil {
+		return r.config.Codec
+	}
+	return JSONCodec{}
+}
+
+// encodeRequest serializes req for key, returning it together with the
+// metadata of ctx, extended with the name of the request's type if it was
+// registered with RegisterType.
+func (r *Registry) encodeRequest(ctx context.Context, key string, req any) ([]byte, Metadata, error) {
+	payload, err := r.codec().Marshal(req)
+	if err != nil {
+		return nil, nil, &Error{Code: InvalidArgument, Key: key, Message: "encode request of " + key, Err: err}
+	}
+
+	md := MetadataFromContext(ctx).Copy()
+	if name, ok := registeredName(req); ok && r.requestType(key) == nil {
+		md[RequestTypeKey] = name
+	}
+	return payload, md, nil
+}
+
+// decodeRequest decodes data into a new value of the request type of key,
+// or of the registered type named in md. Keys without a known request type
+// get the raw bytes.
+func (r *Registry) decodeRequest(key string, md Metadata, data []byte) (any, error) {
+	t := r.requestType(key)
+	if t == nil {
+		t, _ = TypeByName(md[RequestTypeKey])
+	}
+	if t == nil {
+		return data, nil
+	}
+
+	v := reflect.New(t)
+	if err := r.codec().Unmarshal(data, v.Interface()); err != nil {
+		return nil, &Error{Code: InvalidArgument, Key: key, Message: "decode request of " + key, Err: err}
+	}
+	return v.Elem().Interface(), nil
+}
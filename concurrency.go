@@ -0,0 +1,194 @@
+package irpc
+
+import (
+	"context"
+	"sync"
+)
+
+// ConcurrencyStats reports the usage of the registry-wide concurrency
+// limit set with SetMaxConcurrency.
+type ConcurrencyStats struct {
+	Limit    int                           `json:"limit"`
+	InFlight int                           `json:"in_flight"`
+	Waiting  int                           `json:"waiting"`
+	Rejected uint64                        `json:"rejected"`
+	Services map[string]ServiceConcurrency `json:"services"`
+}
+
+// ServiceConcurrency is the share of one service in ConcurrencyStats.
+type ServiceConcurrency struct {
+	InFlight int    `json:"in_flight"`
+	Waiting  int    `json:"waiting"`
+	Rejected uint64 `json:"rejected"`
+}
+
+type slotWaiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+type serviceSlots struct {
+	inFlight int
+	waiters  []*slotWaiter
+	rejected uint64
+}
+
+// limiter caps the number of calls in flight. When the cap is reached,
+// callers wait in per-service queues that are served round robin, so a
+// service producing many calls cannot starve the others.
+type limiter struct {
+	mu       sync.Mutex
+	limit    int
+	queue    int
+	inFlight int
+	rejected uint64
+	services map[string]*serviceSlots
+	ring     []string // services with waiters, in serving order
+}
+
+func (l *limiter) service(name string) *serviceSlots {
+	s := l.services[name]
+	if s == nil {
+		s = &serviceSlots{}
+		l.services[name] = s
+	}
+	return s
+}
+
+func (l *limiter) acquire(ctx context.Context, key string) error {
+	name, _ := SplitKey(key)
+
+	l.mu.Lock()
+	s := l.service(name)
+	if l.limit <= 0 || (l.inFlight < l.limit && len(l.ring) == 0) {
+		l.inFlight++
+		s.inFlight++
+		l.mu.Unlock()
+		return nil
+	}
+	if len(s.waiters) >= l.queue {
+		l.rejected++
+		s.rejected++
+		l.mu.Unlock()
+		return &Error{Code: ResourceExhausted, Key: key, Message: key + ": concurrency limit reached"}
+	}
+	w := &slotWaiter{ready: make(chan struct{})}
+	s.waiters = append(s.waiters, w)
+	if len(s.waiters) == 1 {
+		l.ring = append(l.ring, name)
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	if w.granted {
+		l.mu.Unlock()
+		l.release(key)
+	} else {
+		for i, other := range s.waiters {
+			if other == w {
+				s.waiters = append(s.waiters[:i], s.waiters[i+1:]...)
+				break
+			}
+		}
+		if len(s.waiters) == 0 {
+			l.removeFromRing(name)
+		}
+		l.rejected++
+		s.rejected++
+		l.mu.Unlock()
+	}
+	return ctx.Err()
+}
+
+func (l *limiter) release(key string) {
+	name, _ := SplitKey(key)
+
+	l.mu.Lock()
+	l.inFlight--
+	l.service(name).inFlight--
+	l.grant()
+	l.mu.Unlock()
+}
+
+// grant hands free slots to waiters, one service at a time. The caller
+// must hold l.mu.
+func (l *limiter) grant() {
+	for len(l.ring) > 0 && (l.limit <= 0 || l.inFlight < l.limit) {
+		name := l.ring[0]
+		l.ring = l.ring[1:]
+
+		s := l.services[name]
+		w := s.waiters[0]
+		s.waiters = s.waiters[1:]
+		if len(s.waiters) > 0 {
+			l.ring = append(l.ring, name)
+		}
+
+		l.inFlight++
+		s.inFlight++
+		w.granted = true
+		close(w.ready)
+	}
+}
+
+func (l *limiter) removeFromRing(name string) {
+	for i, other := range l.ring {
+		if other == name {
+			l.ring = append(l.ring[:i], l.ring[i+1:]...)
+			return
+		}
+	}
+}
+
+// SetMaxConcurrency caps the number of calls running at once across the
+// whole registry. When the cap is reached, calls wait in a queue of up to
+// queue calls per service, served round robin between services; calls
+// that find their service's queue full fail with ResourceExhausted.
+//
+// Only outermost calls take a slot: nested calls made by a handler run in
+// the slot of their caller, so the limit cannot deadlock. The limit can be
+// changed at any time; a limit <= 0 removes it.
+func (r *Registry) SetMaxConcurrency(limit, queue int) {
+	r.mu.Lock()
+	if r.limiter == nil {
+		r.limiter = &limiter{services: make(map[string]*serviceSlots)}
+	}
+	l := r.limiter
+	r.mu.Unlock()
+
+	l.mu.Lock()
+	l.limit, l.queue = limit, queue
+	l.grant()
+	l.mu.Unlock()
+}
+
+// ConcurrencyStats returns the current usage of the concurrency limit.
+func (r *Registry) ConcurrencyStats() ConcurrencyStats {
+	r.mu.RLock()
+	l := r.limiter
+	r.mu.RUnlock()
+	if l == nil {
+		return ConcurrencyStats{}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	out := ConcurrencyStats{
+		Limit:    l.limit,
+		InFlight: l.inFlight,
+		Rejected: l.rejected,
+		Services: make(map[string]ServiceConcurrency, len(l.services)),
+	}
+	for name, s := range l.services {
+		out.Waiting += len(s.waiters)
+		out.Services[name] = ServiceConcurrency{InFlight: s.inFlight, Waiting: len(s.waiters), Rejected: s.rejected}
+	}
+	return out
+}
@@ -0,0 +1,26 @@
+package irpc
+
+import (
+	"slices"
+	"strings"
+)
+
+// forService returns c specialized for service by the entries of
+// c.Services, from the shortest matching prefix to the longest.
+func (c Config) forService(service string) Config {
+	var prefixes []string
+	for prefix := range c.Services {
+		if service == prefix || strings.HasPrefix(service, prefix+".") {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	slices.SortFunc(prefixes, func(a, b string) int { return len(a) - len(b) })
+
+	for _, prefix := range prefixes {
+		// Clip the middleware so that appending to it cannot change the
+		// config of other services.
+		c.Middleware = slices.Clip(c.Middleware)
+		c.Services[prefix](&c)
+	}
+	return c
+}
@@ -0,0 +1,55 @@
+package irpc
+
+import (
+	"context"
+	"log/slog"
+)
+
+// CorrelationIDKey is the metadata key holding the correlation ID.
+const CorrelationIDKey = "x-correlation-id"
+
+// CorrelationID returns middleware that ensures every call carries a
+// correlation ID in its metadata, generating one when the caller did not
+// provide it. Nested calls made with the handler's ctx reuse the same ID.
+func CorrelationID() Middleware {
+	return func(key string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, req any) (any, error) {
+			if _, ok := MetadataValue(ctx, CorrelationIDKey); !ok {
+				ctx = WithMetadataValue(ctx, CorrelationIDKey, newID())
+			}
+			return next(ctx, req)
+		}
+	}
+}
+
+// CorrelationIDFromContext returns the correlation ID carried by ctx, or ""
+// if there is none.
+func CorrelationIDFromContext(ctx context.Context) string {
+	id, _ := MetadataValue(ctx, CorrelationIDKey)
+	return id
+}
+
+// NewCorrelationLogHandler wraps h so that records logged with a context
+// carrying a correlation ID get a "correlation_id" attribute.
+func NewCorrelationLogHandler(h slog.Handler) slog.Handler {
+	return correlationLogHandler{h}
+}
+
+type correlationLogHandler struct {
+	slog.Handler
+}
+
+func (h correlationLogHandler) Handle(ctx context.Context, rec slog.Record) error {
+	if id := CorrelationIDFromContext(ctx); id != "" {
+		rec.AddAttrs(slog.String("correlation_id", id))
+	}
+	return h.Handler.Handle(ctx, rec)
+}
+
+func (h correlationLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return correlationLogHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h correlationLogHandler) WithGroup(name string) slog.Handler {
+	return correlationLogHandler{h.Handler.WithGroup(name)}
+}
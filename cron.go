@@ -0,0 +1,186 @@
+package irpc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSpec is a parsed cron expression. Each field is a bit set of the
+// values it matches.
+type cronSpec struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+	every                         time.Duration
+}
+
+type cronField struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	cronMinute = cronField{min: 0, max: 59}
+	cronHour   = cronField{min: 0, max: 23}
+	cronDom    = cronField{min: 1, max: 31}
+	cronMonth  = cronField{min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	cronDow = cronField{min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// parseCron parses a standard five-field cron expression
+// (minute hour day-of-month month day-of-week), one of the descriptors
+// @yearly, @monthly, @weekly, @daily and @hourly, or "@every <duration>".
+func parseCron(spec string) (*cronSpec, error) {
+	spec = strings.TrimSpace(spec)
+	if d, ok := strings.CutPrefix(spec, "@every "); ok {
+		every, err := time.ParseDuration(strings.TrimSpace(d))
+		if err != nil {
+			return nil, fmt.Errorf("irpc: invalid cron spec %q: %w", spec, err)
+		}
+		if every <= 0 {
+			return nil, fmt.Errorf("irpc: invalid cron spec %q: interval must be positive", spec)
+		}
+		return &cronSpec{every: every}, nil
+	}
+	if expanded, ok := cronDescriptors[spec]; ok {
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("irpc: invalid cron spec %q: expected 5 fields", spec)
+	}
+
+	c := &cronSpec{
+		domStar: fields[2] == "*" || fields[2] == "?",
+		dowStar: fields[4] == "*" || fields[4] == "?",
+	}
+	var err error
+	for i, dst := range []*uint64{&c.minute, &c.hour, &c.dom, &c.month, &c.dow} {
+		f := []cronField{cronMinute, cronHour, cronDom, cronMonth, cronDow}[i]
+		if *dst, err = f.parse(fields[i]); err != nil {
+			return nil, fmt.Errorf("irpc: invalid cron spec %q: %w", spec, err)
+		}
+	}
+
+	// Both 0 and 7 mean Sunday.
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	return c, nil
+}
+
+func (f cronField) parse(s string) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(s, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("bad step %q", part)
+			}
+			step = n
+		}
+
+		lo, hi := f.min, f.max
+		if rng != "*" && rng != "?" {
+			loStr, hiStr, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = f.value(loStr); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = f.value(hiStr); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				hi = f.max
+			}
+		}
+		if lo > hi {
+			return 0, fmt.Errorf("bad range %q", part)
+		}
+
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+func (f cronField) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("value %q out of range [%d-%d]", s, f.min, f.max)
+	}
+	return v, nil
+}
+
+// next returns the first activation time strictly after t, or the zero
+// time if there is none within five years.
+func (c *cronSpec) next(t time.Time) time.Time {
+	if c.every > 0 {
+		return t.Add(c.every)
+	}
+
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches applies the cron rule that when both day fields are
+// restricted, a day matching either of them is selected.
+func (c *cronSpec) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case c.domStar && c.dowStar:
+		return true
+	case c.domStar:
+		return dow
+	case c.dowStar:
+		return dom
+	}
+	return dom || dow
+}
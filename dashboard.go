@@ -0,0 +1,45 @@
+package irpc
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+//go:embed dashboard/index.html
+var dashboardHTML []byte
+
+type dashboardSnapshot struct {
+	Time  time.Time           `json:"time"`
+	Keys  []string            `json:"keys"`
+	Stats map[string]KeyStats `json:"stats"`
+	Graph CallGraph           `json:"graph"`
+}
+
+// DashboardHandler returns an http.Handler serving a self-contained web
+// dashboard with registered services, live call and error rates, latency
+// charts and the call graph. Mount it on a path ending in a slash:
+//
+//	mux.Handle("/debug/irpc/ui/", http.StripPrefix("/debug/irpc/ui", registry.DashboardHandler()))
+//
+// The page polls the "api" path below the mount point for snapshots.
+func (r *Registry) DashboardHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if strings.HasSuffix(req.URL.Path, "/api") {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Cache-Control", "no-store")
+			_ = json.NewEncoder(w).Encode(dashboardSnapshot{
+				Time:  time.Now(),
+				Keys:  r.Keys(),
+				Stats: r.Stats(),
+				Graph: r.CallGraph(),
+			})
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(dashboardHTML)
+	})
+}
@@ -0,0 +1,255 @@
+package irpc
+
+import (
+	"context"
+	"path"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DeadLetter is a fire-and-forget call that failed for good, kept with
+// enough context to inspect it and re-drive it with Redrive.
+type DeadLetter struct {
+	ID  string
+	Key string
+	// Request is the original request. It is only kept in memory; stores
+	// that persist dead letters rely on Payload, the request encoded with
+	// the registry's Codec.
+	Request  any `json:"-"`
+	Payload  []byte
+	Metadata Metadata
+	Priority Priority
+	// Attempts lists the failed attempts, oldest first, including those
+	// made before earlier re-drives.
+	Attempts  []Attempt
+	CreatedAt time.Time
+}
+
+// Attempt records one failed attempt of an async call.
+type Attempt struct {
+	At    time.Time
+	Code  Code
+	Error string
+}
+
+func newAttempt(err error) Attempt {
+	return Attempt{At: time.Now(), Code: CodeOf(err), Error: err.Error()}
+}
+
+// LastError returns the error message of the last attempt.
+func (d DeadLetter) LastError() string {
+	if len(d.Attempts) == 0 {
+		return ""
+	}
+	return d.Attempts[len(d.Attempts)-1].Error
+}
+
+// DeadLetterStore keeps dead letters until they are re-driven or deleted.
+// Implementations must be safe for concurrent use.
+type DeadLetterStore interface {
+	Add(ctx context.Context, letter DeadLetter) error
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context) ([]DeadLetter, error)
+}
+
+// SetDeadLetterStore sets the store receiving Notify and NotifyDurable
+// calls that failed, including calls dropped by the executor. Without a
+// store or an OnDeadLetter handler, such failures are discarded.
+func (r *Registry) SetDeadLetterStore(s DeadLetterStore) {
+	r.mu.Lock()
+	r.deadLetters = s
+	r.mu.Unlock()
+}
+
+// OnDeadLetter calls fn with every dead letter, after it has been added to
+// the dead-letter store if one is set. fn runs on the goroutine that ran
+// the failed call and must not block.
+func (r *Registry) OnDeadLetter(fn func(DeadLetter)) {
+	r.mu.Lock()
+	r.onDeadLetter = fn
+	r.mu.Unlock()
+}
+
+// DeadLetters returns the dead letters in the store.
+func (r *Registry) DeadLetters(ctx context.Context) ([]DeadLetter, error) {
+	store, err := r.deadLetterStore()
+	if err != nil {
+		return nil, err
+	}
+	return store.List(ctx)
+}
+
+// Redrive queues the dead letter id again, as a Notify with its original
+// metadata and priority, and removes it from the store. If it fails again
+// it comes back under the same ID with the new attempt appended.
+func (r *Registry) Redrive(ctx context.Context, id string) error {
+	store, err := r.deadLetterStore()
+	if err != nil {
+		return err
+	}
+	letters, err := store.List(ctx)
+	if err != nil {
+		return err
+	}
+	for _, l := range letters {
+		if l.ID == id {
+			return r.redrive(ctx, store, l)
+		}
+	}
+	return Errorf(NotFound, "dead letter %s not found", id)
+}
+
+// RedriveAll re-drives every dead letter whose key matches pattern, in the
+// syntax of path.Match, and returns how many were queued.
+//
+//	n, err := registry.RedriveAll(ctx, "Mail.*")
+func (r *Registry) RedriveAll(ctx context.Context, pattern string) (int, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return 0, err
+	}
+	store, err := r.deadLetterStore()
+	if err != nil {
+		return 0, err
+	}
+	letters, err := store.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, l := range letters {
+		if ok, _ := path.Match(pattern, l.Key); !ok {
+			continue
+		}
+		if err := r.redrive(ctx, store, l); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+func (r *Registry) redrive(ctx context.Context, store DeadLetterStore, l DeadLetter) error {
+	req := l.Request
+	if req == nil && l.Payload != nil {
+		var err error
+		if req, err = r.decodeRequest(l.Key, l.Metadata, l.Payload); err != nil {
+			return err
+		}
+	}
+
+	t := newAsyncTask(WithMetadata(context.Background(), l.Metadata), l.Key, req, []AsyncOption{WithPriority(l.Priority)})
+	t.letter = &l
+
+	// Delete first: a re-driven call failing again is added back under the
+	// same ID, possibly before submit returns.
+	if err := store.Delete(ctx, l.ID); err != nil {
+		return err
+	}
+	if err := r.submit(t); err != nil {
+		_ = store.Add(ctx, l)
+		return err
+	}
+	return nil
+}
+
+func (r *Registry) deadLetterStore() (DeadLetterStore, error) {
+	r.mu.RLock()
+	s := r.deadLetters
+	r.mu.RUnlock()
+
+	if s == nil {
+		return nil, Errorf(FailedPrecondition, "no dead-letter store configured")
+	}
+	return s, nil
+}
+
+// deadLetter records the failure of task t, a Notify-style call. It
+// reports whether the dead letter was added to the store.
+func (r *Registry) deadLetter(t *asyncTask, err error) bool {
+	r.mu.RLock()
+	store, fn := r.deadLetters, r.onDeadLetter
+	r.mu.RUnlock()
+	if store == nil && fn == nil {
+		return false
+	}
+	r.asyncStats.deadLettered(t.key)
+
+	l := DeadLetter{
+		Key:       t.key,
+		Request:   t.req,
+		Payload:   t.payload,
+		Metadata:  MetadataFromContext(t.ctx).Copy(),
+		Priority:  t.priority,
+		CreatedAt: time.Now(),
+	}
+	if t.letter != nil {
+		l.ID, l.CreatedAt = t.letter.ID, t.letter.CreatedAt
+		l.Attempts = append(l.Attempts, t.letter.Attempts...)
+		if l.Payload == nil {
+			l.Payload = t.letter.Payload
+		}
+	}
+	if l.ID == "" {
+		l.ID = newID()
+	}
+	l.Attempts = append(l.Attempts, t.attempts...)
+	l.Attempts = append(l.Attempts, newAttempt(err))
+	if l.Payload == nil && l.Request != nil {
+		if payload, _, encErr := r.encodeRequest(t.ctx, t.key, t.req); encErr == nil {
+			l.Payload = payload
+		}
+	}
+
+	stored := false
+	if store != nil {
+		stored = store.Add(context.WithoutCancel(t.ctx), l) == nil
+	}
+	if fn != nil {
+		fn(l)
+	}
+	r.emit(t.ctx, deadLetterEvent(l))
+	return stored
+}
+
+// MemoryDeadLetterStore is an in-memory DeadLetterStore. It does not
+// survive restarts and is meant for tests and development.
+type MemoryDeadLetterStore struct {
+	mu      sync.Mutex
+	letters map[string]DeadLetter
+}
+
+// NewMemoryDeadLetterStore returns an empty MemoryDeadLetterStore.
+func NewMemoryDeadLetterStore() *MemoryDeadLetterStore {
+	return &MemoryDeadLetterStore{letters: make(map[string]DeadLetter)}
+}
+
+func (s *MemoryDeadLetterStore) Add(ctx context.Context, letter DeadLetter) error {
+	s.mu.Lock()
+	s.letters[letter.ID] = letter
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryDeadLetterStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	delete(s.letters, id)
+	s.mu.Unlock()
+	return nil
+}
+
+// List returns the stored dead letters, oldest first.
+func (s *MemoryDeadLetterStore) List(ctx context.Context) ([]DeadLetter, error) {
+	s.mu.Lock()
+	letters := make([]DeadLetter, 0, len(s.letters))
+	for _, l := range s.letters {
+		letters = append(letters, l)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(letters, func(i, j int) bool {
+		return letters[i].CreatedAt.Before(letters[j].CreatedAt)
+	})
+	return letters, nil
+}
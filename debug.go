@@ -0,0 +1,40 @@
+package irpc
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+type debugKey struct {
+	Key   string    `json:"key"`
+	Tags  []Tag     `json:"tags,omitempty"`
+	Stats *KeyStats `json:"stats,omitempty"`
+}
+
+// DebugHandler returns an http.Handler that reports every registered key
+// together with its call statistics as JSON. It is meant to be mounted on
+// an internal-only mux, e.g. mux.Handle("/debug/irpc", registry.DebugHandler()).
+func (r *Registry) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		stats := r.Stats()
+
+		keys := r.Keys()
+		out := make([]debugKey, 0, len(keys))
+		for _, key := range keys {
+			entry := debugKey{Key: key, Tags: r.Tags(key)}
+			if s, ok := stats[key]; ok {
+				entry.Stats = &s
+			}
+			out = append(out, entry)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(map[string]any{
+			"keys":        out,
+			"concurrency": r.ConcurrencyStats(),
+			"quotas":      r.QuotaUsages(),
+		})
+	})
+}
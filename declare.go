@@ -0,0 +1,196 @@
+package irpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+)
+
+// Declaration describes registrations and operational settings outside
+// compiled code, bound to it by the names of Factories. It is usually read
+// at startup with ReadDeclaration:
+//
+//	{
+//	  "services": [{"name": "Billing", "factory": "billing", "params": {"currency": "EUR"}}],
+//	  "middleware": [{"factory": "audit", "pattern": "Billing.*"}],
+//	  "timeouts": {"Billing.*": "2s"},
+//	  "rate_limits": {"Search.*": {"rate": 100, "burst": 20}},
+//	  "routes": [{"key": "Search.Query", "experiment": "rewrite", "assign_by": "x-user-id",
+//	    "variants": [{"impl": "default", "weight": 9}, {"impl": "v2", "weight": 1}]}]
+//	}
+//
+// Its fields carry yaml tags too, so declarations can be kept in YAML and
+// decoded with a package such as gopkg.in/yaml.v3.
+type Declaration struct {
+	Services   []ServiceDeclaration    `json:"services,omitempty" yaml:"services,omitempty"`
+	Middleware []MiddlewareDeclaration `json:"middleware,omitempty" yaml:"middleware,omitempty"`
+	// Settings are added to the registry's; unlike ApplySettings,
+	// Declare does not remove settings missing from them.
+	Settings `yaml:",inline"`
+}
+
+// ServiceDeclaration registers the contract built by a service factory
+// under Name, as RegisterContractImpl does. An empty Impl registers it as
+// DefaultImpl.
+type ServiceDeclaration struct {
+	Name    string `json:"name" yaml:"name"`
+	Factory string `json:"factory" yaml:"factory"`
+	Impl    string `json:"impl,omitempty" yaml:"impl,omitempty"`
+	Params  Params `json:"params,omitempty" yaml:"params,omitempty"`
+	// Tags maps method names to their tags.
+	Tags map[string][]Tag `json:"tags,omitempty" yaml:"tags,omitempty"`
+	// Info is set with WithServiceInfo, if present.
+	Info *ServiceInfo `json:"info,omitempty" yaml:"info,omitempty"`
+}
+
+// MiddlewareDeclaration appends the middleware built by a middleware
+// factory to the chain, limited to the keys matching Pattern and carrying
+// Tag when they are set.
+type MiddlewareDeclaration struct {
+	Factory string `json:"factory" yaml:"factory"`
+	Pattern string `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	Tag     Tag    `json:"tag,omitempty" yaml:"tag,omitempty"`
+	Params  Params `json:"params,omitempty" yaml:"params,omitempty"`
+}
+
+// Params are the free-form parameters a declaration passes to its factory.
+type Params map[string]any
+
+// Decode decodes p into v, which is usually a pointer to a struct with
+// json tags.
+func (p Params) Decode(v any) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// ServiceFactory builds the implementation of a declared service.
+type ServiceFactory func(p Params) (any, error)
+
+// MiddlewareFactory builds declared middleware.
+type MiddlewareFactory func(p Params) (Middleware, error)
+
+// Factories binds the names used in a Declaration to code. The zero value
+// is ready to use.
+//
+//	var f irpc.Factories
+//	f.Service("billing", (*Billing)(nil), func(p irpc.Params) (any, error) {
+//		var cfg billingConfig
+//		if err := p.Decode(&cfg); err != nil {
+//			return nil, err
+//		}
+//		return newBilling(cfg), nil
+//	})
+//	f.Middleware("audit", func(irpc.Params) (irpc.Middleware, error) { return audit, nil })
+type Factories struct {
+	services   map[string]serviceFactory
+	middleware map[string]MiddlewareFactory
+}
+
+type serviceFactory struct {
+	iface any
+	build ServiceFactory
+}
+
+// Service names a factory building implementations of the contract iface,
+// a nil pointer to the interface as for RegisterContract.
+func (f *Factories) Service(name string, iface any, build ServiceFactory) {
+	if f.services == nil {
+		f.services = make(map[string]serviceFactory)
+	}
+	f.services[name] = serviceFactory{iface: iface, build: build}
+}
+
+// Middleware names a middleware factory.
+func (f *Factories) Middleware(name string, build MiddlewareFactory) {
+	if f.middleware == nil {
+		f.middleware = make(map[string]MiddlewareFactory)
+	}
+	f.middleware[name] = build
+}
+
+// ReadDeclaration reads a JSON declaration. Unknown fields are errors, so
+// that typos in the file do not go unnoticed.
+func ReadDeclaration(rd io.Reader) (Declaration, error) {
+	dec := json.NewDecoder(rd)
+	dec.DisallowUnknownFields()
+	var d Declaration
+	if err := dec.Decode(&d); err != nil {
+		return Declaration{}, fmt.Errorf("irpc: reading declaration: %w", err)
+	}
+	return d, nil
+}
+
+// Declare applies d to the registry using the factories of f. Every
+// factory is looked up and run, and every setting checked, before the
+// registry is changed, so a bad declaration returns an error without
+// leaving it half applied. Registration itself panics as
+// RegisterContractImpl does, e.g. on duplicate keys.
+func (r *Registry) Declare(d Declaration, f *Factories) error {
+	type service struct {
+		decl  ServiceDeclaration
+		iface any
+		impl  any
+	}
+	services := make([]service, 0, len(d.Services))
+	for _, s := range d.Services {
+		sf, ok := f.services[s.Factory]
+		if !ok {
+			return fmt.Errorf("irpc: service %s: unknown factory %q", s.Name, s.Factory)
+		}
+		if s.Name == "" {
+			return fmt.Errorf("irpc: service of factory %q has no name", s.Factory)
+		}
+		impl, err := sf.build(s.Params)
+		if err != nil {
+			return fmt.Errorf("irpc: service %s: %w", s.Name, err)
+		}
+		services = append(services, service{decl: s, iface: sf.iface, impl: impl})
+	}
+
+	mws := make([]scopedMiddleware, 0, len(d.Middleware))
+	for _, m := range d.Middleware {
+		build, ok := f.middleware[m.Factory]
+		if !ok {
+			return fmt.Errorf("irpc: unknown middleware factory %q", m.Factory)
+		}
+		if _, err := path.Match(m.Pattern, ""); err != nil {
+			return fmt.Errorf("irpc: middleware %s: %w", m.Factory, err)
+		}
+		mw, err := build(m.Params)
+		if err != nil {
+			return fmt.Errorf("irpc: middleware %s: %w", m.Factory, err)
+		}
+		mws = append(mws, scopedMiddleware{pattern: m.Pattern, tag: m.Tag, mw: mw})
+	}
+
+	if err := d.Settings.check(); err != nil {
+		return err
+	}
+
+	for _, s := range services {
+		var opts []RegisterOption
+		for method, tags := range s.decl.Tags {
+			opts = append(opts, WithMethodTags(method, tags...))
+		}
+		if s.decl.Info != nil {
+			opts = append(opts, WithServiceInfo(*s.decl.Info))
+		}
+		impl := s.decl.Impl
+		if impl == "" {
+			impl = DefaultImpl
+		}
+		r.RegisterContractImpl(s.decl.Name, impl, s.iface, s.impl, opts...)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.middleware = append(r.middleware, mws...)
+	r.chainGen++
+	r.setSettings(d.Settings)
+	return nil
+}
@@ -0,0 +1,83 @@
+package irpc
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// DegradedTrailer is the trailer set when a call was answered with
+// degraded data: "timeout" for a Degradation default, "fallback" for a
+// Fallback.
+const DegradedTrailer = "x-degraded"
+
+// Degradation answers calls whose handler exceeds its deadline with a
+// default response.
+type Degradation struct {
+	// Timeout bounds the handler in addition to the caller's deadline.
+	// Zero only uses the caller's deadline.
+	Timeout time.Duration
+	// Default produces the response, e.g. StaticResponder(emptyList).
+	Default Responder
+}
+
+// SetDegradation makes calls to key that exceed their deadline return the
+// response of d.Default instead of DeadlineExceeded. The handler's call is
+// canceled, and the caller's Trailer gets DegradedTrailer set to "timeout".
+func (r *Registry) SetDegradation(key string, d Degradation) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.ensureEntry(key).degrade = &d
+}
+
+// ClearDegradation removes the degradation of key.
+func (r *Registry) ClearDegradation(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if e := r.entries[key]; e != nil {
+		e.degrade = nil
+	}
+}
+
+func (r *Registry) callDegraded(ctx context.Context, d *dispatch, req any) (any, error) {
+	var hctx context.Context
+	var cancel context.CancelFunc
+	if d.degrade.Timeout > 0 {
+		hctx, cancel = context.WithTimeout(ctx, d.degrade.Timeout)
+	} else {
+		hctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	done := make(chan Result, 1)
+	shared := d.share(ctx)
+	go func() {
+		res, err := r.call(hctx, shared, req)
+		done <- Result{Res: res, Err: err}
+	}()
+
+	var err error
+	select {
+	case res := <-done:
+		if !errors.Is(res.Err, context.DeadlineExceeded) {
+			return res.Res, res.Err
+		}
+		err = res.Err
+	case <-hctx.Done():
+		err = hctx.Err()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+	}
+
+	if d.streamRes {
+		discardResults(done, 1)
+	}
+	if d.degrade.Default == nil {
+		return nil, err
+	}
+	markTrailer(ctx, DegradedTrailer, "timeout")
+	return d.degrade.Default(context.WithoutCancel(ctx), d.key, req)
+}
@@ -0,0 +1,318 @@
+package irpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Delivery is how a topic delivers its events.
+type Delivery int
+
+const (
+	// DeliverSync calls every subscriber in turn on the publishing
+	// goroutine, and Publish returns their errors. It is the default.
+	DeliverSync Delivery = iota
+	// DeliverOrdered gives every subscriber its own queue and goroutine:
+	// each sees the events in publish order, at its own pace.
+	DeliverOrdered
+	// DeliverGlobalOrder delivers one event at a time to every subscriber
+	// from a single topic queue, so all of them observe the same order and
+	// no subscriber gets an event before all got the previous one.
+	DeliverGlobalOrder
+	// DeliverConcurrent gives every subscriber its own queue drained by
+	// several goroutines, see WithWorkers. Events are unordered.
+	DeliverConcurrent
+)
+
+func (d Delivery) String() string {
+	switch d {
+	case DeliverOrdered:
+		return "ordered"
+	case DeliverGlobalOrder:
+		return "global-order"
+	case DeliverConcurrent:
+		return "concurrent"
+	default:
+		return "sync"
+	}
+}
+
+// queued reports whether subscribers of d have their own queue.
+func (d Delivery) queued() bool {
+	return d == DeliverOrdered || d == DeliverConcurrent
+}
+
+// SlowConsumerPolicy is what happens when an event is published while a
+// queue is full.
+type SlowConsumerPolicy int
+
+const (
+	// SlowBlock makes Publish wait for room in the queue. It is the
+	// default.
+	SlowBlock SlowConsumerPolicy = iota
+	// SlowDropOldest drops the oldest queued event to make room.
+	SlowDropOldest
+	// SlowDisconnect unsubscribes the subscriber and reports
+	// ErrSlowConsumer to the OnSubscriberError handler. It does not apply
+	// to the topic queue of DeliverGlobalOrder.
+	SlowDisconnect
+)
+
+// DefaultQueueSize is the queue size of topics that do not set one.
+const DefaultQueueSize = 64
+
+// ErrSlowConsumer is reported for subscribers disconnected by
+// SlowDisconnect.
+var ErrSlowConsumer = errors.New("irpc: slow consumer disconnected")
+
+// WithDelivery sets how the topic delivers its events. It panics if
+// another NewTopic call set a different Delivery, or if the topic already
+// has subscribers.
+func WithDelivery(d Delivery) TopicOption {
+	return func(s *topicState) {
+		if (s.deliverySet || len(s.subs) > 0) && s.delivery != d {
+			panic(fmt.Sprintf("irpc: topic %s already delivers %s, not %s", s.name, s.delivery, d))
+		}
+		s.delivery, s.deliverySet = d, true
+	}
+}
+
+// WithQueue sets the size and slow-consumer policy of the topic's queues:
+// the queue of every subscriber, unless it subscribes with
+// WithSubscriberQueue, or the topic queue of DeliverGlobalOrder.
+func WithQueue(size int, policy SlowConsumerPolicy) TopicOption {
+	return func(s *topicState) {
+		s.queueSize, s.policy = size, policy
+	}
+}
+
+// OnSubscriberError sets the handler of subscriber failures of topics that
+// queue their events, since Publish cannot return them.
+func OnSubscriberError(fn func(topic string, ev any, err error)) TopicOption {
+	return func(s *topicState) {
+		s.onError = fn
+	}
+}
+
+// SubscribeOption configures a subscriber of a topic that queues events.
+type SubscribeOption func(*subscribeOptions)
+
+type subscribeOptions struct {
+	queueSize int
+	policy    SlowConsumerPolicy
+	workers   int
+}
+
+// WithSubscriberQueue sets the size and slow-consumer policy of the
+// subscriber's queue.
+func WithSubscriberQueue(size int, policy SlowConsumerPolicy) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.queueSize, o.policy = size, policy
+	}
+}
+
+// WithWorkers sets how many goroutines drain the subscriber's queue with
+// DeliverConcurrent. The default is GOMAXPROCS.
+func WithWorkers(n int) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.workers = max(n, 1)
+	}
+}
+
+// configure applies opts and starts the topic queue of DeliverGlobalOrder.
+func (s *topicState) configure(opts []TopicOption) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.delivery == DeliverGlobalOrder && s.policy == SlowDisconnect {
+		panic("irpc: topic " + s.name + ": SlowDisconnect does not apply to DeliverGlobalOrder")
+	}
+	if s.delivery == DeliverGlobalOrder && s.global == nil {
+		s.global = newEventQueue(s.queueSize, s.policy, &s.dropped)
+		s.global.start(1, func(e queuedEvent) {
+			for _, sub := range e.subs {
+				if err := sub.deliver(e.ctx, e.ev); err != nil {
+					s.fail(e.ev, err)
+				}
+			}
+		})
+	}
+}
+
+// enqueue queues ev for every subscriber, disconnecting the slow ones if
+// their policy says so.
+func (s *topicState) enqueue(ctx context.Context, subs []*topicSub, ev any) error {
+	e := queuedEvent{ctx: context.WithoutCancel(ctx), ev: ev}
+	for _, sub := range subs {
+		ok, err := sub.queue.push(ctx, e)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			s.remove(sub)
+			sub.stop(false)
+			s.fail(ev, ErrSlowConsumer)
+		}
+	}
+	return nil
+}
+
+// serve replays events to a queued subscriber, then starts its workers.
+func (s *topicState) serve(sub *topicSub, replay []retainedEvent) {
+	for _, r := range replay {
+		_ = sub.fn(WithMetadata(context.Background(), r.metadata), r.ev)
+	}
+	sub.queue.start(sub.workers, func(e queuedEvent) {
+		if err := sub.fn(e.ctx, e.ev); err != nil {
+			s.fail(e.ev, err)
+		}
+	})
+}
+
+func (s *topicState) fail(ev any, err error) {
+	s.mu.Lock()
+	onError := s.onError
+	s.mu.Unlock()
+	if onError != nil {
+		onError(s.name, ev, err)
+	}
+}
+
+// stop stops the subscriber's queue, delivering what is queued first if
+// drain is set.
+func (sub *topicSub) stop(drain bool) {
+	if sub.queue != nil {
+		sub.once.Do(func() { sub.queue.stop(drain) })
+	}
+}
+
+// shutdown stops the topic's queues on registry Shutdown, delivering the
+// queued events until ctx is done.
+func (s *topicState) shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	subs, global := s.subs, s.global
+	s.mu.Unlock()
+
+	queues := make([]*eventQueue, 0, len(subs)+1)
+	if global != nil {
+		global.stop(true)
+		queues = append(queues, global)
+	}
+	for _, sub := range subs {
+		if sub.queue != nil {
+			sub.stop(true)
+			queues = append(queues, sub.queue)
+		}
+	}
+	for _, q := range queues {
+		select {
+		case <-q.stopped:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// eventQueue is a bounded queue of events drained by worker goroutines.
+type eventQueue struct {
+	events  chan queuedEvent
+	policy  SlowConsumerPolicy
+	dropped *atomic.Uint64
+	done    chan struct{}
+	drain   atomic.Bool
+	wg      sync.WaitGroup
+	stopped chan struct{}
+}
+
+func newEventQueue(size int, policy SlowConsumerPolicy, dropped *atomic.Uint64) *eventQueue {
+	return &eventQueue{
+		events:  make(chan queuedEvent, max(size, 1)),
+		policy:  policy,
+		dropped: dropped,
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// push queues e according to the policy. It returns false if the queue is
+// full and the policy is SlowDisconnect.
+func (q *eventQueue) push(ctx context.Context, e queuedEvent) (bool, error) {
+	switch q.policy {
+	case SlowDropOldest:
+		for {
+			select {
+			case q.events <- e:
+				return true, nil
+			case <-q.done:
+				return true, nil
+			default:
+			}
+			select {
+			case <-q.events:
+				q.dropped.Add(1)
+			default:
+			}
+		}
+	case SlowDisconnect:
+		select {
+		case q.events <- e:
+			return true, nil
+		case <-q.done:
+			return true, nil
+		default:
+			return false, nil
+		}
+	default:
+		select {
+		case q.events <- e:
+			return true, nil
+		case <-q.done:
+			return true, nil
+		case <-ctx.Done():
+			return true, ctx.Err()
+		}
+	}
+}
+
+// start runs n workers calling fn with every queued event.
+func (q *eventQueue) start(n int, fn func(queuedEvent)) {
+	q.wg.Add(n)
+	for range n {
+		go func() {
+			defer q.wg.Done()
+			for {
+				select {
+				case e := <-q.events:
+					fn(e)
+				case <-q.done:
+					for q.drain.Load() {
+						select {
+						case e := <-q.events:
+							fn(e)
+						default:
+							return
+						}
+					}
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		q.wg.Wait()
+		close(q.stopped)
+	}()
+}
+
+func (q *eventQueue) stop(drain bool) {
+	q.drain.Store(drain)
+	close(q.done)
+}
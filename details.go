@@ -0,0 +1,84 @@
+package irpc
+
+import "time"
+
+// FieldViolation is an error detail describing an invalid request field.
+type FieldViolation struct {
+	Field       string `json:"field"`
+	Description string `json:"description"`
+}
+
+// RetryInfo is an error detail telling the caller how long to wait before
+// retrying. Retry middleware honors it.
+type RetryInfo struct {
+	Delay time.Duration `json:"delay_ns"`
+}
+
+// QuotaFailure is an error detail describing an exhausted quota.
+type QuotaFailure struct {
+	Subject     string `json:"subject"`
+	Description string `json:"description"`
+}
+
+// WithDetails returns a copy of e with details appended.
+func (e *Error) WithDetails(details ...any) *Error {
+	out := *e
+	out.Details = append(e.Details[:len(e.Details):len(e.Details)], details...)
+	return &out
+}
+
+// ErrorDetail returns the first detail of type T attached to any *Error
+// in the chain of err.
+func ErrorDetail[T any](err error) (T, bool) {
+	var found T
+	ok := false
+	walkErrors(err, func(e *Error) bool {
+		for _, d := range e.Details {
+			if v, match := d.(T); match {
+				found, ok = v, true
+				return false
+			}
+		}
+		return true
+	})
+	return found, ok
+}
+
+// ErrorDetails returns every detail of type T attached to the chain of
+// err, e.g. all the FieldViolations of a validation error.
+func ErrorDetails[T any](err error) []T {
+	var out []T
+	walkErrors(err, func(e *Error) bool {
+		for _, d := range e.Details {
+			if v, ok := d.(T); ok {
+				out = append(out, v)
+			}
+		}
+		return true
+	})
+	return out
+}
+
+// walkErrors calls fn for every *Error in the tree of err, depth first,
+// until fn returns false.
+func walkErrors(err error, fn func(*Error) bool) bool {
+	for err != nil {
+		if e, ok := err.(*Error); ok && !fn(e) {
+			return false
+		}
+		switch u := err.(type) {
+		case interface{ Unwrap() error }:
+			err = u.Unwrap()
+		case interface{ Unwrap() []error }:
+			for _, inner := range u.Unwrap() {
+				if !walkErrors(inner, fn) {
+					return false
+				}
+			}
+			return true
+		default:
+			return true
+		}
+	}
+	return true
+}
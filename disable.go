@@ -0,0 +1,104 @@
+package irpc
+
+import (
+	"path"
+	"sort"
+)
+
+const defaultDisabledMessage = "method is disabled"
+
+type disabledSet struct {
+	keys     map[string]string
+	patterns map[string]string
+}
+
+func (d *disabledSet) lookup(key string) (string, bool) {
+	if msg, ok := d.keys[key]; ok {
+		return msg, true
+	}
+	for pattern, msg := range d.patterns {
+		if ok, _ := path.Match(pattern, key); ok {
+			return msg, true
+		}
+	}
+	return "", false
+}
+
+// Disable makes calls to key fail with Unavailable until Enable is called.
+// message is returned to callers; an empty message uses a default.
+func (r *Registry) Disable(key, message string) {
+	if message == "" {
+		message = defaultDisabledMessage
+	}
+
+	r.mu.Lock()
+	if r.disabled.keys == nil {
+		r.disabled.keys = make(map[string]string)
+	}
+	r.disabled.keys[key] = message
+	r.mu.Unlock()
+}
+
+// Enable re-enables a key disabled with Disable.
+func (r *Registry) Enable(key string) {
+	r.mu.Lock()
+	delete(r.disabled.keys, key)
+	r.mu.Unlock()
+}
+
+// DisableMatching disables every key matching pattern, using path.Match
+// syntax, e.g. "Report.*". It also applies to keys registered later.
+func (r *Registry) DisableMatching(pattern, message string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return err
+	}
+	if message == "" {
+		message = defaultDisabledMessage
+	}
+
+	r.mu.Lock()
+	if r.disabled.patterns == nil {
+		r.disabled.patterns = make(map[string]string)
+	}
+	r.disabled.patterns[pattern] = message
+	r.mu.Unlock()
+	return nil
+}
+
+// EnableMatching removes a pattern added with DisableMatching.
+func (r *Registry) EnableMatching(pattern string) {
+	r.mu.Lock()
+	delete(r.disabled.patterns, pattern)
+	r.mu.Unlock()
+}
+
+// DisabledKeys returns the registered keys that are currently disabled,
+// mapped to the message their callers receive.
+func (r *Registry) DisabledKeys() map[string]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make(map[string]string)
+	for key, e := range r.entries {
+		if len(e.impls) == 0 {
+			continue
+		}
+		if msg, ok := r.disabled.lookup(key); ok {
+			out[key] = msg
+		}
+	}
+	return out
+}
+
+// DisabledPatterns returns the patterns added with DisableMatching, sorted.
+func (r *Registry) DisabledPatterns() []string {
+	r.mu.RLock()
+	patterns := make([]string, 0, len(r.disabled.patterns))
+	for p := range r.disabled.patterns {
+		patterns = append(patterns, p)
+	}
+	r.mu.RUnlock()
+
+	sort.Strings(patterns)
+	return patterns
+}
@@ -0,0 +1,267 @@
+package irpc
+
+import (
+	"context"
+	"runtime/pprof"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// dispatch is the registry state needed to serve one call of a key,
+// captured under a single read lock.
+type dispatch struct {
+	key         string
+	impls       []*impl
+	route       routeFunc
+	aggregate   Aggregator
+	hedge       *Hedge
+	fallback    *fallback
+	degrade     *Degradation
+	versioned   bool
+	uow         bool
+	tags        []Tag
+	mws         []scopedMiddleware
+	configMws   []Middleware
+	chainGen    uint64
+	transforms  []scopedTransformer
+	responder   Responder
+	boundary    *contextBoundary
+	disabled    bool
+	disabledMsg string
+	panics      PanicPolicy
+	limiter     *limiter
+	quotas      []*quotaRule
+	alerts      []*alertRule
+	slo         *sloRule
+	rateLimits  []*tokenBucket
+	bulkhead    *bulkhead
+	timeout     time.Duration
+	profiled    bool
+	watchdog    *watchdog
+	strict      *strictCall
+	streamReq   bool
+	streamRes   bool
+	notFound    *Provenance
+	owner       *ServiceInfo
+	gen         uint64
+	// streamCtx, if not nil, is the context of the caller that response
+	// streams are bound to, when the handler runs with a context canceled
+	// on its own.
+	streamCtx context.Context
+
+	slowThreshold time.Duration
+	onSlowCall    func(SlowCall)
+}
+
+func (r *Registry) resolve(key string) dispatch {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	d := dispatch{
+		key:           key,
+		mws:           r.middleware,
+		chainGen:      r.chainGen,
+		transforms:    r.transformers,
+		slowThreshold: r.slowThreshold,
+		onSlowCall:    r.onSlowCall,
+		gen:           r.mu.gen.Load(),
+	}
+	if e := r.entries[key]; e != nil {
+		d.impls, d.route, d.aggregate, d.uow = e.impls, e.route, e.aggregate, e.uow
+		d.tags, d.hedge, d.fallback, d.degrade = e.tags, e.hedge, e.fallback, e.degrade
+		d.strict, d.streamReq, d.streamRes = e.strict, e.streamReq, e.streamRes
+		d.timeout, d.configMws = e.timeout, e.middleware
+		d.owner = e.owner
+	}
+	if len(d.impls) == 0 {
+		d.notFound = r.missingProvenance(key)
+	}
+	d.versioned = len(r.versions[key]) > 0 || len(d.impls) > 1
+	d.disabledMsg, d.disabled = r.disabled.lookup(key)
+	service, _ := SplitKey(key)
+	d.responder, _ = r.maintenanceFor(service)
+	d.panics = r.panicPolicy(service)
+	d.limiter = r.limiter
+	d.quotas = r.quotas
+	d.alerts = r.alerts
+	d.slo = r.sloFor(key)
+	d.rateLimits = r.rateLimits
+	d.bulkhead = r.bulkheads[service]
+	if t := r.timeout(key); t > 0 {
+		d.timeout = t
+	}
+	if r.restarting[service] {
+		d.disabled, d.disabledMsg = true, "restarting after a panic"
+	}
+	d.boundary = r.boundary
+	d.profiled = r.profiled(key)
+	d.watchdog = r.watchdog
+	return d
+}
+
+// genMutex is the lock of a Registry. It counts the times it is locked for
+// writing, so that state read under it, such as a dispatch, can be kept
+// until the next write.
+type genMutex struct {
+	sync.RWMutex
+	gen atomic.Uint64
+}
+
+func (m *genMutex) Unlock() {
+	m.gen.Add(1)
+	m.RWMutex.Unlock()
+}
+
+// resolveCached returns the dispatch of key from cache, resolving it again
+// if the registry changed since. The dispatch is shared: callers must copy
+// it before modifying it.
+func (r *Registry) resolveCached(key string, cache *atomic.Pointer[dispatch]) *dispatch {
+	if d := cache.Load(); d != nil && d.gen == r.mu.gen.Load() {
+		return d
+	}
+	d := r.resolve(key)
+	cache.Store(&d)
+	return &d
+}
+
+// share returns a heap copy of d for use by other goroutines serving a
+// call made with ctx. Calls that stay on the caller's goroutine then keep d
+// on the stack. Response streams of the copy are bound to ctx rather than
+// to the contexts the goroutines derive from it and cancel.
+func (d *dispatch) share(ctx context.Context) *dispatch {
+	shared := *d
+	if shared.streamCtx == nil {
+		shared.streamCtx = ctx
+	}
+	return &shared
+}
+
+// enter derives the context the call runs with: the call chain is
+// extended, and the unit of work and non-allowlisted values are stripped.
+func (d *dispatch) enter(ctx context.Context) (context.Context, Chain) {
+	ctx, calls := withChain(ctx, d.key, d.owner)
+	if !d.uow {
+		ctx = withoutUnitOfWork(ctx)
+	}
+	return d.boundary.wrap(ctx, d.key), calls
+}
+
+// check reports why the call cannot be dispatched, if it cannot.
+func (d *dispatch) check(calls Chain) error {
+	if len(d.impls) == 0 {
+		msg := "handler not found: " + d.key
+		if len(calls) > 1 {
+			msg += " (call chain: " + calls.String() + ")"
+		}
+		if p := d.notFound.String(); p != "" {
+			service, _ := SplitKey(d.key)
+			msg += " (service " + service + ": " + p + ")"
+		}
+		return &Error{Code: NotFound, Key: d.key, Message: msg, Details: []any{*d.notFound}}
+	}
+	if d.disabled {
+		return withOwner(&Error{Code: Unavailable, Key: d.key, Message: d.key + ": " + d.disabledMsg}, d.owner)
+	}
+	return nil
+}
+
+// admit applies the quotas, rate limits and bulkhead of the call. If it
+// returns nil the caller must leave the bulkhead.
+func (d *dispatch) admit(ctx context.Context, calls Chain) error {
+	if err := checkQuotas(d.quotas, d.key, callerOf(ctx, calls)); err != nil {
+		return err
+	}
+	if err := takeTokens(d.rateLimits, d.key); err != nil {
+		return err
+	}
+	if d.bulkhead != nil && !d.bulkhead.enter() {
+		return &Error{Code: ResourceExhausted, Key: d.key, Message: d.key + ": bulkhead full"}
+	}
+	return nil
+}
+
+// invoke runs im through the middleware chain and records the outcome.
+func (r *Registry) invoke(ctx context.Context, d *dispatch, im *impl, calls Chain, req any) (any, error) {
+	if err := d.admit(ctx, calls); err != nil {
+		r.stats.record(d.key, 0, err)
+		return nil, withOwner(err, d.owner)
+	}
+	if d.bulkhead != nil {
+		defer d.bulkhead.leave()
+	}
+	if d.limiter != nil && len(calls) == 1 {
+		if err := d.limiter.acquire(ctx, d.key); err != nil {
+			r.stats.record(d.key, 0, err)
+			return nil, withOwner(err, d.owner)
+		}
+		defer d.limiter.release(d.key)
+	}
+	// Response streams outlive the call, so they are bound to the context
+	// of the caller rather than to the timeout.
+	streamCtx := ctx
+	if d.streamCtx != nil {
+		streamCtx = d.streamCtx
+	}
+	if d.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.timeout)
+		defer cancel()
+	}
+
+	h := im.chained(d)
+
+	if err := d.strict.checkRequest(d.key, req); err != nil {
+		r.stats.record(d.key, 0, err)
+		return nil, withOwner(err, d.owner)
+	}
+
+	start := time.Now()
+	res, err := r.callHandler(ctx, d, im, h, req)
+	if err == nil && len(d.transforms) > 0 {
+		res, err = transform(ctx, d.transforms, d.key, req, res)
+	}
+	if err == nil {
+		err = d.strict.checkResponse(d.key, res)
+	}
+	if d.streamReq || d.streamRes {
+		res = r.manageStreams(streamCtx, d, req, res, err)
+	}
+	err = wrapHandlerError(d.key, calls, err, d.owner)
+	elapsed := time.Since(start)
+
+	r.stats.record(d.key, elapsed, err)
+	if len(d.alerts) > 0 {
+		recordAlerts(d.alerts, d.key, elapsed, err)
+	}
+	if d.slo != nil {
+		if st, changed := d.slo.record(d.key, elapsed, err); changed {
+			r.budgetChanged(ctx, st)
+		}
+	}
+	if len(calls) > 1 {
+		r.graph.record(calls[len(calls)-2], d.key, err)
+	}
+	if d.onSlowCall != nil && elapsed >= d.slowThreshold {
+		d.onSlowCall(SlowCall{Key: d.key, Chain: calls, Duration: elapsed, Err: err})
+	}
+
+	return res, err
+}
+
+func (r *Registry) callHandler(ctx context.Context, d *dispatch, im *impl, h HandlerFunc, req any) (res any, err error) {
+	im.inFlight.Add(1)
+	defer im.inFlight.Add(-1)
+	defer r.recoverPanic(d.key, im, d.panics, &err)
+	if d.watchdog != nil {
+		defer d.watchdog.leave(d.watchdog.enter(ctx, d.key))
+	}
+
+	if d.profiled {
+		pprof.Do(ctx, pprof.Labels(ProfileLabel, d.key), func(ctx context.Context) {
+			res, err = h(ctx, req)
+		})
+		return res, err
+	}
+	return h(ctx, req)
+}
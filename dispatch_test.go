@@ -0,0 +1,110 @@
+package irpc
+
+import (
+	"context"
+	"testing"
+)
+
+type echoReq struct{ N int }
+type echoRes struct{ N int }
+
+// echoResult is returned by every echo handler, so that the allocations
+// counted are the registry's own.
+var echoResult = &echoRes{}
+
+type echoContract interface {
+	Echo(ctx context.Context, req *echoReq) (*echoRes, error)
+}
+
+type echoImpl struct{}
+
+func (*echoImpl) Echo(ctx context.Context, req *echoReq) (*echoRes, error) {
+	return echoResult, nil
+}
+
+// typedEchoContract has the handlers cmd/irpcgen would generate for it.
+type typedEchoContract interface {
+	Echo(ctx context.Context, req *echoReq) (*echoRes, error)
+}
+
+func init() {
+	RegisterHandlerFactory(func(impl typedEchoContract) map[string]HandlerFunc {
+		return map[string]HandlerFunc{
+			"Echo": func(ctx context.Context, req any) (any, error) {
+				r, ok := req.(*echoReq)
+				if !ok && req != nil {
+					return nil, Errorf(InvalidArgument, "request is %T, want *echoReq", req)
+				}
+				return impl.Echo(ctx, r)
+			},
+		}
+	})
+}
+
+func newEchoRegistry() *Registry {
+	r := NewRegistry(Config{})
+	r.Register("Plain.Echo", func(ctx context.Context, req any) (any, error) {
+		return echoResult, nil
+	})
+	r.RegisterContract("Echo", (*echoContract)(nil), &echoImpl{})
+	r.RegisterContract("TypedEcho", (*typedEchoContract)(nil), &echoImpl{})
+	return r
+}
+
+// The allocation left on every path is the context of the call, which
+// carries the call chain and which the handler may keep. A contract
+// method called through reflection adds the result slice of reflect and
+// the boxing of its error result.
+var callAllocs = []struct {
+	key    string
+	allocs float64
+}{
+	{"Plain.Echo", 1},
+	{"TypedEcho.Echo", 1},
+	{"Echo.Echo", 3},
+}
+
+func TestCallAllocs(t *testing.T) {
+	r := newEchoRegistry()
+	ctx := context.Background()
+	req := &echoReq{}
+
+	for _, tt := range callAllocs {
+		if _, err := r.Call(ctx, tt.key, req); err != nil {
+			t.Fatalf("%s: %v", tt.key, err)
+		}
+		got := testing.AllocsPerRun(1000, func() {
+			r.Call(ctx, tt.key, req)
+		})
+		if got > tt.allocs {
+			t.Errorf("%s: %v allocations per call, want at most %v", tt.key, got, tt.allocs)
+		}
+	}
+}
+
+func TestTypedHandlers(t *testing.T) {
+	r := newEchoRegistry()
+	ctx := context.Background()
+
+	if res, err := r.Call(ctx, "TypedEcho.Echo", nil); err != nil || res != echoResult {
+		t.Fatalf("nil request: got %v, %v", res, err)
+	}
+	if _, err := r.Call(ctx, "TypedEcho.Echo", 1); CodeOf(err) != InvalidArgument {
+		t.Fatalf("wrong request type: got %v, want InvalidArgument", err)
+	}
+}
+
+func BenchmarkCall(b *testing.B) {
+	r := newEchoRegistry()
+	ctx := context.Background()
+	req := &echoReq{}
+
+	for _, tt := range callAllocs {
+		b.Run(tt.key, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				r.Call(ctx, tt.key, req)
+			}
+		})
+	}
+}
@@ -0,0 +1,291 @@
+package irpc
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultVisibilityTimeout is the visibility timeout of durable calls
+// unless set with WithVisibilityTimeout.
+const DefaultVisibilityTimeout = 5 * time.Minute
+
+// DeferredCall is a Notify-style call persisted in a DeferredStore until
+// it has been dispatched.
+type DeferredCall struct {
+	ID        string
+	Key       string
+	Payload   []byte
+	Metadata  Metadata
+	Priority  Priority
+	CreatedAt time.Time
+	// Deliveries counts how many times the call was dispatched.
+	Deliveries int
+	// VisibleAt is when the current delivery expires: until then, the call
+	// is skipped by Redispatch. The zero time means it is not delivered.
+	VisibleAt time.Time
+}
+
+// DeferredStore persists durable calls so that they survive a process
+// restart. Save replaces a call saved with the same ID. Implementations
+// must be safe for concurrent use.
+type DeferredStore interface {
+	Save(ctx context.Context, call DeferredCall) error
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context) ([]DeferredCall, error)
+}
+
+// DeferredLeaser is implemented by deferred stores shared between
+// processes. Lease atomically claims the call id until the given time,
+// incrementing its deliveries, and reports false if the call is gone or
+// another delivery of it has not expired yet. Without it, Redispatch
+// claims calls with Save, which is only safe within a single process.
+type DeferredLeaser interface {
+	Lease(ctx context.Context, id string, until time.Time) (bool, error)
+}
+
+// DeferredOption configures the deferred store.
+type DeferredOption func(*deferredConfig)
+
+type deferredConfig struct {
+	visibility time.Duration
+}
+
+// WithVisibilityTimeout sets how long a dispatched durable call may take
+// to be acknowledged, queueing and redelivery backoff included, before
+// Redispatch delivers it again. The default is DefaultVisibilityTimeout.
+func WithVisibilityTimeout(d time.Duration) DeferredOption {
+	return func(c *deferredConfig) {
+		c.visibility = d
+	}
+}
+
+// SetDeferredStore sets the store used by NotifyDurable and Redispatch.
+func (r *Registry) SetDeferredStore(s DeferredStore, opts ...DeferredOption) {
+	c := deferredConfig{visibility: DefaultVisibilityTimeout}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	r.mu.Lock()
+	r.deferred = s
+	r.deferredOpts = c
+	r.mu.Unlock()
+}
+
+func (r *Registry) deferredStore(key string) (DeferredStore, time.Duration, error) {
+	r.mu.RLock()
+	s, visibility := r.deferred, r.deferredOpts.visibility
+	r.mu.RUnlock()
+
+	if s == nil {
+		return nil, 0, &Error{Code: FailedPrecondition, Key: key, Message: "no deferred store configured"}
+	}
+	return s, visibility, nil
+}
+
+// NotifyDurable is like Notify, but persists the call in the deferred store
+// before queueing it, with at-least-once semantics: the call is only
+// removed from the store once its handler has succeeded, or once it has
+// been moved to the dead-letter store after failing. A call that is not
+// acknowledged within the visibility timeout, because the process stopped
+// or the handler hangs, is delivered again by the next Redispatch, so
+// handlers of durable calls must tolerate duplicates. Every delivery
+// carries the same IdempotencyKey, for EnableIdempotency to deduplicate.
+//
+// The request is serialized with the registry's Codec, and decoded into the
+// request type declared by the key's contract.
+func (r *Registry) NotifyDurable(ctx context.Context, key string, req any, opts ...AsyncOption) error {
+	store, visibility, err := r.deferredStore(key)
+	if err != nil {
+		return err
+	}
+
+	payload, md, err := r.encodeRequest(ctx, key, req)
+	if err != nil {
+		return err
+	}
+
+	t := newAsyncTask(context.WithoutCancel(ctx), key, nil, opts)
+	now := time.Now()
+	id := newID()
+	if md[IdempotencyKey] == "" {
+		md[IdempotencyKey] = id
+	}
+	call := DeferredCall{
+		ID:         id,
+		Key:        key,
+		Payload:    payload,
+		Metadata:   md,
+		Priority:   t.priority,
+		CreatedAt:  now,
+		Deliveries: 1,
+		VisibleAt:  now.Add(visibility),
+	}
+	if err := store.Save(ctx, call); err != nil {
+		return err
+	}
+
+	return r.dispatchDeferred(store, visibility, call, opts)
+}
+
+// Redispatch queues every call of the deferred store that is not being
+// delivered, or whose delivery exceeded the visibility timeout. It returns
+// the number of calls queued.
+//
+// Calls left by a previous process, and calls whose delivery was lost or
+// hangs, are only delivered again by Redispatch, so a registry with a
+// deferred store must run RunDeferred, or call Redispatch periodically
+// itself, once all contracts are registered. Calling it once at startup
+// does not redeliver calls that expire later.
+func (r *Registry) Redispatch(ctx context.Context) (int, error) {
+	store, visibility, err := r.deferredStore("")
+	if err != nil {
+		return 0, err
+	}
+
+	calls, err := store.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	now := time.Now()
+	for _, call := range calls {
+		if call.VisibleAt.After(now) {
+			continue
+		}
+		until := now.Add(visibility)
+		if leaser, ok := store.(DeferredLeaser); ok {
+			leased, err := leaser.Lease(ctx, call.ID, until)
+			if err != nil {
+				return n, err
+			}
+			if !leased {
+				continue
+			}
+			call.Deliveries++
+			call.VisibleAt = until
+		} else {
+			call.Deliveries++
+			call.VisibleAt = until
+			if err := store.Save(ctx, call); err != nil {
+				return n, err
+			}
+		}
+
+		if err := r.dispatchDeferred(store, visibility, call, nil); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// RunDeferred calls Redispatch now and then every interval until ctx is
+// done, so that calls whose delivery expired are delivered again without a
+// restart. A failing Redispatch is emitted as an EventRedispatchError and
+// retried at the next interval.
+//
+//	// after registering contracts
+//	go registry.RunDeferred(ctx, time.Minute)
+func (r *Registry) RunDeferred(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := r.Redispatch(ctx); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			r.emit(ctx, TelemetryEvent{Name: EventRedispatchError, Attrs: []slog.Attr{
+				slog.Int("queued", n),
+				slog.String("error", err.Error()),
+			}})
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Registry) dispatchDeferred(store DeferredStore, visibility time.Duration, call DeferredCall, opts []AsyncOption) error {
+	ctx := WithMetadata(context.Background(), call.Metadata)
+	opts = append([]AsyncOption{WithPriority(call.Priority)}, opts...)
+	t := newAsyncTask(ctx, call.Key, nil, opts)
+	t.created, t.payload = call.CreatedAt, call.Payload
+	t.ack = func() {
+		_ = store.Delete(ctx, call.ID)
+	}
+	t.extend = func(d time.Duration) {
+		call.VisibleAt = time.Now().Add(d + visibility)
+		_ = store.Save(ctx, call)
+	}
+
+	req, err := r.decodeRequest(call.Key, call.Metadata, call.Payload)
+	if err != nil {
+		r.settle(t, nil, err)
+		return nil
+	}
+	t.req = req
+	return r.submit(t)
+}
+
+// MemoryDeferredStore is an in-memory DeferredStore. It does not survive
+// restarts and is meant for tests and development.
+type MemoryDeferredStore struct {
+	mu    sync.Mutex
+	calls map[string]DeferredCall
+}
+
+// NewMemoryDeferredStore returns an empty MemoryDeferredStore.
+func NewMemoryDeferredStore() *MemoryDeferredStore {
+	return &MemoryDeferredStore{calls: make(map[string]DeferredCall)}
+}
+
+func (s *MemoryDeferredStore) Save(ctx context.Context, call DeferredCall) error {
+	s.mu.Lock()
+	s.calls[call.ID] = call
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryDeferredStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	delete(s.calls, id)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryDeferredStore) Lease(ctx context.Context, id string, until time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	call, ok := s.calls[id]
+	if !ok || call.VisibleAt.After(time.Now()) {
+		return false, nil
+	}
+	call.Deliveries++
+	call.VisibleAt = until
+	s.calls[id] = call
+	return true, nil
+}
+
+// List returns the stored calls, oldest first.
+func (s *MemoryDeferredStore) List(ctx context.Context) ([]DeferredCall, error) {
+	s.mu.Lock()
+	calls := make([]DeferredCall, 0, len(s.calls))
+	for _, c := range s.calls {
+		calls = append(calls, c)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(calls, func(i, j int) bool {
+		return calls[i].CreatedAt.Before(calls[j].CreatedAt)
+	})
+	return calls, nil
+}
@@ -0,0 +1,171 @@
+package irpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func deferredStores(t *testing.T) map[string]DeferredStore {
+	fs, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]DeferredStore{
+		"memory":       NewMemoryDeferredStore(),
+		"memory store": NewDeferredStore(NewMemoryStore()),
+		"file store":   NewDeferredStore(fs),
+	}
+}
+
+func TestDeferredLeaseExpiry(t *testing.T) {
+	ctx := context.Background()
+	for name, store := range deferredStores(t) {
+		t.Run(name, func(t *testing.T) {
+			leaser := store.(DeferredLeaser)
+			call := DeferredCall{ID: "c1", Key: "Mail.Send", CreatedAt: time.Now(), Deliveries: 1}
+			if err := store.Save(ctx, call); err != nil {
+				t.Fatal(err)
+			}
+
+			until := time.Now().Add(50 * time.Millisecond)
+			if ok, err := leaser.Lease(ctx, "c1", until); err != nil || !ok {
+				t.Fatalf("first lease: %v, %v", ok, err)
+			}
+			if ok, err := leaser.Lease(ctx, "c1", until); err != nil || ok {
+				t.Fatalf("lease before expiry: %v, %v, want it refused", ok, err)
+			}
+
+			time.Sleep(time.Until(until) + 10*time.Millisecond)
+			if ok, err := leaser.Lease(ctx, "c1", time.Now().Add(time.Minute)); err != nil || !ok {
+				t.Fatalf("lease after expiry: %v, %v", ok, err)
+			}
+			calls, err := store.List(ctx)
+			if err != nil || len(calls) != 1 || calls[0].Deliveries != 3 {
+				t.Fatalf("got %+v, %v, want one call delivered 3 times", calls, err)
+			}
+
+			if err := store.Delete(ctx, "c1"); err != nil {
+				t.Fatal(err)
+			}
+			if ok, err := leaser.Lease(ctx, "c1", time.Now()); err != nil || ok {
+				t.Fatalf("lease of a deleted call: %v, %v", ok, err)
+			}
+		})
+	}
+}
+
+func TestDeferredConcurrentLease(t *testing.T) {
+	ctx := context.Background()
+	for name, store := range deferredStores(t) {
+		t.Run(name, func(t *testing.T) {
+			leaser := store.(DeferredLeaser)
+			if err := store.Save(ctx, DeferredCall{ID: "c1", Key: "Mail.Send", CreatedAt: time.Now()}); err != nil {
+				t.Fatal(err)
+			}
+
+			var wg sync.WaitGroup
+			var won atomic.Int32
+			until := time.Now().Add(time.Minute)
+			for range 16 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					ok, err := leaser.Lease(ctx, "c1", until)
+					if err != nil {
+						t.Error(err)
+					}
+					if ok {
+						won.Add(1)
+					}
+				}()
+			}
+			wg.Wait()
+
+			if n := won.Load(); n != 1 {
+				t.Fatalf("%d concurrent leases won, want 1", n)
+			}
+		})
+	}
+}
+
+func TestRedispatchExpiredDelivery(t *testing.T) {
+	r := NewRegistry(Config{AsyncWorkers: 2})
+	store := NewMemoryDeferredStore()
+	r.SetDeferredStore(store, WithVisibilityTimeout(20*time.Millisecond))
+
+	release := make(chan struct{})
+	keys := make(chan string, 2)
+	r.Register("Mail.Send", func(ctx context.Context, req any) (any, error) {
+		key, _ := MetadataValue(ctx, IdempotencyKey)
+		keys <- key
+		<-release
+		return nil, nil
+	})
+
+	ctx := context.Background()
+	if err := r.NotifyDurable(ctx, "Mail.Send", "hello"); err != nil {
+		t.Fatal(err)
+	}
+	first := <-keys
+
+	if n, err := r.Redispatch(ctx); err != nil || n != 0 {
+		t.Fatalf("redispatch within the visibility timeout: %d, %v", n, err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if n, err := r.Redispatch(ctx); err != nil || n != 1 {
+		t.Fatalf("redispatch after the visibility timeout: %d, %v", n, err)
+	}
+	if second := <-keys; second != first {
+		t.Fatalf("redelivery has idempotency key %q, want %q", second, first)
+	}
+	calls, _ := store.List(ctx)
+	if len(calls) != 1 || calls[0].Deliveries != 2 {
+		t.Fatalf("got %+v, want one call delivered twice", calls)
+	}
+
+	close(release)
+	deadline := time.Now().Add(time.Second)
+	for {
+		calls, _ := store.List(ctx)
+		if len(calls) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("acknowledged call still stored: %+v", calls)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// brokenStore is a DeferredStore whose List fails.
+type brokenStore struct{ DeferredStore }
+
+func (brokenStore) List(context.Context) ([]DeferredCall, error) {
+	return nil, errors.New("store unavailable")
+}
+
+func TestRunDeferredEmitsErrors(t *testing.T) {
+	r := NewRegistry(Config{})
+	events := &eventRecorder{}
+	r.ExportTelemetry(events)
+	r.SetDeferredStore(brokenStore{NewMemoryDeferredStore()})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- r.RunDeferred(ctx, time.Millisecond) }()
+	waitFor(t, "two redispatch errors", func() bool { return len(events.named(EventRedispatchError)) >= 2 })
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("RunDeferred() = %v, want context.Canceled", err)
+	}
+
+	for _, a := range events.named(EventRedispatchError)[0].Attrs {
+		if a.Key == "error" && a.Value.String() != "store unavailable" {
+			t.Errorf("event error = %q, want the store's", a.Value)
+		}
+	}
+}
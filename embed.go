@@ -0,0 +1,57 @@
+package irpc
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// WithSubContracts names the interfaces embedded in the contract being
+// registered, so that introspection records which one declared each key.
+// Go flattens embedded interfaces, so they must be listed explicitly:
+//
+//	type ExamContract interface {
+//		ReadContract
+//		WriteContract
+//	}
+//
+//	registry.RegisterContract("Exam", (*ExamContract)(nil), impl,
+//		irpc.WithSubContracts((*ReadContract)(nil), (*WriteContract)(nil)))
+//
+// A method declared by several of them is attributed to the first.
+func WithSubContracts(ifaces ...any) RegisterOption {
+	return func(o *registerOptions) {
+		for _, iface := range ifaces {
+			o.subContracts = append(o.subContracts, reflect.TypeOf(iface).Elem())
+		}
+	}
+}
+
+// checkSubContracts panics if a sub-contract is not part of contract.
+func (o *registerOptions) checkSubContracts(contract reflect.Type) {
+	for _, sub := range o.subContracts {
+		if sub.Kind() != reflect.Interface || !contract.Implements(sub) {
+			panic(fmt.Sprintf("irpc: %s is not a sub-contract of %s", sub, contract))
+		}
+	}
+}
+
+// contractOf returns the name of the interface declaring method.
+func (o *registerOptions) contractOf(contract reflect.Type, method string) string {
+	for _, sub := range o.subContracts {
+		if _, ok := sub.MethodByName(method); ok {
+			return sub.Name()
+		}
+	}
+	return contract.Name()
+}
+
+// setContract records the interface declaring key and whether its method
+// takes no context.
+func (r *Registry) setContract(key, contract string, noContext bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if e := r.entries[key]; e != nil {
+		e.contract, e.noContext = contract, noContext
+	}
+}
@@ -0,0 +1,111 @@
+package irpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Code classifies an irpc error. The values match the gRPC status codes so
+// they can be mapped one to one when a call crosses a process boundary.
+type Code int
+
+const (
+	OK Code = iota
+	Canceled
+	Unknown
+	InvalidArgument
+	DeadlineExceeded
+	NotFound
+	AlreadyExists
+	PermissionDenied
+	ResourceExhausted
+	FailedPrecondition
+	Aborted
+	OutOfRange
+	Unimplemented
+	Internal
+	Unavailable
+	DataLoss
+	Unauthenticated
+)
+
+var codeNames = [...]string{
+	OK:                 "OK",
+	Canceled:           "Canceled",
+	Unknown:            "Unknown",
+	InvalidArgument:    "InvalidArgument",
+	DeadlineExceeded:   "DeadlineExceeded",
+	NotFound:           "NotFound",
+	AlreadyExists:      "AlreadyExists",
+	PermissionDenied:   "PermissionDenied",
+	ResourceExhausted:  "ResourceExhausted",
+	FailedPrecondition: "FailedPrecondition",
+	Aborted:            "Aborted",
+	OutOfRange:         "OutOfRange",
+	Unimplemented:      "Unimplemented",
+	Internal:           "Internal",
+	Unavailable:        "Unavailable",
+	DataLoss:           "DataLoss",
+	Unauthenticated:    "Unauthenticated",
+}
+
+func (c Code) String() string {
+	if c >= 0 && int(c) < len(codeNames) {
+		return codeNames[c]
+	}
+	return fmt.Sprintf("Code(%d)", int(c))
+}
+
+// Error is the error type produced by the registry itself, and the one
+// handlers should return when they want callers to see a specific Code.
+type Error struct {
+	Code    Code
+	Key     string
+	Message string
+	Err     error
+	// Details carries typed payloads for programmatic handling, such as
+	// FieldViolation or RetryInfo. Read them with ErrorDetail.
+	Details []any
+}
+
+func (e *Error) Error() string {
+	s := "irpc: " + e.Message
+	if e.Err != nil {
+		if e.Message == "" {
+			return "irpc: " + e.Err.Error()
+		}
+		s += ": " + e.Err.Error()
+	}
+	return s
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Errorf returns an *Error with the given code and formatted message.
+func Errorf(code Code, format string, args ...any) error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// CodeOf returns the code of err: OK for nil, the code of the outermost
+// *Error in the chain, Canceled or DeadlineExceeded for context errors,
+// and Unknown otherwise.
+func CodeOf(err error) Code {
+	if err == nil {
+		return OK
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	if errors.Is(err, context.Canceled) {
+		return Canceled
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return DeadlineExceeded
+	}
+	return Unknown
+}
@@ -0,0 +1,77 @@
+package irpc
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Event records one successful call of an event-producing method.
+type Event struct {
+	ID       string    `json:"id"`
+	Key      string    `json:"key"`
+	Request  any       `json:"request"`
+	Response any       `json:"response"`
+	Time     time.Time `json:"time"`
+	Metadata Metadata  `json:"metadata,omitempty"`
+	Chain    Chain     `json:"chain,omitempty"`
+}
+
+// EventStore receives the events of event-producing methods, e.g. an
+// append-only table or a stream. Append must be safe for concurrent use.
+type EventStore interface {
+	Append(ctx context.Context, ev Event) error
+}
+
+// EmitEvents marks the keys matching pattern as event-producing: every
+// successful call appends an Event to store before returning. Failed
+// appends are reported to onError, which may be nil.
+//
+//	registry.EmitEvents("Billing.*", store, func(ev irpc.Event, err error) {
+//		log.Printf("audit: lost event %s for %s: %v", ev.ID, ev.Key, err)
+//	})
+func (r *Registry) EmitEvents(pattern string, store EventStore, onError func(Event, error)) error {
+	return r.UseFor(pattern, func(key string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, req any) (any, error) {
+			res, err := next(ctx, req)
+			if err != nil {
+				return res, err
+			}
+
+			ev := Event{
+				ID:       newID(),
+				Key:      key,
+				Request:  req,
+				Response: res,
+				Time:     time.Now(),
+				Metadata: MetadataFromContext(ctx),
+				Chain:    ChainFromContext(ctx),
+			}
+			if appendErr := store.Append(ctx, ev); appendErr != nil && onError != nil {
+				onError(ev, appendErr)
+			}
+			return res, nil
+		}
+	})
+}
+
+// MemoryEventStore is an EventStore keeping events in memory, for tests
+// and development.
+type MemoryEventStore struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (s *MemoryEventStore) Append(ctx context.Context, ev Event) error {
+	s.mu.Lock()
+	s.events = append(s.events, ev)
+	s.mu.Unlock()
+	return nil
+}
+
+// Events returns the events appended so far, oldest first.
+func (s *MemoryEventStore) Events() []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Event(nil), s.events...)
+}
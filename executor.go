@@ -0,0 +1,489 @@
+package irpc
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Task is a unit of async work handed to an Executor.
+type Task struct {
+	Key      string
+	Priority Priority
+	Run      func()
+
+	// Ctx bounds how long Submit may block under the Block policy. It may
+	// be nil.
+	Ctx context.Context
+	// Reject, if set, is called with the reason when an already queued
+	// task is dropped instead of run, e.g. shed for higher-priority work.
+	Reject func(error)
+	// Deadline, if set, is when the task's result stops being useful.
+	// Within a priority, tasks with a deadline run earliest deadline first,
+	// before tasks without one.
+	Deadline time.Time
+}
+
+// Executor runs the calls queued with CallAsync and Notify. Submit must not
+// block on the task itself; it returns an error when the task is rejected.
+type Executor interface {
+	Submit(t Task) error
+	Shutdown(ctx context.Context) error
+	Stats() ExecutorStats
+}
+
+// RejectionPolicy decides what happens to a task submitted while the queue
+// of a PoolExecutor is full.
+type RejectionPolicy int
+
+const (
+	// Reject fails the submission with ResourceExhausted.
+	Reject RejectionPolicy = iota
+	// CallerRuns runs the task on the submitting goroutine, which slows
+	// producers down to the pace of the pool.
+	CallerRuns
+	// Block waits for space in the queue until the task's Ctx is done or
+	// PoolConfig.BlockTimeout elapses, then fails with ResourceExhausted.
+	Block
+	// ShedLowest drops the oldest queued task of the lowest priority below
+	// the new task's to make room for it, and rejects the new task if
+	// there is none.
+	ShedLowest
+)
+
+// PoolConfig configures a PoolExecutor.
+type PoolConfig struct {
+	// Size is the number of worker goroutines. Zero uses GOMAXPROCS.
+	Size int
+	// QueueLen bounds the number of queued tasks across all priorities.
+	// Zero means unbounded.
+	QueueLen int
+	// Rejection applies when the queue is full.
+	Rejection RejectionPolicy
+	// BlockTimeout bounds the wait of the Block policy. Zero waits until
+	// the task's Ctx is done, or forever without one.
+	BlockTimeout time.Duration
+	// Estimate returns the expected run time of tasks of key. Queued tasks
+	// that cannot finish before their deadline given that estimate are
+	// expired, i.e. rejected with DeadlineExceeded, instead of run. Nil
+	// estimates zero: tasks expire once their deadline has passed.
+	Estimate func(key string) time.Duration
+}
+
+// QueueStats describes the queue of one priority.
+type QueueStats struct {
+	Priority Priority      `json:"priority"`
+	Depth    int           `json:"depth"`
+	Enqueued uint64        `json:"enqueued"`
+	Started  uint64        `json:"started"`
+	Wait     time.Duration `json:"wait_ns"`
+	// Oldest is how long the task at the head of the queue has waited.
+	Oldest time.Duration `json:"oldest_ns"`
+}
+
+// MeanWait returns the average time tasks spent queued before starting.
+func (s QueueStats) MeanWait() time.Duration {
+	if s.Started == 0 {
+		return 0
+	}
+	return s.Wait / time.Duration(s.Started)
+}
+
+// ExecutorStats is a snapshot of an Executor's state.
+type ExecutorStats struct {
+	Workers   int          `json:"workers"`
+	Busy      int          `json:"busy"`
+	Completed uint64       `json:"completed"`
+	Rejected  uint64       `json:"rejected"`
+	Shed      uint64       `json:"shed"`
+	Expired   uint64       `json:"expired"`
+	Queues    []QueueStats `json:"queues"`
+}
+
+// Utilization returns the fraction of workers currently running a task.
+func (s ExecutorStats) Utilization() float64 {
+	if s.Workers == 0 {
+		return 0
+	}
+	return float64(s.Busy) / float64(s.Workers)
+}
+
+// Depth returns the number of queued tasks across all priorities.
+func (s ExecutorStats) Depth() int {
+	n := 0
+	for _, q := range s.Queues {
+		n += q.Depth
+	}
+	return n
+}
+
+// Oldest returns how long the oldest queued task has waited.
+func (s ExecutorStats) Oldest() time.Duration {
+	var d time.Duration
+	for _, q := range s.Queues {
+		d = max(d, q.Oldest)
+	}
+	return d
+}
+
+type queuedTask struct {
+	Task
+	enqueued time.Time
+}
+
+type queueCounters struct {
+	enqueued uint64
+	started  uint64
+	wait     time.Duration
+}
+
+// PoolExecutor runs tasks on a fixed number of workers, always taking from
+// the highest non-empty priority the task with the earliest deadline, or
+// the oldest task if none has a deadline.
+type PoolExecutor struct {
+	config PoolConfig
+
+	mu        sync.Mutex
+	cond      *sync.Cond
+	space     *sync.Cond
+	queues    [numPriorities][]queuedTask
+	counters  [numPriorities]queueCounters
+	queued    int
+	busy      int
+	completed uint64
+	rejected  uint64
+	shed      uint64
+	expired   uint64
+	closed    bool
+	wg        sync.WaitGroup
+
+	// expiry sweeps the queues when the earliest queued deadline passes,
+	// at expiryAt.
+	expiry   *time.Timer
+	expiryAt time.Time
+}
+
+// NewPoolExecutor starts a PoolExecutor.
+func NewPoolExecutor(config PoolConfig) *PoolExecutor {
+	if config.Size <= 0 {
+		config.Size = runtime.GOMAXPROCS(0)
+	}
+
+	e := &PoolExecutor{config: config}
+	e.cond = sync.NewCond(&e.mu)
+	e.space = sync.NewCond(&e.mu)
+
+	e.wg.Add(config.Size)
+	for i := 0; i < config.Size; i++ {
+		go e.worker()
+	}
+	return e
+}
+
+func (e *PoolExecutor) Submit(t Task) error {
+	t.Priority = t.Priority.clamp()
+
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return &Error{Code: Unavailable, Key: t.Key, Message: "executor is shut down"}
+	}
+
+	if e.full() {
+		var shed *queuedTask
+		switch e.config.Rejection {
+		case CallerRuns:
+			e.rejected++
+			e.mu.Unlock()
+			t.Run()
+			return nil
+
+		case Block:
+			if !e.waitForSpace(t) {
+				e.rejected++
+				e.mu.Unlock()
+				return &Error{Code: ResourceExhausted, Key: t.Key, Message: "executor queue is full"}
+			}
+
+		case ShedLowest:
+			if shed = e.shedBelow(t.Priority); shed == nil {
+				e.rejected++
+				e.mu.Unlock()
+				return &Error{Code: ResourceExhausted, Key: t.Key, Message: "executor queue is full"}
+			}
+
+		default:
+			e.rejected++
+			e.mu.Unlock()
+			return &Error{Code: ResourceExhausted, Key: t.Key, Message: "executor queue is full"}
+		}
+
+		if e.closed {
+			e.mu.Unlock()
+			return &Error{Code: Unavailable, Key: t.Key, Message: "executor is shut down"}
+		}
+		if shed != nil {
+			defer rejectShed(shed)
+		}
+	}
+
+	e.enqueue(queuedTask{Task: t, enqueued: time.Now()})
+	e.counters[t.Priority].enqueued++
+	e.queued++
+	e.cond.Signal()
+	e.mu.Unlock()
+	return nil
+}
+
+// enqueue inserts t in the queue of its priority, after the tasks with an
+// earlier or equal deadline, and tasks without a deadline at the end.
+// e.mu must be held.
+func (e *PoolExecutor) enqueue(t queuedTask) {
+	q := e.queues[t.Priority]
+	i := len(q)
+	if !t.Deadline.IsZero() {
+		i = sort.Search(len(q), func(i int) bool {
+			return q[i].Deadline.IsZero() || q[i].Deadline.After(t.Deadline)
+		})
+		e.armExpiry(t.Deadline)
+	}
+	q = append(q, queuedTask{})
+	copy(q[i+1:], q[i:])
+	q[i] = t
+	e.queues[t.Priority] = q
+}
+
+// expire removes the queued tasks at the head of each queue that can no
+// longer meet their deadline, and returns them. e.mu must be held.
+func (e *PoolExecutor) expire(now time.Time) []queuedTask {
+	var expired []queuedTask
+	for p := range e.queues {
+		q := e.queues[p]
+		n := 0
+		for n < len(q) && !q[n].Deadline.IsZero() && now.Add(e.estimate(q[n].Key)).After(q[n].Deadline) {
+			n++
+		}
+		if n == 0 {
+			continue
+		}
+		expired = append(expired, q[:n]...)
+		clear(q[:n])
+		e.queues[p] = q[n:]
+		e.queued -= n
+		e.expired += uint64(n)
+		e.space.Broadcast()
+	}
+	return expired
+}
+
+func (e *PoolExecutor) estimate(key string) time.Duration {
+	if e.config.Estimate == nil {
+		return 0
+	}
+	return e.config.Estimate(key)
+}
+
+// armExpiry makes the queues be swept at deadline, unless a sweep is due
+// earlier. e.mu must be held.
+func (e *PoolExecutor) armExpiry(deadline time.Time) {
+	if !e.expiryAt.IsZero() && !deadline.Before(e.expiryAt) {
+		return
+	}
+	e.expiryAt = deadline
+	if e.expiry == nil {
+		e.expiry = time.AfterFunc(time.Until(deadline), e.sweep)
+	} else {
+		e.expiry.Reset(time.Until(deadline))
+	}
+}
+
+// sweep expires the tasks whose deadline has passed, and arms the next
+// sweep for the earliest deadline left.
+func (e *PoolExecutor) sweep() {
+	e.mu.Lock()
+	e.expiryAt = time.Time{}
+	expired := e.expire(time.Now())
+	for _, q := range e.queues {
+		if len(q) > 0 && !q[0].Deadline.IsZero() {
+			e.armExpiry(q[0].Deadline)
+		}
+	}
+	e.mu.Unlock()
+
+	rejectExpired(expired)
+}
+
+func rejectExpired(tasks []queuedTask) {
+	for _, t := range tasks {
+		if t.Reject != nil {
+			t.Reject(&Error{Code: DeadlineExceeded, Key: t.Key, Message: "deadline can no longer be met"})
+		}
+	}
+}
+
+func (e *PoolExecutor) full() bool {
+	return e.config.QueueLen > 0 && e.queued >= e.config.QueueLen
+}
+
+// waitForSpace blocks until the queue has room, reporting false when the
+// task's context or the block timeout ran out first. e.mu must be held.
+func (e *PoolExecutor) waitForSpace(t Task) bool {
+	ctx := t.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if e.config.BlockTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.config.BlockTimeout)
+		defer cancel()
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		e.mu.Lock()
+		e.space.Broadcast()
+		e.mu.Unlock()
+	})
+	defer stop()
+
+	for e.full() && !e.closed {
+		if ctx.Err() != nil {
+			return false
+		}
+		e.space.Wait()
+	}
+	return true
+}
+
+// shedBelow removes the oldest queued task of the lowest priority below p.
+// e.mu must be held.
+func (e *PoolExecutor) shedBelow(p Priority) *queuedTask {
+	for low := 0; low < int(p); low++ {
+		q := e.queues[low]
+		if len(q) == 0 {
+			continue
+		}
+
+		t := q[0]
+		q[0] = queuedTask{}
+		e.queues[low] = q[1:]
+		e.queued--
+		e.shed++
+		return &t
+	}
+	return nil
+}
+
+func rejectShed(t *queuedTask) {
+	if t.Reject != nil {
+		t.Reject(&Error{Code: ResourceExhausted, Key: t.Key, Message: "shed for higher-priority work"})
+	}
+}
+
+func (e *PoolExecutor) next() (Task, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for {
+		if expired := e.expire(time.Now()); len(expired) > 0 {
+			e.mu.Unlock()
+			rejectExpired(expired)
+			e.mu.Lock()
+			continue
+		}
+		for p := numPriorities - 1; p >= 0; p-- {
+			q := e.queues[p]
+			if len(q) == 0 {
+				continue
+			}
+
+			t := q[0]
+			q[0] = queuedTask{}
+			e.queues[p] = q[1:]
+			e.queued--
+			e.busy++
+			e.space.Signal()
+
+			c := &e.counters[p]
+			c.started++
+			c.wait += time.Since(t.enqueued)
+			return t.Task, true
+		}
+
+		if e.closed {
+			return Task{}, false
+		}
+		e.cond.Wait()
+	}
+}
+
+func (e *PoolExecutor) worker() {
+	defer e.wg.Done()
+	for {
+		t, ok := e.next()
+		if !ok {
+			return
+		}
+
+		t.Run()
+
+		e.mu.Lock()
+		e.busy--
+		e.completed++
+		e.mu.Unlock()
+	}
+}
+
+// Shutdown stops accepting tasks and waits for the queued ones to finish.
+func (e *PoolExecutor) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	e.closed = true
+	e.cond.Broadcast()
+	e.space.Broadcast()
+	e.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		e.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (e *PoolExecutor) Stats() ExecutorStats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	s := ExecutorStats{
+		Workers:   e.config.Size,
+		Busy:      e.busy,
+		Completed: e.completed,
+		Rejected:  e.rejected,
+		Shed:      e.shed,
+		Expired:   e.expired,
+		Queues:    make([]QueueStats, 0, numPriorities),
+	}
+	now := time.Now()
+	for p := numPriorities - 1; p >= 0; p-- {
+		c := e.counters[p]
+		q := QueueStats{
+			Priority: Priority(p),
+			Depth:    len(e.queues[p]),
+			Enqueued: c.enqueued,
+			Started:  c.started,
+			Wait:     c.wait,
+		}
+		for _, t := range e.queues[p] {
+			q.Oldest = max(q.Oldest, now.Sub(t.enqueued))
+		}
+		s.Queues = append(s.Queues, q)
+	}
+	return s
+}
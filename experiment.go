@@ -0,0 +1,176 @@
+package irpc
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// ExperimentMetadataPrefix prefixes the metadata key under which the
+// variant assigned by an experiment is recorded, e.g.
+// "x-experiment-search-rewrite".
+const ExperimentMetadataPrefix = "x-experiment-"
+
+// Variant is one arm of an Experiment, served by the implementation Impl.
+// Weight is its relative share of the assigned callers.
+type Variant struct {
+	Impl   string
+	Weight int
+}
+
+// Experiment splits callers of a key between implementations. Callers are
+// assigned deterministically by hashing the metadata value AssignBy, so
+// the same caller always sees the same variant. Calls without that
+// metadata are not enrolled and go to the default implementation.
+type Experiment struct {
+	Name     string
+	AssignBy string
+	Variants []Variant
+}
+
+type experimentState struct {
+	stats *statsCollector
+}
+
+// RunExperiment routes key according to exp. The assigned variant is
+// recorded in the call metadata and in per-variant statistics available
+// from ExperimentStats.
+func (r *Registry) RunExperiment(key string, exp Experiment) error {
+	total, err := exp.check()
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.runExperiment(key, exp, total)
+	return nil
+}
+
+// runExperiment is RunExperiment with r.mu held and exp checked. An
+// experiment run again under the same name, e.g. with new weights, keeps
+// its statistics.
+func (r *Registry) runExperiment(key string, exp Experiment, total uint64) {
+	if r.experiments == nil {
+		r.experiments = make(map[string]*experimentState)
+	}
+	state := r.experiments[exp.Name]
+	if state == nil {
+		state = &experimentState{stats: newStatsCollector()}
+		r.experiments[exp.Name] = state
+	}
+
+	mdKey := ExperimentMetadataPrefix + exp.Name
+	e := r.ensureEntry(key)
+	var variants sync.Map // of variant name to *variantImpl
+	for _, v := range exp.Variants {
+		if im := findImpl(e.impls, v.Impl); im != nil {
+			variants.Store(v.Impl, newVariantImpl(im, state, v.Impl))
+		}
+	}
+
+	e.route = func(ctx context.Context, impls []*impl) (context.Context, *impl) {
+		subject, ok := MetadataValue(ctx, exp.AssignBy)
+		if !ok {
+			return ctx, nil
+		}
+
+		variant := exp.assign(subject, total)
+		im := findImpl(impls, variant)
+		if im == nil {
+			return ctx, nil
+		}
+
+		// The variant is built again only if its implementation was
+		// registered again since.
+		v, _ := variants.Load(variant)
+		if v == nil || v.(*variantImpl).base != im {
+			v = newVariantImpl(im, state, variant)
+			variants.Store(variant, v)
+		}
+
+		ctx = WithMetadataValue(ctx, mdKey, variant)
+		return ctx, &v.(*variantImpl).impl
+	}
+}
+
+// variantImpl is the implementation serving a variant of an experiment:
+// base, recording the variant's statistics. It keeps the name and
+// registration of base, and counts its calls in the in-flight calls of
+// base.
+type variantImpl struct {
+	impl
+	base *impl
+}
+
+func newVariantImpl(base *impl, state *experimentState, variant string) *variantImpl {
+	v := &variantImpl{base: base}
+	v.name, v.source, v.registeredAt = base.name, base.source, base.registeredAt
+	v.handler = func(ctx context.Context, req any) (any, error) {
+		base.inFlight.Add(1)
+		defer base.inFlight.Add(-1)
+
+		start := time.Now()
+		res, err := base.handler(ctx, req)
+		state.stats.record(variant, time.Since(start), err)
+		return res, err
+	}
+	return v
+}
+
+// check validates exp and returns the total weight of its variants.
+func (exp Experiment) check() (uint64, error) {
+	if exp.Name == "" || exp.AssignBy == "" {
+		return 0, errors.New("irpc: experiment requires Name and AssignBy")
+	}
+
+	var total uint64
+	for _, v := range exp.Variants {
+		if v.Weight <= 0 {
+			return 0, errors.New("irpc: experiment variant weights must be positive")
+		}
+		total += uint64(v.Weight)
+	}
+	if total == 0 {
+		return 0, errors.New("irpc: experiment requires at least one variant")
+	}
+	return total, nil
+}
+
+func (exp Experiment) assign(subject string, total uint64) string {
+	h := fnv.New64a()
+	h.Write([]byte(exp.Name))
+	h.Write([]byte{0})
+	h.Write([]byte(subject))
+
+	n := h.Sum64() % total
+	for _, v := range exp.Variants {
+		if n < uint64(v.Weight) {
+			return v.Impl
+		}
+		n -= uint64(v.Weight)
+	}
+	return exp.Variants[len(exp.Variants)-1].Impl
+}
+
+// ExperimentStats returns the call statistics of each variant of the
+// experiment name, keyed by implementation name.
+func (r *Registry) ExperimentStats(name string) map[string]KeyStats {
+	r.mu.RLock()
+	state := r.experiments[name]
+	r.mu.RUnlock()
+
+	if state == nil {
+		return nil
+	}
+	return state.stats.snapshot()
+}
+
+// VariantFromContext returns the variant of experiment name assigned to
+// the call described by ctx.
+func VariantFromContext(ctx context.Context, name string) (string, bool) {
+	return MetadataValue(ctx, ExperimentMetadataPrefix+name)
+}
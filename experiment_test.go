@@ -0,0 +1,96 @@
+package irpc
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+)
+
+func newExperimentRegistry(t *testing.T) *Registry {
+	t.Helper()
+	r := NewRegistry(Config{})
+	for _, name := range []string{DefaultImpl, "rewrite"} {
+		r.RegisterImpl("Search.Query", name, func(ctx context.Context, req any) (any, error) {
+			return name, nil
+		})
+	}
+	err := r.RunExperiment("Search.Query", Experiment{
+		Name:     "search-rewrite",
+		AssignBy: "user",
+		Variants: []Variant{{Impl: "rewrite", Weight: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestExperimentVariantBuiltOnce(t *testing.T) {
+	r := newExperimentRegistry(t)
+
+	var built atomic.Int32
+	r.Use(func(key string, next HandlerFunc) HandlerFunc {
+		built.Add(1)
+		return next
+	})
+
+	ctx := WithMetadataValue(context.Background(), "user", "42")
+	for range 10 {
+		res, err := r.Call(ctx, "Search.Query", nil)
+		if err != nil || res != "rewrite" {
+			t.Fatalf("Call() = %v, %v, want rewrite", res, err)
+		}
+	}
+	if n := built.Load(); n != 1 {
+		t.Errorf("middleware chain built %d times, want once", n)
+	}
+	if got := r.ExperimentStats("search-rewrite")["rewrite"].Calls; got != 10 {
+		t.Errorf("variant calls = %d, want 10", got)
+	}
+
+	d := r.resolve("Search.Query")
+	_, first := d.route(ctx, d.impls)
+	_, second := d.route(ctx, d.impls)
+	if first != second {
+		t.Error("variant implementation rebuilt between calls")
+	}
+	if base := d.impls[1]; first.name != base.name || first.registration() != base.registration() {
+		t.Errorf("variant registration = %+v, want %+v", first.registration(), base.registration())
+	}
+}
+
+func TestExperimentVariantInFlight(t *testing.T) {
+	r := newExperimentRegistry(t)
+	var inFlight int64
+	r.RegisterImpl("Search.Query", "rewrite", func(ctx context.Context, req any) (any, error) {
+		inFlight = r.resolve("Search.Query").impls[1].inFlight.Load()
+		return "rewrite", nil
+	})
+
+	ctx := WithMetadataValue(context.Background(), "user", "42")
+	if _, err := r.Call(ctx, "Search.Query", nil); err != nil {
+		t.Fatal(err)
+	}
+	if inFlight != 1 {
+		t.Errorf("in-flight calls of the variant's implementation = %d, want 1", inFlight)
+	}
+}
+
+func TestExperimentVariantReregistered(t *testing.T) {
+	r := newExperimentRegistry(t)
+	ctx := WithMetadataValue(context.Background(), "user", "42")
+	if _, err := r.Call(ctx, "Search.Query", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	r.RegisterImpl("Search.Query", "rewrite", func(ctx context.Context, req any) (any, error) {
+		return "rewrite v2", nil
+	})
+	res, err := r.Call(ctx, "Search.Query", nil)
+	if err != nil || res != "rewrite v2" {
+		t.Fatalf("Call() = %v, %v, want rewrite v2", res, err)
+	}
+	if got := r.ExperimentStats("search-rewrite")["rewrite"].Calls; got != 2 {
+		t.Errorf("variant calls = %d, want 2", got)
+	}
+}
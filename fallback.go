@@ -0,0 +1,68 @@
+package irpc
+
+import (
+	"context"
+	"errors"
+)
+
+// FallbackFunc produces the response of a call whose handler failed with
+// err, e.g. from a cache or with degraded data.
+type FallbackFunc func(ctx context.Context, req any, err error) (any, error)
+
+// DefaultFallbackCodes are the codes that trigger a fallback registered
+// without explicit codes.
+var DefaultFallbackCodes = []Code{Unavailable, DeadlineExceeded, ResourceExhausted}
+
+type fallback struct {
+	fn    FallbackFunc
+	codes []Code
+}
+
+func (f *fallback) applies(err error) bool {
+	if f == nil || err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	code := CodeOf(err)
+	for _, c := range f.codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Fallback registers fn to answer calls to key that fail with one of codes,
+// or with DefaultFallbackCodes if none are given. Timeouts always trigger
+// it; fn then runs with a context that is no longer canceled. The caller's
+// Trailer gets DegradedTrailer set to "fallback".
+func (r *Registry) Fallback(key string, fn FallbackFunc, codes ...Code) {
+	if len(codes) == 0 {
+		codes = DefaultFallbackCodes
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.ensureEntry(key).fallback = &fallback{fn: fn, codes: codes}
+}
+
+// ClearFallback removes the fallback of key.
+func (r *Registry) ClearFallback(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if e := r.entries[key]; e != nil {
+		e.fallback = nil
+	}
+}
+
+func (f *fallback) call(ctx context.Context, req any, err error) (any, error) {
+	markTrailer(ctx, DegradedTrailer, "fallback")
+	if ctx.Err() != nil {
+		ctx = context.WithoutCancel(ctx)
+	}
+	return f.fn(ctx, req, err)
+}
@@ -0,0 +1,174 @@
+package irpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileStore is a Store keeping one file per key in a directory, so key
+// lengths are bounded by the file system's limit on names. Writes are
+// atomic, so entries survive crashes, but CompareAndSwap is only atomic
+// within the process: share a directory between processes only if at most
+// one of them leases durable calls.
+type FileStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileStore returns a FileStore keeping its entries in dir, creating it
+// if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// fileEntry is the content of an entry's file.
+type fileEntry struct {
+	Value   []byte    `json:"value"`
+	Expires time.Time `json:"expires,omitzero"`
+}
+
+const fileStorePrefix = "k-"
+
+func (s *FileStore) path(key string) string {
+	return filepath.Join(s.dir, fileStorePrefix+url.QueryEscape(key))
+}
+
+func (s *FileStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok, err := s.read(key)
+	return e.Value, ok, err
+}
+
+func (s *FileStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.write(key, fileEntry{Value: value, Expires: expiry(ttl)})
+}
+
+func (s *FileStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := os.Remove(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *FileStore) CompareAndSwap(ctx context.Context, key string, old, value []byte) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok, err := s.read(key)
+	if err != nil {
+		return false, err
+	}
+	if ok != (old != nil) || (ok && !bytes.Equal(e.Value, old)) {
+		return false, nil
+	}
+	if err := s.write(key, fileEntry{Value: value, Expires: e.Expires}); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *FileStore) List(ctx context.Context, prefix string) ([]StoreEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var out []StoreEntry
+	for _, f := range files {
+		name, ok := strings.CutPrefix(f.Name(), fileStorePrefix)
+		if !ok {
+			continue
+		}
+		key, err := url.QueryUnescape(name)
+		if err != nil || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		e, ok, err := s.read(key)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out = append(out, StoreEntry{Key: key, Value: e.Value})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out, nil
+}
+
+// read returns the live entry of key, removing it if it expired. s.mu
+// must be held.
+func (s *FileStore) read(key string) (fileEntry, bool, error) {
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return fileEntry{}, false, nil
+	}
+	if err != nil {
+		return fileEntry{}, false, err
+	}
+
+	var e fileEntry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return fileEntry{}, false, err
+	}
+	if !e.Expires.IsZero() && !time.Now().Before(e.Expires) {
+		_ = os.Remove(s.path(key))
+		return fileEntry{}, false, nil
+	}
+	return e, true, nil
+}
+
+// write replaces the file of key atomically. s.mu must be held.
+func (s *FileStore) write(key string, e fileEntry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(s.dir, "tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), s.path(key)); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package irpc
+
+import "context"
+
+// FlagProvider evaluates feature flags for a call. Variant returns the name
+// of the implementation flag selects for the call described by ctx (its
+// metadata, caller, deadline, ...), or "" to use the default one.
+type FlagProvider interface {
+	Variant(ctx context.Context, flag string) string
+}
+
+// FlagFunc adapts a function to a FlagProvider.
+type FlagFunc func(ctx context.Context, flag string) string
+
+func (f FlagFunc) Variant(ctx context.Context, flag string) string {
+	return f(ctx, flag)
+}
+
+// RouteByFlag makes dispatch of key choose between its registered
+// implementations by evaluating flag with p on every call. Unknown variant
+// names fall back to the default implementation.
+//
+//	registry.RegisterContractImpl("Exam", "v2", (*ExamContract)(nil), examV2)
+//	registry.RouteByFlag("Exam.FindExamById", flags, "exam-v2")
+func (r *Registry) RouteByFlag(key string, p FlagProvider, flag string) {
+	r.setRoute(key, func(ctx context.Context, impls []*impl) (context.Context, *impl) {
+		name := p.Variant(ctx, flag)
+		if name == "" {
+			return ctx, nil
+		}
+		return ctx, findImpl(impls, name)
+	})
+}
+
+// ClearRoute removes any routing rule from key so that calls go to its
+// default implementation again.
+func (r *Registry) ClearRoute(key string) {
+	r.setRoute(key, nil)
+}
@@ -0,0 +1,68 @@
+package irpc
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// packageQualifier matches the import path and package name qualifying a
+// type name, e.g. "github.com/acme/exam." in "github.com/acme/exam.Exam".
+var packageQualifier = regexp.MustCompile(`[^\[\],*\s]*\.`)
+
+// ServiceName returns the conventional service name of the contract
+// interface iface: its type name without a Contract suffix, followed by
+// the type arguments of a generic instantiation, so one generic contract
+// can be registered once per entity:
+//
+//	type CrudContract[T any] interface {
+//		Get(ctx context.Context, id string) (*T, error)
+//		Save(ctx context.Context, v T) error
+//	}
+//
+//	registry.RegisterContract(irpc.ServiceName((*CrudContract[Exam])(nil)), (*CrudContract[Exam])(nil), exams)
+//	registry.Call(ctx, "Crud.Exam.Get", "EX-1")
+//
+// Type arguments lose their package qualifier and pointer marks and are
+// joined with dots: CrudContract[*exam.Exam] is "Crud.Exam" and
+// PairContract[Exam, Grade] is "Pair.Exam.Grade"; composite arguments
+// keep their Go spelling. Because path patterns
+// match across dots, "Crud.*" then selects every instantiation and
+// "Crud.Exam.*" a single one.
+func ServiceName(iface any) string {
+	name := reflect.TypeOf(iface).Elem().Name()
+	base, args, generic := strings.Cut(name, "[")
+	base = strings.TrimSuffix(base, "Contract")
+	if !generic {
+		return base
+	}
+
+	args = strings.TrimSuffix(args, "]")
+	args = packageQualifier.ReplaceAllString(args, "")
+	parts := []string{base}
+	for _, arg := range splitTypeArgs(args) {
+		parts = append(parts, strings.TrimLeft(strings.TrimSpace(arg), "*"))
+	}
+	return strings.Join(parts, ".")
+}
+
+// splitTypeArgs splits a type argument list on the commas that are not
+// nested in brackets.
+func splitTypeArgs(args string) []string {
+	var out []string
+	depth, start := 0, 0
+	for i, c := range args {
+		switch c {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				out = append(out, args[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(out, args[start:])
+}
@@ -0,0 +1,226 @@
+package irpc
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultHealthTimeout bounds the health checks of a HealthHandler request
+// that has no deadline of its own.
+const DefaultHealthTimeout = 5 * time.Second
+
+// HealthCheck reports whether a service can serve calls, e.g. by pinging
+// the database it depends on. It must return once ctx is done. An error
+// marks the service NotServing, or Degraded if marked with Degrade.
+type HealthCheck func(ctx context.Context) error
+
+// SetHealthCheck makes check decide the health of service, replacing any
+// earlier check. A nil check removes it.
+//
+//	registry.SetHealthCheck("Billing", func(ctx context.Context) error {
+//		return db.PingContext(ctx)
+//	})
+func (r *Registry) SetHealthCheck(service string, check HealthCheck) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if check == nil {
+		delete(r.healthChecks, service)
+		return
+	}
+	if r.healthChecks == nil {
+		r.healthChecks = make(map[string]HealthCheck)
+	}
+	r.healthChecks[service] = check
+}
+
+// HealthReport is the health of a registry and of each of its services.
+// The Status of the registry is the worst of its services, or NotServing
+// once it has been shut down.
+type HealthReport struct {
+	Status
+	Services map[string]Status `json:"services"`
+}
+
+// CheckHealth runs the health checks of the registered services
+// concurrently, each bounded by ctx, and reports their health. Only the
+// services in services are checked, if any are given.
+//
+// A service is NotServing if its health check fails or if it has stuck
+// calls, and Degraded if its check fails with an error marked by Degrade,
+// while it is in maintenance mode, or while an OnAlert rule fires for one
+// of its keys. Details of each service hold the duration of its check and
+// what else made it degrade.
+func (r *Registry) CheckHealth(ctx context.Context, services ...string) HealthReport {
+	now := time.Now()
+	names := map[string]bool{}
+	for _, key := range r.Keys() {
+		service, _ := SplitKey(key)
+		names[service] = true
+	}
+
+	r.mu.RLock()
+	checks := maps.Clone(r.healthChecks)
+	for service := range checks {
+		names[service] = true
+	}
+	report := HealthReport{
+		Status:   Status{State: Serving, CheckedAt: now},
+		Services: make(map[string]Status),
+	}
+	if r.closed {
+		report.worsen(NotServing, "shut down")
+	}
+	for service := range names {
+		h := Status{State: Serving, CheckedAt: now}
+		if _, ok := r.maintenanceFor(service); ok {
+			h.worsen(Degraded, "in maintenance")
+			h.setDetail("maintenance", true)
+		}
+		report.Services[service] = h
+	}
+	r.mu.RUnlock()
+
+	if len(services) > 0 {
+		selected := make(map[string]Status, len(services))
+		for _, service := range services {
+			if h, ok := report.Services[service]; ok {
+				selected[service] = h
+			} else {
+				selected[service] = Status{State: NotServing, Message: "no such service", CheckedAt: now}
+			}
+		}
+		report.Services = selected
+	}
+
+	for key, reasons := range r.firingAlerts() {
+		service, _ := SplitKey(key)
+		if h, ok := report.Services[service]; ok {
+			alerts, _ := h.Details["alerts"].(map[string][]string)
+			if alerts == nil {
+				alerts = make(map[string][]string)
+			}
+			alerts[key] = reasons
+			h.worsen(Degraded, key+": "+strings.Join(reasons, ", "))
+			h.setDetail("alerts", alerts)
+			report.Services[service] = h
+		}
+	}
+
+	for _, c := range r.StuckCalls() {
+		service, _ := SplitKey(c.Key)
+		if h, ok := report.Services[service]; ok {
+			stuck, _ := h.Details["stuck_calls"].(int)
+			h.worsen(NotServing, "stuck calls")
+			h.setDetail("stuck_calls", stuck+1)
+			report.Services[service] = h
+		}
+	}
+
+	for service := range checks {
+		if _, ok := report.Services[service]; !ok {
+			delete(checks, service)
+		}
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for service, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			err := runHealthCheck(ctx, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			h := report.Services[service]
+			h.setDetail("duration_ns", time.Since(start))
+			if err != nil {
+				h.worsen(checkState(err), err.Error())
+			}
+			report.Services[service] = h
+		}()
+	}
+	wg.Wait()
+
+	for _, service := range slices.Sorted(maps.Keys(report.Services)) {
+		h := report.Services[service]
+		if h.State != Serving {
+			report.worsen(h.State, service+": "+h.Message)
+		}
+	}
+	return report
+}
+
+// runHealthCheck runs check, turning a panic into an error and returning
+// when ctx is done even if check does not.
+func runHealthCheck(ctx context.Context, check HealthCheck) error {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if v := recover(); v != nil {
+				done <- fmt.Errorf("health check panicked: %v", v)
+			}
+		}()
+		done <- check(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// HealthHandler returns an http.Handler for liveness and readiness probes,
+// e.g. of Kubernetes:
+//
+//	GET /livez                 200 until the registry is shut down, 503 after
+//	GET /readyz                200 unless CheckHealth reports the registry NotServing, 503 then
+//	GET /readyz?service=S      the same for service S only; repeat service for several
+//
+// /livez writes a Status and /readyz the HealthReport, as JSON. Checks are
+// bounded by the request's context, or by DefaultHealthTimeout if it has
+// no deadline.
+//
+//	mux.Handle("/health/", http.StripPrefix("/health", registry.HealthHandler()))
+func (r *Registry) HealthHandler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /livez", func(w http.ResponseWriter, req *http.Request) {
+		r.mu.RLock()
+		closed := r.closed
+		r.mu.RUnlock()
+
+		if closed {
+			writeJSON(w, http.StatusServiceUnavailable, Status{State: NotServing, Message: "shut down", CheckedAt: time.Now()})
+			return
+		}
+		writeJSON(w, http.StatusOK, Status{State: Serving, CheckedAt: time.Now()})
+	})
+
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, req *http.Request) {
+		ctx := req.Context()
+		if _, ok := ctx.Deadline(); !ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, DefaultHealthTimeout)
+			defer cancel()
+		}
+
+		report := r.CheckHealth(ctx, req.URL.Query()["service"]...)
+		status := http.StatusOK
+		if !report.OK() {
+			status = http.StatusServiceUnavailable
+		}
+		writeJSON(w, status, report)
+	})
+
+	return mux
+}
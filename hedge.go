@@ -0,0 +1,132 @@
+package irpc
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultHedgeMinSamples and DefaultHedgeDelay are the MinSamples and
+// FallbackDelay of a Hedge that leaves them zero.
+const (
+	DefaultHedgeMinSamples = 100
+	DefaultHedgeDelay      = 100 * time.Millisecond
+)
+
+// Hedge configures hedged calls of a key with several implementations.
+type Hedge struct {
+	// Delay is how long to wait for the selected implementation before
+	// sending the same request to a second one. Zero uses the key's
+	// observed p95 latency once MinSamples calls have been recorded, and
+	// FallbackDelay until then.
+	Delay time.Duration
+	// MinSamples is the number of recorded calls below which the p95 of
+	// the key is not trusted. Zero means DefaultHedgeMinSamples.
+	MinSamples int
+	// FallbackDelay is the delay used while there are fewer samples. Zero
+	// means DefaultHedgeDelay.
+	FallbackDelay time.Duration
+}
+
+// delay returns the hedge delay of key.
+func (h *Hedge) delay(r *Registry, key string) time.Duration {
+	if h.Delay > 0 {
+		return h.Delay
+	}
+	samples := h.MinSamples
+	if samples <= 0 {
+		samples = DefaultHedgeMinSamples
+	}
+	if d, ok := r.quantile(key, 0.95, uint64(samples)); ok && d > 0 {
+		return d
+	}
+	if h.FallbackDelay > 0 {
+		return h.FallbackDelay
+	}
+	return DefaultHedgeDelay
+}
+
+// SetHedge enables hedged calls of key: when the implementation selected
+// for a call has not answered within the hedge delay, or fails, the
+// request is also sent to the next implementation. The first successful
+// response wins and the other call is canceled. Only use it for keys that
+// are safe to execute twice.
+func (r *Registry) SetHedge(key string, h Hedge) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.ensureEntry(key).hedge = &h
+}
+
+// ClearHedge disables hedged calls of key.
+func (r *Registry) ClearHedge(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if e := r.entries[key]; e != nil {
+		e.hedge = nil
+	}
+}
+
+func (r *Registry) callHedged(ctx context.Context, d *dispatch, im *impl, calls Chain, req any) (any, error) {
+	// Compare by name, since the selected implementation may be a variant
+	// of an experiment rather than one of d.impls.
+	second := d.impls[0]
+	for i, other := range d.impls {
+		if other.name == im.name {
+			second = d.impls[(i+1)%len(d.impls)]
+			break
+		}
+	}
+
+	delay := d.hedge.delay(r, d.key)
+
+	results := make(chan Result, 2)
+	shared := d.share(ctx)
+	start := func(im *impl) context.CancelFunc {
+		ctx, cancel := context.WithCancel(ctx)
+		go func() {
+			res, err := r.invoke(ctx, shared, im, calls, req)
+			results <- Result{Impl: im.name, Res: res, Err: err}
+		}()
+		return cancel
+	}
+
+	cancelFirst := start(im)
+	defer cancelFirst()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	pending := 1
+	var cancelSecond context.CancelFunc
+	hedge := func() {
+		if cancelSecond == nil {
+			cancelSecond = start(second)
+			pending++
+		}
+	}
+	defer func() {
+		if cancelSecond != nil {
+			cancelSecond()
+		}
+	}()
+
+	for {
+		select {
+		case <-timer.C:
+			hedge()
+		case res := <-results:
+			pending--
+			if res.Err == nil {
+				if d.streamRes {
+					discardResults(results, pending)
+				}
+				return res.Res, nil
+			}
+			hedge()
+			if pending == 0 {
+				return res.Res, res.Err
+			}
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package irpc
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newHedgedRegistry registers a primary and a backup implementation of
+// Search.Query, the primary taking slow to answer.
+func newHedgedRegistry(slow time.Duration, backups *atomic.Int32) *Registry {
+	r := NewRegistry(Config{})
+	r.RegisterImpl("Search.Query", "primary", func(ctx context.Context, req any) (any, error) {
+		select {
+		case <-time.After(slow):
+			return "primary", nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	})
+	r.RegisterImpl("Search.Query", "backup", func(ctx context.Context, req any) (any, error) {
+		backups.Add(1)
+		return "backup", nil
+	})
+	return r
+}
+
+func TestHedgeFallbackDelayWithoutStats(t *testing.T) {
+	var backups atomic.Int32
+	r := newHedgedRegistry(5*time.Millisecond, &backups)
+	r.SetHedge("Search.Query", Hedge{FallbackDelay: time.Second})
+
+	res, err := r.Call(context.Background(), "Search.Query", nil)
+	if err != nil || res != "primary" {
+		t.Fatalf("Call() = %v, %v, want primary", res, err)
+	}
+	if n := backups.Load(); n != 0 {
+		t.Errorf("backup called %d times before any latency was observed", n)
+	}
+}
+
+func TestHedgeAfterFallbackDelay(t *testing.T) {
+	var backups atomic.Int32
+	r := newHedgedRegistry(time.Second, &backups)
+	r.SetHedge("Search.Query", Hedge{FallbackDelay: 5 * time.Millisecond})
+
+	res, err := r.Call(context.Background(), "Search.Query", nil)
+	if err != nil || res != "backup" {
+		t.Fatalf("Call() = %v, %v, want backup", res, err)
+	}
+}
+
+func TestHedgeDelay(t *testing.T) {
+	var backups atomic.Int32
+	r := newHedgedRegistry(time.Millisecond, &backups)
+	h := &Hedge{MinSamples: 3, FallbackDelay: time.Hour}
+
+	if got := h.delay(r, "Search.Query"); got != time.Hour {
+		t.Errorf("delay without stats = %v, want the fallback", got)
+	}
+	for range 2 {
+		if _, err := r.Call(context.Background(), "Search.Query", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := h.delay(r, "Search.Query"); got != time.Hour {
+		t.Errorf("delay with 2 samples = %v, want the fallback", got)
+	}
+	if _, err := r.Call(context.Background(), "Search.Query", nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := h.delay(r, "Search.Query"); got <= 0 || got >= time.Hour {
+		t.Errorf("delay with 3 samples = %v, want the p95", got)
+	}
+
+	if got := (&Hedge{Delay: time.Minute}).delay(r, "Search.Query"); got != time.Minute {
+		t.Errorf("delay = %v, want the configured one", got)
+	}
+	if got := (&Hedge{}).delay(r, "Search.Query"); got != DefaultHedgeDelay {
+		t.Errorf("delay = %v, want DefaultHedgeDelay", got)
+	}
+}
+
+func TestHedgeSecondByName(t *testing.T) {
+	var backups atomic.Int32
+	r := newHedgedRegistry(time.Second, &backups)
+	r.SetHedge("Search.Query", Hedge{Delay: time.Millisecond})
+
+	// A copy of the primary, as an experiment selects, still hedges to the
+	// backup rather than to the primary again.
+	d := r.resolve("Search.Query")
+	primary := &impl{name: d.impls[0].name, handler: d.impls[0].handler}
+	ctx, calls := d.enter(context.Background())
+	res, err := r.callHedged(ctx, &d, primary, calls, nil)
+	if err != nil || res != "backup" {
+		t.Fatalf("callHedged() = %v, %v, want backup", res, err)
+	}
+}
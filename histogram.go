@@ -0,0 +1,77 @@
+package irpc
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+// histogram is a log-linear latency histogram in the spirit of HDR
+// histograms: every power of two is split into histSubBuckets linear
+// buckets, which bounds the relative error of a quantile estimate at
+// roughly 1/histSubBuckets regardless of the magnitude of the value.
+const (
+	histSubBits    = 4
+	histSubBuckets = 1 << histSubBits
+	histMaxExp     = 40 - histSubBits // values above ~18 minutes are clamped
+	histBuckets    = (histMaxExp + 2) * histSubBuckets
+)
+
+type histogram struct {
+	counts [histBuckets]uint64
+	total  uint64
+}
+
+func histIndex(v uint64) int {
+	if v < histSubBuckets {
+		return int(v)
+	}
+	exp := bits.Len64(v) - histSubBits - 1
+	if exp > histMaxExp {
+		return histBuckets - 1
+	}
+	return (exp+1)*histSubBuckets + int(v>>uint(exp)) - histSubBuckets
+}
+
+// histValue returns the midpoint of the bucket at idx.
+func histValue(idx int) uint64 {
+	if idx < histSubBuckets {
+		return uint64(idx)
+	}
+	exp := uint(idx/histSubBuckets - 1)
+	sub := uint64(idx%histSubBuckets + histSubBuckets)
+	lower := sub << exp
+	upper := (sub + 1) << exp
+	return lower + (upper-lower)/2
+}
+
+func (h *histogram) record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	h.counts[histIndex(uint64(d))]++
+	h.total++
+}
+
+// quantile returns the estimated value at quantile q (0 < q <= 1).
+func (h *histogram) quantile(q float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	if q <= 0 {
+		q = math.SmallestNonzeroFloat64
+	}
+	if q > 1 {
+		q = 1
+	}
+
+	rank := uint64(math.Ceil(q * float64(h.total)))
+	var seen uint64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= rank {
+			return time.Duration(histValue(i))
+		}
+	}
+	return time.Duration(histValue(histBuckets - 1))
+}
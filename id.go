@@ -0,0 +1,13 @@
+package irpc
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// newID returns a random 128-bit identifier in hex.
+func newID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
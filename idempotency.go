@@ -0,0 +1,190 @@
+package irpc
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// IdempotencyKey is the metadata key holding the idempotency key of a
+// call. NotifyDurable sets it to the ID of the deferred call, so that every
+// delivery of the call carries the same key.
+const IdempotencyKey = "x-idempotency-key"
+
+// DefaultIdempotencyTTL is how long EnableIdempotency remembers a call
+// unless told otherwise.
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// WithIdempotencyKey returns a copy of ctx making the next calls to
+// Idempotent methods deduplicated on id once EnableIdempotency is on.
+func WithIdempotencyKey(ctx context.Context, id string) context.Context {
+	return WithMetadataValue(ctx, IdempotencyKey, id)
+}
+
+// IdempotencyRecord is the stored outcome of a successful call made with
+// an idempotency key.
+type IdempotencyRecord struct {
+	// ID is the RPC key and the idempotency key, joined by a slash.
+	ID string
+	// Response is the response of the call. It is only kept in memory;
+	// stores that persist records rely on Payload, the response encoded
+	// with the registry's Codec.
+	Response  any `json:"-"`
+	Payload   []byte
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// IdempotencyStore remembers the calls served with an idempotency key.
+// Get must not return expired records. Implementations must be safe for
+// concurrent use.
+type IdempotencyStore interface {
+	Get(ctx context.Context, id string) (IdempotencyRecord, bool, error)
+	Put(ctx context.Context, record IdempotencyRecord) error
+}
+
+// EnableIdempotency deduplicates calls to Idempotent methods that carry an
+// idempotency key: the first successful call is recorded in store for ttl
+// (DefaultIdempotencyTTL if zero), and later calls with the same key get
+// its response without running the handler.
+//
+// Combined with NotifyDurable, this gives effectively exactly-once
+// processing: a call redelivered after a crash, or after its
+// acknowledgement was lost, is answered from the store. A crash between
+// the handler returning and the record being written still runs the
+// handler twice, which is why only Idempotent methods are deduplicated.
+// Failed calls are not recorded, so they can be retried. Of the phases
+// Atomic calls a TwoPhase key in, only Commit is deduplicated, as the
+// plain call it stands for.
+//
+// A call arriving while a call with the same key is still running in this
+// process waits for it and gets its outcome, error included, instead of
+// running the handler again. Duplicates running in other processes are
+// only deduplicated once the first call is recorded.
+//
+// The store is consulted on a best-effort basis: when it fails, calls run
+// as if it were empty.
+func (r *Registry) EnableIdempotency(store IdempotencyStore, ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = DefaultIdempotencyTTL
+	}
+
+	var mu sync.Mutex
+	inflight := make(map[string]*batch)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.middleware = append(r.middleware, scopedMiddleware{tag: Idempotent, mw: func(key string, next HandlerFunc) HandlerFunc {
+		r.mu.RLock()
+		var resType reflect.Type
+		if e := r.entries[key]; e != nil {
+			resType = e.resType
+		}
+		r.mu.RUnlock()
+
+		return func(ctx context.Context, req any) (any, error) {
+			idem, ok := MetadataValue(ctx, IdempotencyKey)
+			if !ok || idem == "" {
+				return next(ctx, req)
+			}
+			if phase, ok := PhaseFromContext(ctx); ok && phase != PhaseCommit {
+				return next(ctx, req)
+			}
+			id := key + "/" + idem
+
+			if rec, found, err := store.Get(ctx, id); err == nil && found {
+				if res, err := r.replayResponse(key, resType, rec); err == nil {
+					return res, nil
+				}
+			}
+
+			mu.Lock()
+			if b, running := inflight[id]; running {
+				mu.Unlock()
+				return b.wait(ctx)
+			}
+			b := newBatch()
+			inflight[id] = b
+			mu.Unlock()
+
+			// The call stays in flight until it is recorded, so that no
+			// duplicate runs in between.
+			defer close(b.done)
+			defer func() {
+				v := recover()
+				if v != nil {
+					b.res, b.err = nil, panicError(key, v)
+				}
+				mu.Lock()
+				delete(inflight, id)
+				mu.Unlock()
+				if v != nil {
+					panic(v)
+				}
+			}()
+
+			b.res, b.err = next(ctx, req)
+			if b.err != nil {
+				return b.res, b.err
+			}
+			now := time.Now()
+			rec := IdempotencyRecord{ID: id, Response: b.res, CreatedAt: now, ExpiresAt: now.Add(ttl)}
+			if payload, encErr := r.codec().Marshal(b.res); encErr == nil {
+				rec.Payload = payload
+			}
+			_ = store.Put(context.WithoutCancel(ctx), rec)
+			return b.res, nil
+		}
+	}})
+	r.chainGen++
+}
+
+// replayResponse returns the response recorded in rec, decoding it into
+// resType if only its payload was kept.
+func (r *Registry) replayResponse(key string, resType reflect.Type, rec IdempotencyRecord) (any, error) {
+	if rec.Response != nil || rec.Payload == nil {
+		return rec.Response, nil
+	}
+	if resType == nil {
+		return rec.Payload, nil
+	}
+
+	v := reflect.New(resType)
+	if err := r.codec().Unmarshal(rec.Payload, v.Interface()); err != nil {
+		return nil, &Error{Code: Internal, Key: key, Message: "decode recorded response of " + key, Err: err}
+	}
+	return v.Elem().Interface(), nil
+}
+
+// MemoryIdempotencyStore is an in-memory IdempotencyStore. It does not
+// survive restarts and is meant for tests and development.
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]IdempotencyRecord
+}
+
+// NewMemoryIdempotencyStore returns an empty MemoryIdempotencyStore.
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{records: make(map[string]IdempotencyRecord)}
+}
+
+func (s *MemoryIdempotencyStore) Get(ctx context.Context, id string) (IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.records[id]
+	if ok && !rec.ExpiresAt.IsZero() && !time.Now().Before(rec.ExpiresAt) {
+		delete(s.records, id)
+		return IdempotencyRecord{}, false, nil
+	}
+	return rec, ok, nil
+}
+
+func (s *MemoryIdempotencyStore) Put(ctx context.Context, record IdempotencyRecord) error {
+	s.mu.Lock()
+	s.records[record.ID] = record
+	s.mu.Unlock()
+	return nil
+}
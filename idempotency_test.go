@@ -0,0 +1,146 @@
+package irpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type chargeReq struct{ Amount int }
+type chargeRes struct{ Receipt int }
+
+type chargeContract interface {
+	Charge(ctx context.Context, req chargeReq) (chargeRes, error)
+}
+
+type chargeImpl struct {
+	calls atomic.Int32
+	fail  atomic.Bool
+	block chan struct{}
+}
+
+func (c *chargeImpl) Charge(ctx context.Context, req chargeReq) (chargeRes, error) {
+	n := c.calls.Add(1)
+	if c.block != nil {
+		<-c.block
+	}
+	if c.fail.Load() {
+		return chargeRes{}, errors.New("declined")
+	}
+	return chargeRes{Receipt: int(n)}, nil
+}
+
+func newChargeRegistry(store IdempotencyStore, ttl time.Duration, impl *chargeImpl) *Registry {
+	r := NewRegistry(Config{})
+	r.RegisterContract("Billing", (*chargeContract)(nil), impl)
+	r.Tag("Billing.Charge", Idempotent)
+	r.EnableIdempotency(store, ttl)
+	return r
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	stores := map[string]func() IdempotencyStore{
+		"memory":       func() IdempotencyStore { return NewMemoryIdempotencyStore() },
+		"memory store": func() IdempotencyStore { return NewIdempotencyStore(NewMemoryStore()) },
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			impl := &chargeImpl{}
+			r := newChargeRegistry(newStore(), time.Hour, impl)
+			ctx := WithIdempotencyKey(context.Background(), "order-1")
+
+			first, err := r.Call(ctx, "Billing.Charge", chargeReq{Amount: 5})
+			if err != nil {
+				t.Fatal(err)
+			}
+			again, err := r.Call(ctx, "Billing.Charge", chargeReq{Amount: 5})
+			if err != nil || again != first {
+				t.Fatalf("replay: got %v, %v, want %v", again, err, first)
+			}
+			if n := impl.calls.Load(); n != 1 {
+				t.Fatalf("handler ran %d times, want 1", n)
+			}
+
+			other := WithIdempotencyKey(context.Background(), "order-2")
+			if _, err := r.Call(other, "Billing.Charge", chargeReq{Amount: 5}); err != nil {
+				t.Fatal(err)
+			}
+			if n := impl.calls.Load(); n != 2 {
+				t.Fatalf("handler ran %d times for two keys, want 2", n)
+			}
+		})
+	}
+}
+
+func TestIdempotencyFailureNotRecorded(t *testing.T) {
+	impl := &chargeImpl{}
+	impl.fail.Store(true)
+	r := newChargeRegistry(NewMemoryIdempotencyStore(), time.Hour, impl)
+	ctx := WithIdempotencyKey(context.Background(), "order-1")
+
+	if _, err := r.Call(ctx, "Billing.Charge", chargeReq{}); err == nil {
+		t.Fatal("want the handler's error")
+	}
+	impl.fail.Store(false)
+	if _, err := r.Call(ctx, "Billing.Charge", chargeReq{}); err != nil {
+		t.Fatalf("retry after a failure: %v", err)
+	}
+	if n := impl.calls.Load(); n != 2 {
+		t.Fatalf("handler ran %d times, want 2", n)
+	}
+}
+
+func TestIdempotencyExpiry(t *testing.T) {
+	impl := &chargeImpl{}
+	r := newChargeRegistry(NewMemoryIdempotencyStore(), 20*time.Millisecond, impl)
+	ctx := WithIdempotencyKey(context.Background(), "order-1")
+
+	r.Call(ctx, "Billing.Charge", chargeReq{})
+	time.Sleep(30 * time.Millisecond)
+	r.Call(ctx, "Billing.Charge", chargeReq{})
+	if n := impl.calls.Load(); n != 2 {
+		t.Fatalf("handler ran %d times across an expired record, want 2", n)
+	}
+}
+
+func TestIdempotencyDuplicateInFlight(t *testing.T) {
+	impl := &chargeImpl{block: make(chan struct{})}
+	r := newChargeRegistry(NewMemoryIdempotencyStore(), time.Hour, impl)
+	ctx := WithIdempotencyKey(context.Background(), "order-1")
+
+	const callers = 4
+	var wg sync.WaitGroup
+	results := make([]any, callers)
+	errs := make([]error, callers)
+	for i := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = r.Call(ctx, "Billing.Charge", chargeReq{Amount: 5})
+		}()
+	}
+
+	// Let every duplicate arrive while the first call is blocked.
+	deadline := time.Now().Add(time.Second)
+	for impl.calls.Load() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("handler never ran")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(impl.block)
+	wg.Wait()
+
+	if n := impl.calls.Load(); n != 1 {
+		t.Fatalf("handler ran %d times, want 1", n)
+	}
+	for i := range callers {
+		if errs[i] != nil || results[i] != results[0] {
+			t.Fatalf("caller %d got %v, %v, want %v", i, results[i], errs[i], results[0])
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package irpc
+
+import (
+	"context"
+	"reflect"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultImpl is the implementation name used by Register and
+// RegisterContract. It is the implementation a call falls back to when no
+// routing rule selects another one.
+const DefaultImpl = "default"
+
+type impl struct {
+	name         string
+	handler      HandlerFunc
+	source       string
+	registeredAt time.Time
+	inFlight     atomic.Int64
+	chain        atomic.Pointer[chainedHandler]
+}
+
+// routeFunc picks the implementation serving a call, or returns nil to use
+// the default one. It may return a derived ctx, e.g. to record the decision
+// in the call metadata.
+type routeFunc func(ctx context.Context, impls []*impl) (context.Context, *impl)
+
+// entry holds everything registered under a key. impls is copy-on-write so
+// Call can use it after releasing the registry lock.
+type entry struct {
+	impls     []*impl
+	route     routeFunc
+	aggregate Aggregator
+	hedge     *Hedge
+	fallback  *fallback
+	degrade   *Degradation
+	reqType   reflect.Type
+	resType   reflect.Type
+	strict    *strictCall
+	streamReq bool
+	streamRes bool
+	contract  string
+	noContext bool
+	uow       bool
+	tags      []Tag
+	info      ServiceInfo
+	// owner is the info of the key merged over that of its service, or nil
+	// if there is none. It is replaced, never modified.
+	owner *ServiceInfo
+
+	// timeout and middleware come from the config of the key's service.
+	timeout    time.Duration
+	middleware []Middleware
+}
+
+// RegisterImpl registers h as the implementation called name of key, in
+// addition to the implementations already registered for it. Registering
+// the same name again replaces that implementation.
+func (r *Registry) RegisterImpl(key, name string, h HandlerFunc) {
+	service, _ := SplitKey(key)
+	r.registerImpl(key, name, h, r.config.forService(service))
+}
+
+// registerImpl is RegisterImpl with the config of the key's service.
+func (r *Registry) registerImpl(key, name string, h HandlerFunc, config Config) {
+	source := registrationSource()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e := r.ensureEntry(key)
+	e.timeout = config.Timeout
+	if len(e.middleware) > 0 || len(config.Middleware) > 0 {
+		e.middleware = config.Middleware
+		r.chainGen++
+	}
+
+	next := &impl{name: name, handler: h, source: source, registeredAt: time.Now()}
+	service, _ := SplitKey(key)
+	if _, ok := r.registered[service]; !ok {
+		if r.registered == nil {
+			r.registered = make(map[string]Registration)
+		}
+		r.registered[service] = next.registration()
+	}
+	impls := make([]*impl, 0, len(e.impls)+1)
+	replaced := false
+	for _, im := range e.impls {
+		if im.name == name {
+			im, replaced = next, true
+		}
+		impls = append(impls, im)
+	}
+	if !replaced {
+		if name == DefaultImpl {
+			impls = append([]*impl{next}, impls...)
+		} else {
+			impls = append(impls, next)
+		}
+	}
+	e.impls = impls
+}
+
+// Impls returns the names of the implementations registered for key, the
+// default one first.
+func (r *Registry) Impls(key string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	e := r.entries[key]
+	if e == nil {
+		return nil
+	}
+	names := make([]string, len(e.impls))
+	for i, im := range e.impls {
+		names[i] = im.name
+	}
+	return names
+}
+
+func (r *Registry) hasImpl(key, name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	e := r.entries[key]
+	return e != nil && findImpl(e.impls, name) != nil
+}
+
+func (r *Registry) setRoute(key string, route routeFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.ensureEntry(key).route = route
+}
+
+// ensureEntry returns the entry of key, creating it if needed. The caller
+// must hold r.mu.
+func (r *Registry) ensureEntry(key string) *entry {
+	e := r.entries[key]
+	if e == nil {
+		e = &entry{}
+		r.entries[key] = e
+		r.updateOwner(key, e)
+	}
+	return e
+}
+
+// setTypes records the request and response types of key as declared by
+// its contract. Either may be nil.
+func (r *Registry) setTypes(key string, req, res reflect.Type, roundTrip bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if e := r.entries[key]; e != nil {
+		e.reqType, e.resType = req, res
+		e.streamReq = req != nil && isStream(req)
+		e.streamRes = res != nil && isStream(res)
+		if roundTrip {
+			e.strict = &strictCall{codec: r.codec(), reqType: req, resType: res}
+		}
+	}
+}
+
+// requestType returns the request type of key as declared by its contract,
+// or nil for keys registered with a plain HandlerFunc.
+func (r *Registry) requestType(key string) reflect.Type {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if e := r.entries[key]; e != nil {
+		return e.reqType
+	}
+	return nil
+}
+
+func findImpl(impls []*impl, name string) *impl {
+	for _, im := range impls {
+		if im.name == name {
+			return im
+		}
+	}
+	return nil
+}
+
+func selectImpl(ctx context.Context, impls []*impl, route routeFunc) (context.Context, *impl) {
+	if route != nil {
+		routed, im := route(ctx, impls)
+		if im != nil {
+			return routed, im
+		}
+	}
+	return ctx, impls[0]
+}
@@ -54,6 +54,9 @@ Call(ctx context.Context, key string, req any)
     type Config struct {
         AllowOverride bool
         AllowPartial  bool
+        AsyncWorkers  int
+        Executor      Executor
+        Codec         Codec
     }
 
     var DEFAULT_CONFIG = Config{
@@ -68,6 +71,26 @@ HandlerFunc
 
     type HandlerFunc func(ctx context.Context, req any) (any, error)
 
+# Middleware
+
+    type Middleware func(key string, next HandlerFunc) HandlerFunc
+
+Use appends middleware that wraps every handler on Call. OpenTelemetry
+metrics middleware lives in the irpcotel package.
+
+# Async calls
+
+CallAsync and Notify queue calls on an Executor, by default a PoolExecutor
+of AsyncWorkers goroutines. WithPriority(PriorityHigh) schedules
+latency-sensitive work ahead of bulk work when the pool is saturated.
+Shutdown drains the queue.
+
+# Statistics
+
+Every call is recorded per key: call and error counts, min/mean/max latency
+and streaming p50/p95/p99 estimates. Use Stats, StatsFor or Quantile to read
+them, or mount DebugHandler to expose them as JSON.
+
 Performance Characteristics remain unchanged.
 
 */
@@ -78,12 +101,56 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type Config struct {
 	AllowOverride bool
 	AllowPartial  bool
+
+	// AsyncWorkers is the number of goroutines running CallAsync and
+	// Notify calls. Zero uses GOMAXPROCS.
+	AsyncWorkers int
+	// Executor runs CallAsync and Notify calls instead of the default pool
+	// of AsyncWorkers. The registry does not shut it down.
+	Executor Executor
+	// Codec serializes requests that leave memory, e.g. durable calls.
+	// Nil uses JSONCodec.
+	Codec Codec
+
+	// StrictTypes makes RegisterContract panic if a request or response
+	// type cannot be serialized; see CheckSerializable.
+	StrictTypes bool
+	// RoundTripCalls round-trips every request and response of contract
+	// keys through the Codec, failing calls whose values do not survive
+	// it. It is meant for tests: it encodes every value twice.
+	RoundTripCalls bool
+
+	// Timeout bounds every call to a registered key that no pattern set
+	// with SetTimeout matches.
+	Timeout time.Duration
+	// Middleware wraps every call to a registered key, outside the
+	// middleware added with Use, so that none of it can skip it.
+	Middleware []Middleware
+
+	// Services specializes the config for the services named by its keys
+	// and those nested under them: "Billing" applies to "Billing" and
+	// "Billing.Invoices". Each function changes a copy of the config,
+	// already specialized by shorter prefixes, and is run whenever a key
+	// of a matching service is registered. Only AllowOverride,
+	// AllowPartial, StrictTypes, RoundTripCalls, Timeout and Middleware
+	// can be specialized.
+	//
+	//	Services: map[string]func(*irpc.Config){
+	//		"Billing": func(c *irpc.Config) {
+	//			c.Timeout = time.Second
+	//			c.Middleware = append(c.Middleware, audit)
+	//		},
+	//	}
+	Services map[string]func(*Config)
 }
 
 var DEFAULT_CONFIG = Config{
@@ -94,26 +161,113 @@ var DEFAULT_CONFIG = Config{
 type HandlerFunc func(context.Context, any) (any, error)
 
 type Registry struct {
-	mu       sync.RWMutex
-	handlers map[string]HandlerFunc
-	config   Config
+	mu           genMutex
+	entries      map[string]*entry
+	config       Config
+	stats        *statsCollector
+	graph        *callGraph
+	middleware   []scopedMiddleware
+	chainGen     uint64
+	disabled     disabledSet
+	maintenance  map[string]Responder
+	experiments  map[string]*experimentState
+	deferred     DeferredStore
+	deferredOpts deferredConfig
+	deadLetters  DeadLetterStore
+	twoPhase     map[string]TwoPhase
+	boundary     *contextBoundary
+	codeMappings map[string]CodeMapping
+	limiter      *limiter
+	quotas       []*quotaRule
+	alerts       []*alertRule
+	slos         []*sloRule
+	rateLimits   []*tokenBucket
+	bulkheads    map[string]*bulkhead
+	timeouts     []scopedTimeout
+	healthChecks map[string]HealthCheck
+	serviceInfo  map[string]ServiceInfo
+	registered   map[string]Registration
+	caches       []*Cache
+	transformers []scopedTransformer
+	versions     map[string]map[string]versionStep
+	profiling    string
+	watchdog     *watchdog
+	topics       map[string]*topicState
+
+	onVersionMismatch func(VersionMismatch)
+	// versionWarned holds the versioned keys whose unknown version was
+	// logged.
+	versionWarned sync.Map
+
+	panicPolicies map[string]PanicPolicy
+	panics        map[string]*atomic.Uint64
+	restarting    map[string]bool
+
+	retriesEnabled bool
+	retryPolicy    *RetryPolicy
+	retryPolicies  map[string]RetryPolicy
+	asyncRetries   []scopedAsyncRetry
+
+	slowThreshold time.Duration
+	onSlowCall    func(SlowCall)
+
+	onDeadLetter func(DeadLetter)
+	onBudget     func(SLOStatus)
+	telemetry    []TelemetryExporter
+
+	onSLAViolation func(SLAViolation)
+
+	settingsMu       sync.Mutex
+	settings         Settings
+	settingsHistory  []SettingsChange
+	onSettingsChange []func(SettingsChange)
+	onSettingsError  func(error)
+
+	asyncOnce     sync.Once
+	async         Executor
+	asyncOwned    bool
+	pending       pendingSet
+	asyncStats    asyncCollector
+	redeliveries  redeliverySet
+	streams       streamSet
+	subscriptions subscriptionSet
+	schedOnce     sync.Once
+	sched         *scheduler
+	shutdownHooks []func(context.Context) error
+	closed        bool
 }
 
 func NewRegistry(config Config) *Registry {
 	return &Registry{
-		handlers: make(map[string]HandlerFunc),
-		config:   config,
+		entries: make(map[string]*entry),
+		config:  config,
+		stats:   newStatsCollector(),
+		graph:   newCallGraph(),
 	}
 }
 
-func (r *Registry) RegisterContract(serviceName string, iface any, impl any) {
+func (r *Registry) RegisterContract(serviceName string, iface any, impl any, opts ...RegisterOption) {
+	r.RegisterContractImpl(serviceName, DefaultImpl, iface, impl, opts...)
+}
+
+// RegisterContractImpl is like RegisterContract but registers impl as the
+// named implementation implName of every key, next to any other
+// implementations of the same contract.
+func (r *Registry) RegisterContractImpl(serviceName, implName string, iface any, impl any, opts ...RegisterOption) {
+	o := newRegisterOptions(opts)
+	if o.info != nil {
+		r.SetServiceInfo(serviceName, *o.info)
+	}
+	config := r.config.forService(serviceName)
 	ifaceType := reflect.TypeOf(iface).Elem()
+	o.checkSubContracts(ifaceType)
 	implVal := reflect.ValueOf(impl)
 	implType := implVal.Type()
 
 	if implType.Kind() != reflect.Pointer {
 		panic("irpc: impl must be a pointer to struct")
 	}
+	typed := typedHandlers(ifaceType, impl)
 
 	for i := 0; i < ifaceType.NumMethod(); i++ {
 		ifaceMethod := ifaceType.Method(i)
@@ -121,61 +275,315 @@ func (r *Registry) RegisterContract(serviceName string, iface any, impl any) {
 
 		implMethod := implVal.MethodByName(mName)
 		if !implMethod.IsValid() {
-			if r.config.AllowPartial {
+			if config.AllowPartial {
 				continue
 			}
 			panic(fmt.Sprintf("irpc: missing method: %s.%s", serviceName, mName))
 		}
 
 		key := serviceName + "." + mName
-		if _, exists := r.handlers[key]; exists && !r.config.AllowOverride {
+		if r.hasImpl(key, implName) && !config.AllowOverride {
 			panic(fmt.Sprintf("irpc: duplicate method key '%s' in RegisterContract", key))
 		}
 
-		h := makeHandler(implMethod)
+		noContext := !takesContext(implMethod.Type())
+		if noContext && !o.noContext {
+			panic(fmt.Sprintf("irpc: %s does not take a context.Context first; register it with irpc.AllowNoContext()", key))
+		}
 
-		r.Register(key, h)
+		reqType, resType := requestTypeOf(implMethod.Type()), responseTypeOf(implMethod.Type())
+		if config.StrictTypes {
+			checkContractTypes(key, implMethod.Type())
+		}
+
+		h := typed[mName]
+		if h == nil {
+			m, _ := implType.MethodByName(mName)
+			h = makeHandler(implVal, m)
+		}
+
+		r.registerImpl(key, implName, h, config)
+		r.setTypes(key, reqType, resType, config.RoundTripCalls)
+		r.setContract(key, o.contractOf(ifaceType, mName), noContext)
+		tags := o.tags[mName]
+		if isPaginated(reqType, resType) {
+			tags = append(tags[:len(tags):len(tags)], Paginated)
+		}
+		if len(tags) > 0 {
+			r.Tag(key, tags...)
+		}
+		if slo, ok := o.slos[mName]; ok {
+			if err := r.SetSLO(key, slo); err != nil {
+				panic(fmt.Sprintf("irpc: SLO of %s: %v", key, err))
+			}
+		}
 	}
 }
 
-func makeHandler(method reflect.Value) HandlerFunc {
+var handlerFactories sync.Map // reflect.Type -> func(any) map[string]HandlerFunc
+
+// RegisterHandlerFactory registers the typed handlers of the contract
+// interface T: factory returns the handler of every method of impl by
+// method name. RegisterContract then calls the methods of an impl
+// implementing T directly instead of through reflection. It is called by
+// the init function of code generated with cmd/irpcgen.
+func RegisterHandlerFactory[T any](factory func(impl T) map[string]HandlerFunc) {
+	handlerFactories.Store(reflect.TypeFor[T](), func(impl any) map[string]HandlerFunc {
+		return factory(impl.(T))
+	})
+}
+
+// typedHandlers returns the generated handlers of the contract iface for
+// impl, or nil if there are none or impl does not implement all of iface.
+func typedHandlers(iface reflect.Type, impl any) map[string]HandlerFunc {
+	factory, ok := handlerFactories.Load(iface)
+	if !ok || !reflect.TypeOf(impl).Implements(iface) {
+		return nil
+	}
+	return factory.(func(any) map[string]HandlerFunc)(impl)
+}
+
+// makeHandler adapts the method m of recv to a HandlerFunc. After the
+// optional context, a method taking one parameter gets req, a variadic one
+// gets req as its variadic slice or as a single element, and one taking
+// several parameters gets the elements of req, which must be a []any. A
+// nil req becomes the zero value of the parameter. Results before a
+// trailing error become the response: nil, the value, or a []any of them.
+//
+// The method is called through its method expression, with recv as the
+// first argument, which saves reflect an allocation per call over calling
+// a method value.
+func makeHandler(recv reflect.Value, m reflect.Method) HandlerFunc {
+	t := recv.Method(m.Index).Type()
+	fn := m.Func
+	first := 0
+	if takesContext(t) {
+		first = 1
+	}
+	params := make([]reflect.Type, 0, t.NumIn()-first)
+	for i := first; i < t.NumIn(); i++ {
+		params = append(params, t.In(i))
+	}
+	results := t.NumOut()
+	withErr := results > 0 && t.Out(results-1) == errorType
+	if withErr {
+		results--
+	}
+	// fixed is the number of arguments before the parameters.
+	fixed := 1 + first
+
+	// Argument slices are pooled per handler: reflect copies them on Call,
+	// so one can be reused as soon as Call returns.
+	argPool := sync.Pool{New: func() any {
+		in := make([]reflect.Value, 0, fixed+len(params))
+		return &in
+	}}
+
 	return func(ctx context.Context, req any) (any, error) {
-		in := []reflect.Value{reflect.ValueOf(ctx)}
+		pooled := argPool.Get().(*[]reflect.Value)
+		in := *pooled
+		defer func() {
+			clear(in)
+			*pooled = in[:0]
+			argPool.Put(pooled)
+		}()
+
+		in = append(in, recv)
+		if first == 1 {
+			in = append(in, contextArg(ctx))
+		}
+		in, err := methodArgs(in, params, t.IsVariadic(), req)
+		if err != nil {
+			return nil, err
+		}
+
+		var out []reflect.Value
+		if t.IsVariadic() && len(in)-fixed == len(params) && in[len(in)-1].Type() == params[len(params)-1] {
+			out = fn.CallSlice(in)
+		} else {
+			out = fn.Call(in)
+		}
 
-		if method.Type().NumIn() == 2 {
-			in = append(in, reflect.ValueOf(req))
+		if withErr {
+			if e := out[results]; !e.IsNil() {
+				err = e.Interface().(error)
+			}
+		}
+		switch results {
+		case 0:
+			return nil, err
+		case 1:
+			return out[0].Interface(), err
 		}
+		res := make([]any, results)
+		for i := range res {
+			res[i] = out[i].Interface()
+		}
+		return res, err
+	}
+}
 
-		out := method.Call(in)
+// contextArg returns ctx as a reflect argument of type context.Context.
+// The context of the call, which the chain context holds as an interface
+// already, is passed without boxing it again.
+func contextArg(ctx context.Context) reflect.Value {
+	if c, ok := ctx.(*chainCtx); ok {
+		return reflect.ValueOf(&c.self).Elem()
+	}
+	return boxContext(ctx)
+}
 
-		var err error
-		if len(out) == 2 && !out[1].IsNil() {
-			err = out[1].Interface().(error)
+func boxContext(ctx context.Context) reflect.Value {
+	return reflect.ValueOf(&ctx).Elem()
+}
+
+// methodArgs appends to in the arguments of a method taking params for
+// req. For a variadic method the last argument is either the variadic
+// slice itself or one of its elements.
+func methodArgs(in []reflect.Value, params []reflect.Type, variadic bool, req any) ([]reflect.Value, error) {
+	switch {
+	case len(params) == 0:
+		return in, nil
+	case len(params) == 1 && !variadic:
+		v, err := argValue(req, params[0])
+		return append(in, v), err
+	case len(params) == 1:
+		if req == nil {
+			return in, nil
+		}
+		if v := reflect.ValueOf(req); v.Type().AssignableTo(params[0]) {
+			return append(in, v), nil
 		}
+		v, err := argValue(req, params[0].Elem())
+		return append(in, v), err
+	}
 
-		if len(out) >= 1 {
-			return out[0].Interface(), err
+	list, ok := req.([]any)
+	if !ok && req != nil {
+		return in, Errorf(InvalidArgument, "request is %T, want []any of %d arguments", req, len(params))
+	}
+	fixed := len(params)
+	if variadic {
+		fixed--
+	}
+	if variadic && len(list) < fixed {
+		return in, Errorf(InvalidArgument, "got %d arguments, want at least %d", len(list), fixed)
+	}
+	if !variadic && len(list) != fixed {
+		return in, Errorf(InvalidArgument, "got %d arguments, want %d", len(list), fixed)
+	}
+
+	for i, a := range list {
+		pt := params[min(i, len(params)-1)]
+		if variadic && i >= fixed {
+			// A lone trailing argument may be the variadic slice itself.
+			if v := reflect.ValueOf(a); i == fixed && len(list) == len(params) && a != nil && v.Type().AssignableTo(pt) {
+				in = append(in, v)
+				continue
+			}
+			pt = pt.Elem()
 		}
-		return nil, err
+		v, err := argValue(a, pt)
+		if err != nil {
+			return in, err
+		}
+		in = append(in, v)
 	}
+	return in, nil
+}
+
+// argValue returns v as an argument of type t, the zero value if v is nil.
+func argValue(v any, t reflect.Type) (reflect.Value, error) {
+	if v == nil {
+		return reflect.Zero(t), nil
+	}
+	rv := reflect.ValueOf(v)
+	if !rv.Type().AssignableTo(t) {
+		return reflect.Value{}, Errorf(InvalidArgument, "request is %T, want %s", v, t)
+	}
+	return rv, nil
 }
 
 func (r *Registry) Register(key string, h HandlerFunc) {
-	r.mu.Lock()
-	r.handlers[key] = h
-	r.mu.Unlock()
+	r.RegisterImpl(key, DefaultImpl, h)
 }
 
 func (r *Registry) Call(ctx context.Context, key string, req any) (any, error) {
-	r.mu.RLock()
-	h := r.handlers[key]
-	r.mu.RUnlock()
+	if base, version, ok := splitVersion(key); ok {
+		return r.callVersion(ctx, base, version, req)
+	}
+
+	d := r.resolve(key)
+	return r.callDispatch(ctx, &d, req)
+}
+
+// callCached is Call with the dispatch of key kept in cache until the
+// registry changes.
+func (r *Registry) callCached(ctx context.Context, key string, cache *atomic.Pointer[dispatch], req any) (any, error) {
+	if base, version, ok := splitVersion(key); ok {
+		return r.callVersion(ctx, base, version, req)
+	}
 
-	if h == nil {
-		return nil, fmt.Errorf("irpc: handler not found: %s", key)
+	d := *r.resolveCached(key, cache)
+	return r.callDispatch(ctx, &d, req)
+}
+
+func (r *Registry) callDispatch(ctx context.Context, d *dispatch, req any) (any, error) {
+	if d.versioned {
+		if version, ok := r.negotiateVersion(ctx, d); ok {
+			return r.callVersion(ctx, d.key, version, req)
+		}
+	}
+	return r.callResolved(ctx, d, req)
+}
+
+func (r *Registry) callResolved(ctx context.Context, d *dispatch, req any) (any, error) {
+	var res any
+	var err error
+	if d.degrade != nil {
+		res, err = r.callDegraded(ctx, d, req)
+	} else {
+		res, err = r.call(ctx, d, req)
+	}
+	if d.fallback.applies(err) {
+		return d.fallback.call(ctx, req, err)
+	}
+	return res, err
+}
+
+func (r *Registry) call(ctx context.Context, d *dispatch, req any) (any, error) {
+	key := d.key
+	ctx, calls := d.enter(ctx)
+
+	if err := d.check(calls); err != nil {
+		return nil, err
+	}
+	if d.responder != nil {
+		return d.responder(ctx, key, req)
+	}
+	if d.aggregate != nil {
+		return r.callAggregate(ctx, d, calls, req)
 	}
 
-	return h(ctx, req)
+	ctx, im := selectImpl(ctx, d.impls, d.route)
+	if d.hedge != nil && len(d.impls) > 1 {
+		return r.callHedged(ctx, d, im, calls, req)
+	}
+	return r.invoke(ctx, d, im, calls, req)
+}
+
+func (r *Registry) Keys() []string {
+	r.mu.RLock()
+	keys := make([]string, 0, len(r.entries))
+	for key, e := range r.entries {
+		if len(e.impls) > 0 {
+			keys = append(keys, key)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Strings(keys)
+	return keys
 }
 
 func (r *Registry) ValidateImpl(serviceName string, iface any) {
@@ -186,10 +594,10 @@ func (r *Registry) ValidateImpl(serviceName string, iface any) {
 		key := serviceName + "." + mName
 
 		r.mu.RLock()
-		_, exists := r.handlers[key]
+		e := r.entries[key]
 		r.mu.RUnlock()
 
-		if !exists {
+		if e == nil || len(e.impls) == 0 {
 			panic(fmt.Sprintf("irpc: missing registered handler for %s", key))
 		}
 	}
@@ -0,0 +1,181 @@
+package irpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type shapes struct{}
+
+func (shapes) One(ctx context.Context, n int) (int, error) { return n, nil }
+
+func (shapes) Three(ctx context.Context, a, b, c int) (int, error) { return a + b + c, nil }
+
+func (shapes) Sum(ctx context.Context, base int, ns ...int) (int, error) {
+	for _, n := range ns {
+		base += n
+	}
+	return base, nil
+}
+
+type point struct{ X, Y int }
+
+func (shapes) None(ctx context.Context) error { return nil }
+
+func (shapes) NoContext(n int) int { return n }
+
+func (shapes) Value(ctx context.Context, p point) (int, error) { return p.X + p.Y, nil }
+
+func (shapes) Pointer(ctx context.Context, p *point) (bool, error) { return p == nil, nil }
+
+func (shapes) Only(ns ...int) int { return len(ns) }
+
+func (shapes) Keep(ctx context.Context, base int, ns ...int) ([]int, error) { return ns, nil }
+
+func (shapes) Pair(ctx context.Context, a int, b string) (int, string, error) { return a, b, nil }
+
+func (shapes) Fail(ctx context.Context) (int, error) { return 0, errors.New("failed") }
+
+// handlerOf returns the handler RegisterContract builds for method of
+// recv.
+func handlerOf(recv any, method string) HandlerFunc {
+	v := reflect.ValueOf(recv)
+	m, ok := v.Type().MethodByName(method)
+	if !ok {
+		panic("no method " + method)
+	}
+	return makeHandler(v, m)
+}
+
+// BenchmarkMakeHandler measures the handlers of contract methods, whose
+// argument slices are pooled. On amd64, allocating them per call instead
+// takes One and Three from 4 allocations (88 B) to 5 (168 B and 216 B)
+// per call, and Sum from 7 allocations (224 B) to 9 (512 B).
+func BenchmarkMakeHandler(b *testing.B) {
+	ctx := context.Background()
+	benchmarks := []struct {
+		method string
+		req    any
+	}{
+		{"One", 1},
+		{"Three", []any{1, 2, 3}},
+		{"Sum", []any{1, 2, 3}},
+	}
+	for _, bm := range benchmarks {
+		h := handlerOf(shapes{}, bm.method)
+		b.Run(bm.method, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				h(ctx, bm.req)
+			}
+		})
+	}
+}
+
+func BenchmarkMakeHandlerParallel(b *testing.B) {
+	ctx := context.Background()
+	for _, method := range []string{"Three", "Sum"} {
+		h := handlerOf(shapes{}, method)
+		b.Run(method, func(b *testing.B) {
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					h(ctx, []any{1, 2, 3})
+				}
+			})
+		})
+	}
+}
+
+// TestMakeHandlerConcurrent checks, under -race, that the pooled argument
+// slices are not shared between concurrent calls, nor with the slice a
+// variadic method keeps.
+func TestMakeHandlerConcurrent(t *testing.T) {
+	h := handlerOf(shapes{}, "Keep")
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Go(func() {
+			var kept [][]int
+			for i := range 100 {
+				req := []any{0, g, i, g * i}
+				if i%2 == 1 {
+					req = []any{0, []int{g, i, g * i}}
+				}
+				res, err := h(context.Background(), req)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				kept = append(kept, res.([]int))
+			}
+			for i, ns := range kept {
+				if want := fmt.Sprint([]int{g, i, g * i}); fmt.Sprint(ns) != want {
+					t.Errorf("goroutine %d call %d kept %v, want %s", g, i, ns, want)
+				}
+			}
+		})
+	}
+	wg.Wait()
+}
+
+func TestMakeHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		req    any
+		want   any
+		code   Code // of the error, OK for none
+	}{
+		{"no parameters", "None", nil, nil, OK},
+		{"no parameters ignores the request", "None", 1, nil, OK},
+		{"no context", "NoContext", 3, 3, OK},
+		{"one parameter", "One", 7, 7, OK},
+		{"nil request is the zero value", "One", nil, 0, OK},
+		{"wrong request type", "One", "7", nil, InvalidArgument},
+		{"value parameter", "Value", point{1, 2}, 3, OK},
+		{"nil request for a value parameter", "Value", nil, 0, OK},
+		{"nil pointer for a value parameter", "Value", (*point)(nil), nil, InvalidArgument},
+		{"nil request for a pointer parameter", "Pointer", nil, true, OK},
+		{"nil pointer for a pointer parameter", "Pointer", (*point)(nil), true, OK},
+		{"several parameters", "Three", []any{1, 2, 3}, 6, OK},
+		{"several parameters with a nil element", "Three", []any{1, nil, 3}, 4, OK},
+		{"too few arguments", "Three", []any{1, 2}, nil, InvalidArgument},
+		{"too many arguments", "Three", []any{1, 2, 3, 4}, nil, InvalidArgument},
+		{"several parameters without a list", "Three", 1, nil, InvalidArgument},
+		{"nil request for several parameters", "Three", nil, nil, InvalidArgument},
+		{"wrong element type", "Three", []any{1, "2", 3}, nil, InvalidArgument},
+		{"variadic elements", "Sum", []any{1, 2, 3}, 6, OK},
+		{"variadic slice", "Sum", []any{1, []int{2, 3}}, 6, OK},
+		{"empty variadic", "Sum", []any{1}, 1, OK},
+		{"variadic missing fixed argument", "Sum", []any{}, nil, InvalidArgument},
+		{"wrong variadic element type", "Sum", []any{1, "2"}, nil, InvalidArgument},
+		{"only variadic slice", "Only", []int{1, 2}, 2, OK},
+		{"only variadic element", "Only", 1, 1, OK},
+		{"only variadic nil request", "Only", nil, 0, OK},
+		{"only variadic wrong type", "Only", "1", nil, InvalidArgument},
+		{"several results", "Pair", []any{1, "a"}, []any{1, "a"}, OK},
+		{"error result", "Fail", nil, nil, Unknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := handlerOf(shapes{}, tt.method)(context.Background(), tt.req)
+			if tt.code != OK {
+				if err == nil || CodeOf(err) != tt.code {
+					t.Fatalf("got %v, %v, want a %s error", res, err, tt.code)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(res, tt.want) {
+				t.Fatalf("got %#v, want %#v", res, tt.want)
+			}
+		})
+	}
+}
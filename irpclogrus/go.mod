@@ -0,0 +1,12 @@
+module github.com/khunfloat/irpc/irpclogrus
+
+go 1.25.2
+
+require (
+	github.com/khunfloat/irpc v0.0.0
+	github.com/sirupsen/logrus v1.10.2
+)
+
+require golang.org/x/sys v0.13.0 // indirect
+
+replace github.com/khunfloat/irpc => ../
@@ -0,0 +1,42 @@
+// Package irpclogrus adapts irpc logging hooks to github.com/sirupsen/logrus.
+package irpclogrus
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/khunfloat/irpc"
+	"github.com/sirupsen/logrus"
+)
+
+// AccessLogger returns an irpc.AccessLogger writing calls to logger as
+// structured fields, at the level of each record:
+//
+//	registry.Use(irpc.AccessLog(irpclogrus.AccessLogger(logrus.StandardLogger())))
+func AccessLogger(logger logrus.FieldLogger) irpc.AccessLogger {
+	return irpc.AccessLoggerFunc(func(ctx context.Context, rec irpc.AccessRecord) {
+		logger.WithFields(Fields(rec.Attrs())).WithContext(ctx).Log(Level(rec.Level()), "irpc: call")
+	})
+}
+
+// Level maps a slog level to the closest logrus level.
+func Level(l slog.Level) logrus.Level {
+	switch {
+	case l >= slog.LevelError:
+		return logrus.ErrorLevel
+	case l >= slog.LevelWarn:
+		return logrus.WarnLevel
+	case l >= slog.LevelInfo:
+		return logrus.InfoLevel
+	}
+	return logrus.DebugLevel
+}
+
+// Fields converts slog attributes to logrus fields, keeping their values.
+func Fields(attrs []slog.Attr) logrus.Fields {
+	fields := make(logrus.Fields, len(attrs))
+	for _, a := range attrs {
+		fields[a.Key] = a.Value.Resolve().Any()
+	}
+	return fields
+}
@@ -0,0 +1,108 @@
+package irpcotel
+
+import (
+	"context"
+
+	"github.com/khunfloat/irpc"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// WithAsyncStats makes Metrics also report the async calls of registry,
+// observed from Registry.AsyncStatsByKey at every collection, with the
+// same attributes as the call metrics:
+//
+//   - irpc.async.queue.depth       gauge of calls queued, running or waiting to be redelivered
+//   - irpc.async.queue.oldest_age  gauge of the age of the oldest of them, in seconds
+//   - irpc.async.completed         counter of calls that succeeded
+//   - irpc.async.failed            counter of calls that failed for good
+//   - irpc.async.retries           counter of redeliveries
+//   - irpc.async.dead_letters      counter of calls recorded as dead letters
+//
+// Processing rates are the rates of the counters.
+func WithAsyncStats(registry *irpc.Registry) Option {
+	return func(c *config) {
+		c.asyncRegistry = registry
+	}
+}
+
+// asyncTotals aggregates the async stats of the keys sharing a set of
+// attributes.
+type asyncTotals struct {
+	attrs       attribute.Set
+	depth       int64
+	oldest      float64
+	completed   int64
+	failed      int64
+	retries     int64
+	deadLetters int64
+}
+
+func registerAsyncMetrics(meter metric.Meter, registry *irpc.Registry, labels *labels) error {
+	depth, err := meter.Int64ObservableGauge("irpc.async.queue.depth",
+		metric.WithDescription("Number of async calls queued, running or waiting to be redelivered."),
+		metric.WithUnit("{call}"))
+	if err != nil {
+		return err
+	}
+	oldest, err := meter.Float64ObservableGauge("irpc.async.queue.oldest_age",
+		metric.WithDescription("Age of the oldest async call not settled yet."),
+		metric.WithUnit("s"))
+	if err != nil {
+		return err
+	}
+	completed, err := meter.Int64ObservableCounter("irpc.async.completed",
+		metric.WithDescription("Number of async calls that succeeded."),
+		metric.WithUnit("{call}"))
+	if err != nil {
+		return err
+	}
+	failed, err := meter.Int64ObservableCounter("irpc.async.failed",
+		metric.WithDescription("Number of async calls that failed after their last attempt."),
+		metric.WithUnit("{call}"))
+	if err != nil {
+		return err
+	}
+	retries, err := meter.Int64ObservableCounter("irpc.async.retries",
+		metric.WithDescription("Number of async call redeliveries."),
+		metric.WithUnit("{call}"))
+	if err != nil {
+		return err
+	}
+	deadLetters, err := meter.Int64ObservableCounter("irpc.async.dead_letters",
+		metric.WithDescription("Number of async calls recorded as dead letters."),
+		metric.WithUnit("{call}"))
+	if err != nil {
+		return err
+	}
+
+	_, err = meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		totals := make(map[attribute.Distinct]*asyncTotals)
+		for key, s := range registry.AsyncStatsByKey() {
+			attrs := labels.attributes(key)
+			t := totals[attrs.Equivalent()]
+			if t == nil {
+				t = &asyncTotals{attrs: attrs}
+				totals[attrs.Equivalent()] = t
+			}
+			t.depth += int64(s.Depth())
+			t.oldest = max(t.oldest, s.Oldest.Seconds())
+			t.completed += int64(s.Completed)
+			t.failed += int64(s.Failed)
+			t.retries += int64(s.Retries)
+			t.deadLetters += int64(s.DeadLetters)
+		}
+
+		for _, t := range totals {
+			attrs := metric.WithAttributeSet(t.attrs)
+			o.ObserveInt64(depth, t.depth, attrs)
+			o.ObserveFloat64(oldest, t.oldest, attrs)
+			o.ObserveInt64(completed, t.completed, attrs)
+			o.ObserveInt64(failed, t.failed, attrs)
+			o.ObserveInt64(retries, t.retries, attrs)
+			o.ObserveInt64(deadLetters, t.deadLetters, attrs)
+		}
+		return nil
+	}, depth, oldest, completed, failed, retries, deadLetters)
+	return err
+}
@@ -0,0 +1,55 @@
+package irpcotel
+
+import (
+	"context"
+
+	"github.com/khunfloat/irpc"
+	"go.opentelemetry.io/otel/baggage"
+)
+
+// BaggageMetadataKey is the metadata entry carrying W3C-encoded baggage.
+const BaggageMetadataKey = "baggage"
+
+// WithBaggageInMetadata makes Baggage mirror the baggage of every call into
+// its metadata, so that it survives transports that only carry metadata,
+// such as bridges, durable calls and context boundaries.
+func WithBaggageInMetadata() Option {
+	return func(c *config) {
+		c.baggageMetadata = true
+	}
+}
+
+// Baggage returns middleware that propagates OpenTelemetry baggage across
+// calls. A handler whose context has no baggage gets the baggage carried
+// in its metadata, e.g. when the call arrived through a bridge.
+//
+// Add it before Bridge.Produce so that produced messages carry the baggage.
+func Baggage(opts ...Option) irpc.Middleware {
+	c := newConfig(opts)
+
+	return func(key string, next irpc.HandlerFunc) irpc.HandlerFunc {
+		return func(ctx context.Context, req any) (any, error) {
+			if baggage.FromContext(ctx).Len() == 0 {
+				if v, ok := irpc.MetadataValue(ctx, BaggageMetadataKey); ok {
+					if b, err := baggage.Parse(v); err == nil {
+						ctx = baggage.ContextWithBaggage(ctx, b)
+					}
+				}
+			} else if c.baggageMetadata {
+				ctx = ContextWithBaggageMetadata(ctx)
+			}
+			return next(ctx, req)
+		}
+	}
+}
+
+// ContextWithBaggageMetadata returns a copy of ctx whose metadata carries
+// the baggage of ctx. Use it at entry points when a context boundary
+// strips the baggage before middleware can see it.
+func ContextWithBaggageMetadata(ctx context.Context) context.Context {
+	b := baggage.FromContext(ctx)
+	if b.Len() == 0 {
+		return ctx
+	}
+	return irpc.WithMetadataValue(ctx, BaggageMetadataKey, b.String())
+}
@@ -0,0 +1,66 @@
+package irpcotel
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// InProcessDurationBuckets are rpc.server.duration boundaries, in
+// milliseconds, spanning sub-microsecond in-process calls to multi-second
+// ones. The SDK defaults start at 5ms, which puts most irpc calls in the
+// first bucket.
+var InProcessDurationBuckets = []float64{
+	0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5,
+	1, 2.5, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000,
+}
+
+// WithDurationBuckets sets the boundaries, in milliseconds, advised for
+// the rpc.server.duration histogram. A View configured in the SDK takes
+// precedence, which is also how to select an exponential (native)
+// histogram aggregation instead of explicit buckets.
+func WithDurationBuckets(bounds ...float64) Option {
+	return func(c *config) {
+		c.durationBuckets = bounds
+	}
+}
+
+// WithServiceDurationBuckets sets the rpc.server.duration boundaries of
+// one service, e.g. a batch service whose calls take seconds. Its
+// durations are recorded by a histogram of the same name in an
+// instrumentation scope carrying an rpc.service attribute, since an
+// instrument has a single set of boundaries.
+func WithServiceDurationBuckets(service string, bounds ...float64) Option {
+	return func(c *config) {
+		if c.serviceBuckets == nil {
+			c.serviceBuckets = make(map[string][]float64)
+		}
+		c.serviceBuckets[service] = bounds
+	}
+}
+
+func newDurationHistogram(meter metric.Meter, bounds []float64) (metric.Float64Histogram, error) {
+	opts := []metric.Float64HistogramOption{
+		metric.WithDescription("Measures the duration of inbound RPC."),
+		metric.WithUnit("ms"),
+	}
+	if len(bounds) > 0 {
+		opts = append(opts, metric.WithExplicitBucketBoundaries(bounds...))
+	}
+	return meter.Float64Histogram("rpc.server.duration", opts...)
+}
+
+// durationHistograms returns the rpc.server.duration histogram of every
+// service with its own boundaries.
+func durationHistograms(c *config) (map[string]metric.Float64Histogram, error) {
+	hs := make(map[string]metric.Float64Histogram, len(c.serviceBuckets))
+	for service, bounds := range c.serviceBuckets {
+		meter := c.meterProvider.Meter(instrumentationName,
+			metric.WithInstrumentationAttributes(attribute.String("rpc.service", service)))
+		h, err := newDurationHistogram(meter, bounds)
+		if err != nil {
+			return nil, err
+		}
+		hs[service] = h
+	}
+	return hs, nil
+}
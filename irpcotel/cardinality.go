@@ -0,0 +1,102 @@
+package irpcotel
+
+import (
+	"path"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// OtherLabel replaces the service and method attributes of keys whose
+// metrics are collapsed.
+const OtherLabel = "other"
+
+// WithAllowedKeys limits full rpc.service and rpc.method attributes to keys
+// matching one of patterns (path.Match syntax), e.g. "Exam.*". Metrics of
+// other keys are grouped under OtherLabel, so registries with many dynamic
+// keys keep a bounded number of series.
+func WithAllowedKeys(patterns ...string) Option {
+	return func(c *config) {
+		c.allowedKeys = append(c.allowedKeys, patterns...)
+	}
+}
+
+// WithCollapsedMethods keeps the rpc.service attribute of keys matching
+// one of patterns but groups their methods under OtherLabel.
+func WithCollapsedMethods(patterns ...string) Option {
+	return func(c *config) {
+		c.collapsedMethods = append(c.collapsedMethods, patterns...)
+	}
+}
+
+// WithMaxKeys labels at most n distinct keys; metrics of keys seen after
+// the first n are grouped under OtherLabel.
+func WithMaxKeys(n int) Option {
+	return func(c *config) {
+		c.maxKeys = n
+	}
+}
+
+// labels decides the attributes the metrics of keys are recorded with.
+type labels struct {
+	allowed   []string
+	collapsed []string
+	max       int
+
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+func newLabels(c *config) *labels {
+	return &labels{
+		allowed:   c.allowedKeys,
+		collapsed: c.collapsedMethods,
+		max:       c.maxKeys,
+		seen:      make(map[string]bool),
+	}
+}
+
+func (l *labels) attributes(key string) attribute.Set {
+	if len(l.allowed) > 0 && !matchAny(l.allowed, key) {
+		return otherAttributes(OtherLabel)
+	}
+	if l.max > 0 && !l.admit(key) {
+		return otherAttributes(OtherLabel)
+	}
+	if matchAny(l.collapsed, key) {
+		return otherAttributes(serviceOf(key))
+	}
+	return keyAttributes(key)
+}
+
+// admit reports whether key is among the first max keys seen.
+func (l *labels) admit(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.seen[key] {
+		return true
+	}
+	if len(l.seen) >= l.max {
+		return false
+	}
+	l.seen[key] = true
+	return true
+}
+
+func otherAttributes(service string) attribute.Set {
+	return attribute.NewSet(
+		attribute.String("rpc.system", "irpc"),
+		attribute.String("rpc.service", service),
+		attribute.String("rpc.method", OtherLabel),
+	)
+}
+
+func matchAny(patterns []string, key string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, key); ok {
+			return true
+		}
+	}
+	return false
+}
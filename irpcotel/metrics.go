@@ -0,0 +1,141 @@
+// Package irpcotel provides OpenTelemetry instrumentation for irpc.
+package irpcotel
+
+import (
+	"context"
+	"time"
+
+	"github.com/khunfloat/irpc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/khunfloat/irpc/irpcotel"
+
+type config struct {
+	meterProvider   metric.MeterProvider
+	tracerProvider  trace.TracerProvider
+	sampler         Sampler
+	errorSampling   bool
+	baggageMetadata bool
+
+	allowedKeys      []string
+	collapsedMethods []string
+	maxKeys          int
+
+	durationBuckets []float64
+	serviceBuckets  map[string][]float64
+
+	asyncRegistry *irpc.Registry
+	sloRegistry   *irpc.Registry
+}
+
+// Option configures the instrumentation.
+type Option func(*config)
+
+// WithMeterProvider sets the MeterProvider used to create instruments.
+// The global provider is used by default.
+func WithMeterProvider(mp metric.MeterProvider) Option {
+	return func(c *config) {
+		c.meterProvider = mp
+	}
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{
+		meterProvider:  otel.GetMeterProvider(),
+		tracerProvider: otel.GetTracerProvider(),
+		sampler:        AlwaysSample(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Metrics returns middleware that records the standard rpc.server.*
+// metrics for every call, attributed with rpc.system, rpc.service and
+// rpc.method:
+//
+//   - rpc.server.duration  histogram of call latency in milliseconds
+//   - rpc.server.requests  counter of calls
+//   - rpc.server.errors    counter of calls that returned an error
+//
+// WithAllowedKeys, WithCollapsedMethods and WithMaxKeys bound the number
+// of attribute sets; WithDurationBuckets and WithServiceDurationBuckets set
+// the histogram boundaries. WithAsyncStats adds the async queue metrics,
+// and WithSLOs those of the SLOs.
+func Metrics(opts ...Option) (irpc.Middleware, error) {
+	c := newConfig(opts)
+	meter := c.meterProvider.Meter(instrumentationName)
+
+	duration, err := newDurationHistogram(meter, c.durationBuckets)
+	if err != nil {
+		return nil, err
+	}
+	serviceDurations, err := durationHistograms(c)
+	if err != nil {
+		return nil, err
+	}
+	requests, err := meter.Int64Counter("rpc.server.requests",
+		metric.WithDescription("Number of inbound RPC calls."),
+		metric.WithUnit("{call}"))
+	if err != nil {
+		return nil, err
+	}
+	errorsCount, err := meter.Int64Counter("rpc.server.errors",
+		metric.WithDescription("Number of inbound RPC calls that returned an error."),
+		metric.WithUnit("{call}"))
+	if err != nil {
+		return nil, err
+	}
+
+	labels := newLabels(c)
+	if c.asyncRegistry != nil {
+		if err := registerAsyncMetrics(meter, c.asyncRegistry, labels); err != nil {
+			return nil, err
+		}
+	}
+	if c.sloRegistry != nil {
+		if err := registerSLOMetrics(meter, c.sloRegistry); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(key string, next irpc.HandlerFunc) irpc.HandlerFunc {
+		attrs := metric.WithAttributeSet(labels.attributes(key))
+		duration := duration
+		if h, ok := serviceDurations[serviceOf(key)]; ok {
+			duration = h
+		}
+
+		return func(ctx context.Context, req any) (any, error) {
+			start := time.Now()
+			res, err := next(ctx, req)
+			elapsed := float64(time.Since(start)) / float64(time.Millisecond)
+
+			duration.Record(ctx, elapsed, attrs)
+			requests.Add(ctx, 1, attrs)
+			if err != nil {
+				errorsCount.Add(ctx, 1, attrs)
+			}
+			return res, err
+		}
+	}, nil
+}
+
+func serviceOf(key string) string {
+	service, _ := irpc.SplitKey(key)
+	return service
+}
+
+func keyAttributes(key string) attribute.Set {
+	service, method := irpc.SplitKey(key)
+	return attribute.NewSet(
+		attribute.String("rpc.system", "irpc"),
+		attribute.String("rpc.service", service),
+		attribute.String("rpc.method", method),
+	)
+}
@@ -0,0 +1,64 @@
+package irpcotel
+
+import (
+	"context"
+
+	"github.com/khunfloat/irpc"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// WithSLOs makes Metrics also report the SLOs of registry, observed from
+// Registry.SLOs at every collection. Each key with an SLO is attributed
+// with rpc.system, rpc.service and rpc.method, whatever the other options
+// collapse, and irpc.slo.objective, "availability" or "latency":
+//
+//   - irpc.slo.burn_rate         gauge of the rate the error budget is spent at, 1 spending it over the window
+//   - irpc.slo.budget_remaining  gauge of the fraction of the error budget left in the window
+func WithSLOs(registry *irpc.Registry) Option {
+	return func(c *config) {
+		c.sloRegistry = registry
+	}
+}
+
+func registerSLOMetrics(meter metric.Meter, registry *irpc.Registry) error {
+	burnRate, err := meter.Float64ObservableGauge("irpc.slo.burn_rate",
+		metric.WithDescription("Rate at which the error budget of the SLO is spent."),
+		metric.WithUnit("1"))
+	if err != nil {
+		return err
+	}
+	budget, err := meter.Float64ObservableGauge("irpc.slo.budget_remaining",
+		metric.WithDescription("Fraction of the error budget of the SLO left in its window."),
+		metric.WithUnit("1"))
+	if err != nil {
+		return err
+	}
+
+	_, err = meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		for _, s := range registry.SLOs() {
+			if s.SLO.Availability > 0 {
+				attrs := sloAttributes(s.Key, "availability")
+				o.ObserveFloat64(burnRate, s.AvailabilityBurnRate, attrs)
+				o.ObserveFloat64(budget, s.AvailabilityBudget, attrs)
+			}
+			if s.SLO.Latency > 0 {
+				attrs := sloAttributes(s.Key, "latency")
+				o.ObserveFloat64(burnRate, s.LatencyBurnRate, attrs)
+				o.ObserveFloat64(budget, s.LatencyBudget, attrs)
+			}
+		}
+		return nil
+	}, burnRate, budget)
+	return err
+}
+
+func sloAttributes(key, objective string) metric.MeasurementOption {
+	service, method := irpc.SplitKey(key)
+	return metric.WithAttributes(
+		attribute.String("rpc.system", "irpc"),
+		attribute.String("rpc.service", service),
+		attribute.String("rpc.method", method),
+		attribute.String("irpc.slo.objective", objective),
+	)
+}
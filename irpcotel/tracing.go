@@ -0,0 +1,169 @@
+package irpcotel
+
+import (
+	"context"
+	"math/rand/v2"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/khunfloat/irpc"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Sampler decides whether a call that is not part of a sampled trace
+// starts one. Calls made inside a sampled span are always traced, so
+// traces stay complete.
+type Sampler interface {
+	ShouldSample(ctx context.Context, key string) bool
+}
+
+// SamplerFunc adapts a function to a Sampler.
+type SamplerFunc func(ctx context.Context, key string) bool
+
+func (f SamplerFunc) ShouldSample(ctx context.Context, key string) bool {
+	return f(ctx, key)
+}
+
+// AlwaysSample traces every call.
+func AlwaysSample() Sampler {
+	return SamplerFunc(func(context.Context, string) bool { return true })
+}
+
+// NeverSample starts no traces; with WithErrorSampling only failing calls
+// are traced.
+func NeverSample() Sampler {
+	return SamplerFunc(func(context.Context, string) bool { return false })
+}
+
+// ProbabilitySampler traces a fraction p of calls.
+func ProbabilitySampler(p float64) Sampler {
+	return SamplerFunc(func(context.Context, string) bool { return rand.Float64() < p })
+}
+
+// RateLimitSampler traces at most perSecond calls per second, with bursts
+// of up to one second's worth.
+func RateLimitSampler(perSecond float64) Sampler {
+	var mu sync.Mutex
+	tokens, last := perSecond, time.Now()
+	return SamplerFunc(func(context.Context, string) bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		now := time.Now()
+		tokens = min(perSecond, tokens+now.Sub(last).Seconds()*perSecond)
+		last = now
+		if tokens < 1 {
+			return false
+		}
+		tokens--
+		return true
+	})
+}
+
+// KeySampler uses the sampler of the first override whose pattern
+// (path.Match syntax) matches the key, and def otherwise:
+//
+//	irpcotel.KeySampler(irpcotel.ProbabilitySampler(0.01),
+//		irpcotel.SamplerOverride{Pattern: "Billing.*", Sampler: irpcotel.AlwaysSample()})
+func KeySampler(def Sampler, overrides ...SamplerOverride) Sampler {
+	var mu sync.RWMutex
+	resolved := make(map[string]Sampler)
+	return SamplerFunc(func(ctx context.Context, key string) bool {
+		mu.RLock()
+		s, ok := resolved[key]
+		mu.RUnlock()
+		if !ok {
+			s = def
+			for _, o := range overrides {
+				if match, _ := path.Match(o.Pattern, key); match {
+					s = o.Sampler
+					break
+				}
+			}
+			mu.Lock()
+			resolved[key] = s
+			mu.Unlock()
+		}
+		return s.ShouldSample(ctx, key)
+	})
+}
+
+// SamplerOverride is a per-key sampler of KeySampler.
+type SamplerOverride struct {
+	Pattern string
+	Sampler Sampler
+}
+
+// WithTracerProvider sets the TracerProvider used to create spans. The
+// global provider is used by default.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = tp
+	}
+}
+
+// WithSampler sets the sampler deciding which calls start a trace. Every
+// call is traced by default.
+func WithSampler(s Sampler) Option {
+	return func(c *config) {
+		c.sampler = s
+	}
+}
+
+// WithErrorSampling also traces calls the sampler skipped if they fail:
+// their span is recorded once the call has returned, with its actual start
+// time. Calls they made are only part of the trace if they failed too.
+func WithErrorSampling() Option {
+	return func(c *config) {
+		c.errorSampling = true
+	}
+}
+
+// Tracing returns middleware that records a span for every sampled call,
+// named after the key and attributed like Metrics. Failed calls get an
+// error status and an irpc.code attribute.
+func Tracing(opts ...Option) irpc.Middleware {
+	c := newConfig(opts)
+	tracer := c.tracerProvider.Tracer(instrumentationName)
+
+	return func(key string, next irpc.HandlerFunc) irpc.HandlerFunc {
+		set := keyAttributes(key)
+		attrs := set.ToSlice()
+
+		return func(ctx context.Context, req any) (any, error) {
+			if trace.SpanContextFromContext(ctx).IsSampled() || c.sampler.ShouldSample(ctx, key) {
+				ctx, span := tracer.Start(ctx, key, trace.WithSpanKind(trace.SpanKindInternal), trace.WithAttributes(attrs...))
+				defer span.End()
+
+				res, err := next(ctx, req)
+				recordError(span, err)
+				return res, err
+			}
+
+			if !c.errorSampling {
+				return next(ctx, req)
+			}
+			start := time.Now()
+			res, err := next(ctx, req)
+			if err != nil {
+				_, span := tracer.Start(ctx, key, trace.WithSpanKind(trace.SpanKindInternal),
+					trace.WithAttributes(attrs...), trace.WithTimestamp(start))
+				recordError(span, err)
+				span.End()
+			}
+			return res, err
+		}
+	}
+}
+
+func recordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	span.SetAttributes(attribute.String("irpc.code", irpc.CodeOf(err).String()))
+}
@@ -0,0 +1,77 @@
+// Package irpctest provides helpers for testing code built on irpc.
+package irpctest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/khunfloat/irpc"
+)
+
+// LeakTimeout is how long VerifyNoLeaks waits for resources to be released
+// before failing the test.
+var LeakTimeout = time.Second
+
+// VerifyNoLeaks fails t if, when the test finishes, registry still holds
+// resources it did not hold when VerifyNoLeaks was called: async calls
+// that never finished, calls still being served, unclosed streams,
+// schedules, a watchdog or an executor that was never shut down. Each leak
+// is reported with its kind and originating key.
+//
+//	func TestCheckout(t *testing.T) {
+//		registry := irpc.NewRegistry(irpc.DEFAULT_CONFIG)
+//		irpctest.VerifyNoLeaks(t, registry)
+//		defer registry.Shutdown(context.Background())
+//		...
+//	}
+//
+// Deferred cleanup runs before the check, so a registry shut down with
+// defer or t.Cleanup registered after VerifyNoLeaks is not reported.
+func VerifyNoLeaks(t testing.TB, registry *irpc.Registry) {
+	t.Helper()
+
+	baseline := make(map[irpc.Resource]int)
+	for _, res := range registry.OpenResources() {
+		baseline[key(res)] += res.Count
+	}
+
+	t.Cleanup(func() {
+		t.Helper()
+		deadline := time.Now().Add(LeakTimeout)
+		for {
+			leaked := leaks(baseline, registry.OpenResources())
+			if len(leaked) == 0 {
+				return
+			}
+			if time.Now().After(deadline) {
+				var b strings.Builder
+				for _, res := range leaked {
+					b.WriteString("\n\t")
+					b.WriteString(res.String())
+				}
+				t.Errorf("irpc: registry leaked %d resource(s):%s", len(leaked), b.String())
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+}
+
+// leaks returns the resources of open beyond those counted in baseline.
+func leaks(baseline map[irpc.Resource]int, open []irpc.Resource) []irpc.Resource {
+	var out []irpc.Resource
+	for _, res := range open {
+		if n := res.Count - baseline[key(res)]; n > 0 {
+			res.Count = n
+			out = append(out, res)
+		}
+	}
+	return out
+}
+
+// key identifies res regardless of its count.
+func key(res irpc.Resource) irpc.Resource {
+	res.Count = 0
+	return res
+}
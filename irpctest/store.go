@@ -0,0 +1,342 @@
+package irpctest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/khunfloat/irpc"
+)
+
+// TestStore checks that the stores returned by newStore implement the
+// irpc.Store contract, directly and through the durable call, dead-letter
+// and idempotency adapters. Every subtest gets a fresh, empty store.
+// Backends must pass it before being used with the durable features:
+//
+//	func TestRedisStore(t *testing.T) {
+//		irpctest.TestStore(t, func(t *testing.T) irpc.Store {
+//			return newRedisStore(t)
+//		})
+//	}
+func TestStore(t *testing.T, newStore func(t *testing.T) irpc.Store) {
+	for _, c := range storeCases {
+		t.Run(c.name, func(t *testing.T) {
+			c.run(t, newStore(t))
+		})
+	}
+}
+
+var storeCases = []struct {
+	name string
+	run  func(t *testing.T, s irpc.Store)
+}{
+	{"GetMissing", testGetMissing},
+	{"SetGet", testSetGet},
+	{"Overwrite", testOverwrite},
+	{"Delete", testDelete},
+	{"TTL", testTTL},
+	{"List", testList},
+	{"CompareAndSwap", testCompareAndSwap},
+	{"ConcurrentCompareAndSwap", testConcurrentCompareAndSwap},
+	{"Isolation", testIsolation},
+	{"DeferredStore", testDeferredStore},
+	{"DeadLetterStore", testDeadLetterStore},
+	{"IdempotencyStore", testIdempotencyStore},
+}
+
+func mustGet(t *testing.T, s irpc.Store, key string) ([]byte, bool) {
+	t.Helper()
+	v, ok, err := s.Get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Get(%q): %v", key, err)
+	}
+	return v, ok
+}
+
+func mustSet(t *testing.T, s irpc.Store, key, value string, ttl time.Duration) {
+	t.Helper()
+	if err := s.Set(context.Background(), key, []byte(value), ttl); err != nil {
+		t.Fatalf("Set(%q): %v", key, err)
+	}
+}
+
+func testGetMissing(t *testing.T, s irpc.Store) {
+	if _, ok := mustGet(t, s, "missing"); ok {
+		t.Fatal("Get of a missing key reported it present")
+	}
+}
+
+func testSetGet(t *testing.T, s irpc.Store) {
+	keys := []string{"a", "a/b", "with space", "unicode/ключ", "..", "colon:star*"}
+	for _, k := range keys {
+		mustSet(t, s, k, "value of "+k, 0)
+	}
+	for _, k := range keys {
+		v, ok := mustGet(t, s, k)
+		if !ok || string(v) != "value of "+k {
+			t.Errorf("Get(%q) = %q, %v; want %q, true", k, v, ok, "value of "+k)
+		}
+	}
+}
+
+func testOverwrite(t *testing.T, s irpc.Store) {
+	mustSet(t, s, "k", "one", 0)
+	mustSet(t, s, "k", "two", 0)
+	if v, _ := mustGet(t, s, "k"); string(v) != "two" {
+		t.Errorf("Get after overwrite = %q, want %q", v, "two")
+	}
+}
+
+func testDelete(t *testing.T, s irpc.Store) {
+	ctx := context.Background()
+	mustSet(t, s, "k", "v", 0)
+	if err := s.Delete(ctx, "k"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, ok := mustGet(t, s, "k"); ok {
+		t.Error("Get after Delete reported the key present")
+	}
+	if err := s.Delete(ctx, "k"); err != nil {
+		t.Errorf("Delete of a missing key: %v", err)
+	}
+}
+
+func testTTL(t *testing.T, s irpc.Store) {
+	ctx := context.Background()
+	mustSet(t, s, "short", "v", 50*time.Millisecond)
+	mustSet(t, s, "forever", "v", 0)
+	if _, ok := mustGet(t, s, "short"); !ok {
+		t.Fatal("entry expired before its ttl")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if _, ok := mustGet(t, s, "short"); ok {
+		t.Error("Get returned an expired entry")
+	}
+	if _, ok := mustGet(t, s, "forever"); !ok {
+		t.Error("entry without ttl expired")
+	}
+	entries, err := s.List(ctx, "")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Key != "forever" {
+		t.Errorf("List after expiry = %v, want only %q", entries, "forever")
+	}
+	ok, err := s.CompareAndSwap(ctx, "short", nil, []byte("new"))
+	if err != nil || !ok {
+		t.Errorf("CompareAndSwap of an expired key as absent = %v, %v; want true", ok, err)
+	}
+}
+
+func testList(t *testing.T, s irpc.Store) {
+	for _, k := range []string{"p/c", "p/a", "q/a", "p/b", "pa"} {
+		mustSet(t, s, k, k, 0)
+	}
+	entries, err := s.List(context.Background(), "p/")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Key)
+		if string(e.Value) != e.Key {
+			t.Errorf("List value of %q = %q", e.Key, e.Value)
+		}
+	}
+	if fmt.Sprint(got) != "[p/a p/b p/c]" {
+		t.Errorf("List(%q) keys = %v, want [p/a p/b p/c]", "p/", got)
+	}
+}
+
+func testCompareAndSwap(t *testing.T, s irpc.Store) {
+	ctx := context.Background()
+	cas := func(old, value []byte, want bool) {
+		t.Helper()
+		ok, err := s.CompareAndSwap(ctx, "k", old, value)
+		if err != nil {
+			t.Fatalf("CompareAndSwap: %v", err)
+		}
+		if ok != want {
+			t.Fatalf("CompareAndSwap(%q, %q) = %v, want %v", old, value, ok, want)
+		}
+	}
+
+	cas([]byte("x"), []byte("one"), false)
+	cas(nil, []byte("one"), true)
+	cas(nil, []byte("two"), false)
+	cas([]byte("two"), []byte("three"), false)
+	cas([]byte("one"), []byte("two"), true)
+	if v, _ := mustGet(t, s, "k"); string(v) != "two" {
+		t.Errorf("Get after CompareAndSwap = %q, want %q", v, "two")
+	}
+
+	mustSet(t, s, "ttl", "one", 50*time.Millisecond)
+	if ok, err := s.CompareAndSwap(ctx, "ttl", []byte("one"), []byte("two")); err != nil || !ok {
+		t.Fatalf("CompareAndSwap of an entry with ttl = %v, %v", ok, err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, ok := mustGet(t, s, "ttl"); ok {
+		t.Error("CompareAndSwap did not keep the entry's ttl")
+	}
+}
+
+func testConcurrentCompareAndSwap(t *testing.T, s irpc.Store) {
+	const n = 16
+	var wg sync.WaitGroup
+	var won atomic.Int32
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := s.CompareAndSwap(context.Background(), "lock", nil, fmt.Appendf(nil, "owner %d", i))
+			if err != nil {
+				t.Errorf("CompareAndSwap: %v", err)
+			}
+			if ok {
+				won.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := won.Load(); got != 1 {
+		t.Errorf("%d of %d concurrent CompareAndSwap calls on an absent key succeeded, want 1", got, n)
+	}
+}
+
+func testIsolation(t *testing.T, s irpc.Store) {
+	value := []byte("original")
+	if err := s.Set(context.Background(), "k", value, 0); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	value[0] = 'X'
+
+	got, _ := mustGet(t, s, "k")
+	if string(got) != "original" {
+		t.Fatalf("store retained the slice given to Set: got %q", got)
+	}
+	got[0] = 'X'
+	if again, _ := mustGet(t, s, "k"); string(again) != "original" {
+		t.Errorf("Get returned a slice aliasing the stored value: got %q", again)
+	}
+}
+
+func testDeferredStore(t *testing.T, s irpc.Store) {
+	ctx := context.Background()
+	d := irpc.NewDeferredStore(s)
+
+	now := time.Now()
+	calls := []irpc.DeferredCall{
+		{ID: "b", Key: "Mail.Send", Payload: []byte(`{"to":"b"}`), Metadata: irpc.Metadata{"m": "1"}, CreatedAt: now.Add(time.Second)},
+		{ID: "a", Key: "Mail.Send", Payload: []byte(`{"to":"a"}`), CreatedAt: now},
+	}
+	for _, c := range calls {
+		if err := d.Save(ctx, c); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+	got, err := d.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(got) != 2 || got[0].ID != "a" || got[1].ID != "b" {
+		t.Fatalf("List = %v, want calls a and b, oldest first", got)
+	}
+	if !bytes.Equal(got[1].Payload, calls[0].Payload) || got[1].Metadata["m"] != "1" {
+		t.Errorf("List did not round-trip the call: %+v", got[1])
+	}
+
+	leaser, ok := d.(irpc.DeferredLeaser)
+	if !ok {
+		t.Fatal("NewDeferredStore does not implement DeferredLeaser")
+	}
+	var wg sync.WaitGroup
+	var leased atomic.Int32
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := leaser.Lease(ctx, "a", time.Now().Add(time.Minute))
+			if err != nil {
+				t.Errorf("Lease: %v", err)
+			}
+			if ok {
+				leased.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := leased.Load(); n != 1 {
+		t.Errorf("%d concurrent leases of one call succeeded, want 1", n)
+	}
+
+	if err := d.Delete(ctx, "a"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if ok, err := leaser.Lease(ctx, "a", time.Now()); ok || err != nil {
+		t.Errorf("Lease of a deleted call = %v, %v; want false, nil", ok, err)
+	}
+	if got, _ := d.List(ctx); len(got) != 1 {
+		t.Errorf("List after Delete returned %d calls, want 1", len(got))
+	}
+}
+
+func testDeadLetterStore(t *testing.T, s irpc.Store) {
+	ctx := context.Background()
+	d := irpc.NewDeadLetterStore(s)
+
+	letter := irpc.DeadLetter{
+		ID:       "l1",
+		Key:      "Mail.Send",
+		Payload:  []byte(`{}`),
+		Attempts: []irpc.Attempt{{At: time.Now(), Code: irpc.Unavailable, Error: "down"}},
+	}
+	if err := d.Add(ctx, letter); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	got, err := d.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(got) != 1 || got[0].ID != "l1" || got[0].LastError() != "down" || got[0].Attempts[0].Code != irpc.Unavailable {
+		t.Fatalf("List = %+v, want the added dead letter", got)
+	}
+	if err := d.Delete(ctx, "l1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if got, _ := d.List(ctx); len(got) != 0 {
+		t.Errorf("List after Delete returned %d dead letters", len(got))
+	}
+}
+
+func testIdempotencyStore(t *testing.T, s irpc.Store) {
+	ctx := context.Background()
+	d := irpc.NewIdempotencyStore(s)
+
+	now := time.Now()
+	records := []irpc.IdempotencyRecord{
+		{ID: "Bill.Charge/1", Payload: []byte(`"ok"`), CreatedAt: now, ExpiresAt: now.Add(time.Minute)},
+		{ID: "Bill.Charge/2", Payload: []byte(`"ok"`), CreatedAt: now, ExpiresAt: now.Add(50 * time.Millisecond)},
+	}
+	for _, rec := range records {
+		if err := d.Put(ctx, rec); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+	rec, ok, err := d.Get(ctx, "Bill.Charge/1")
+	if err != nil || !ok || string(rec.Payload) != `"ok"` {
+		t.Fatalf("Get = %+v, %v, %v; want the record", rec, ok, err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if _, ok, err := d.Get(ctx, "Bill.Charge/2"); ok || err != nil {
+		t.Errorf("Get of an expired record = %v, %v; want false, nil", ok, err)
+	}
+	if _, ok, _ := d.Get(ctx, "Bill.Charge/3"); ok {
+		t.Error("Get of a missing record reported it present")
+	}
+}
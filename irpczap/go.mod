@@ -0,0 +1,12 @@
+module github.com/khunfloat/irpc/irpczap
+
+go 1.25.2
+
+require (
+	github.com/khunfloat/irpc v0.0.0
+	go.uber.org/zap v1.27.0
+)
+
+require go.uber.org/multierr v1.10.0 // indirect
+
+replace github.com/khunfloat/irpc => ../
@@ -0,0 +1,64 @@
+// Package irpczap adapts irpc logging hooks to go.uber.org/zap.
+package irpczap
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/khunfloat/irpc"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// AccessLogger returns an irpc.AccessLogger writing calls to logger as
+// structured fields, at the level of each record:
+//
+//	registry.Use(irpc.AccessLog(irpczap.AccessLogger(logger)))
+func AccessLogger(logger *zap.Logger) irpc.AccessLogger {
+	return irpc.AccessLoggerFunc(func(_ context.Context, rec irpc.AccessRecord) {
+		level := Level(rec.Level())
+		if ce := logger.Check(level, "irpc: call"); ce != nil {
+			ce.Write(Fields(rec.Attrs())...)
+		}
+	})
+}
+
+// Level maps a slog level to the closest zap level.
+func Level(l slog.Level) zapcore.Level {
+	switch {
+	case l >= slog.LevelError:
+		return zapcore.ErrorLevel
+	case l >= slog.LevelWarn:
+		return zapcore.WarnLevel
+	case l >= slog.LevelInfo:
+		return zapcore.InfoLevel
+	}
+	return zapcore.DebugLevel
+}
+
+// Fields converts slog attributes to zap fields, keeping their types.
+func Fields(attrs []slog.Attr) []zap.Field {
+	fields := make([]zap.Field, 0, len(attrs))
+	for _, a := range attrs {
+		v := a.Value.Resolve()
+		switch v.Kind() {
+		case slog.KindString:
+			fields = append(fields, zap.String(a.Key, v.String()))
+		case slog.KindInt64:
+			fields = append(fields, zap.Int64(a.Key, v.Int64()))
+		case slog.KindUint64:
+			fields = append(fields, zap.Uint64(a.Key, v.Uint64()))
+		case slog.KindFloat64:
+			fields = append(fields, zap.Float64(a.Key, v.Float64()))
+		case slog.KindBool:
+			fields = append(fields, zap.Bool(a.Key, v.Bool()))
+		case slog.KindDuration:
+			fields = append(fields, zap.Duration(a.Key, v.Duration()))
+		case slog.KindTime:
+			fields = append(fields, zap.Time(a.Key, v.Time()))
+		default:
+			fields = append(fields, zap.Any(a.Key, v.Any()))
+		}
+	}
+	return fields
+}
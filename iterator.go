@@ -0,0 +1,205 @@
+package irpc
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"reflect"
+	"sync"
+)
+
+// StreamHandlerFunc serves a streaming call: it sends its results one by
+// one with send, which blocks until the consumer asks for the next one and
+// fails once the consumer has stopped.
+type StreamHandlerFunc func(ctx context.Context, req any, send func(any) error) error
+
+// RegisterStream registers h as the streaming handler of key. Calling key
+// starts h and returns a stream to be read with Iterate; middleware sees
+// the start of the call, not the individual results.
+//
+//	registry.RegisterStream("Exam.Watch", func(ctx context.Context, req any, send func(any) error) error {
+//		for _, e := range exams {
+//			if err := send(e); err != nil {
+//				return err
+//			}
+//		}
+//		return nil
+//	})
+func (r *Registry) RegisterStream(key string, h StreamHandlerFunc) {
+	r.Register(key, func(ctx context.Context, req any) (any, error) {
+		return r.startItems(ctx, key, req, h), nil
+	})
+}
+
+// itemStream runs a StreamHandlerFunc on its own goroutine and hands its
+// results over one at a time. It stays in the registry's streams until
+// the handler has returned.
+type itemStream struct {
+	ctx    context.Context
+	items  chan any
+	done   chan struct{}
+	err    error
+	cancel context.CancelCauseFunc
+}
+
+func (r *Registry) startItems(ctx context.Context, key string, req any, h StreamHandlerFunc) *itemStream {
+	// The stream outlives the call, and middleware may cancel the call's
+	// context when it returns; the consumer ends it with Close instead.
+	ctx, cancel := context.WithCancelCause(context.WithoutCancel(ctx))
+	s := &itemStream{ctx: ctx, items: make(chan any), done: make(chan struct{}), cancel: cancel}
+
+	service, _ := SplitKey(key)
+	r.mu.RLock()
+	policy := r.panicPolicy(service)
+	r.mu.RUnlock()
+
+	r.streams.add(key, s)
+	go func() {
+		defer r.streams.remove(s)
+		defer close(s.done)
+		defer cancel(nil)
+		defer r.recoverPanic(key, nil, policy, &s.err)
+
+		s.err = h(ctx, req, func(v any) error {
+			select {
+			case s.items <- v:
+				return nil
+			case <-ctx.Done():
+				return context.Cause(ctx)
+			}
+		})
+	}()
+	return s
+}
+
+// next returns the next result, or ok false once the handler has returned.
+// Whatever the handler returns after the stream was closed is not an
+// error.
+func (s *itemStream) next(ctx context.Context) (v any, ok bool, err error) {
+	select {
+	case v := <-s.items:
+		return v, true, nil
+	case <-s.done:
+		if errors.Is(context.Cause(s.ctx), ErrStreamClosed) {
+			return nil, false, nil
+		}
+		return nil, false, s.err
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
+	}
+}
+
+// Close stops the handler. It does not wait for it to return.
+func (s *itemStream) Close() error {
+	s.cancel(ErrStreamClosed)
+	return nil
+}
+
+// Iterator reads the results of a streaming call one at a time.
+//
+//	it, err := irpc.Iterate[Exam](ctx, registry, "Exam.Watch", req)
+//	if err != nil {
+//		return err
+//	}
+//	defer it.Close()
+//	for exam, err := range it.All(ctx) {
+//		...
+//	}
+//
+// Besides keys registered with RegisterStream, Iterate reads contract
+// methods returning an iter.Seq2[T, error] or an iter.Seq[T].
+type Iterator[T any] struct {
+	key  string
+	next func(ctx context.Context) (any, bool, error)
+	stop func()
+
+	once sync.Once
+	done bool
+}
+
+// Iterate calls key with req and returns an Iterator over its results. The
+// stream is closed when ctx is done; the Iterator must be closed otherwise.
+func Iterate[T any](ctx context.Context, r *Registry, key string, req any) (*Iterator[T], error) {
+	res, err := r.Call(ctx, key, req)
+	if err != nil {
+		return nil, err
+	}
+
+	it := &Iterator[T]{key: key}
+	switch v := res.(type) {
+	case *itemStream:
+		unbind := context.AfterFunc(ctx, it.Close)
+		it.next = v.next
+		it.stop = func() {
+			unbind()
+			v.Close()
+		}
+	case iter.Seq2[T, error]:
+		next, stop := iter.Pull2(v)
+		it.next = func(context.Context) (any, bool, error) {
+			item, err, ok := next()
+			if err != nil {
+				return nil, false, err
+			}
+			return item, ok, nil
+		}
+		it.stop = stop
+	case iter.Seq[T]:
+		next, stop := iter.Pull(v)
+		it.next = func(context.Context) (any, bool, error) {
+			item, ok := next()
+			return item, ok, nil
+		}
+		it.stop = stop
+	default:
+		return nil, Errorf(Internal, "%s returned %T, want a stream of %s", key, res, reflect.TypeFor[T]())
+	}
+	return it, nil
+}
+
+// Next returns the next result. It returns false without an error once
+// the stream has ended, and false with the error if it failed or ctx is
+// done.
+func (it *Iterator[T]) Next(ctx context.Context) (T, bool, error) {
+	var zero T
+	if it.done {
+		return zero, false, nil
+	}
+	v, ok, err := it.next(ctx)
+	if !ok {
+		it.done = true
+		it.Close()
+		return zero, false, err
+	}
+	item, ok := v.(T)
+	if !ok && v != nil {
+		it.done = true
+		it.Close()
+		return zero, false, Errorf(Internal, "%s sent %T, want %s", it.key, v, reflect.TypeFor[T]())
+	}
+	return item, true, nil
+}
+
+// All returns a range-over-func iterator over the remaining results. It
+// yields at most one error, last, and closes the Iterator when the loop
+// ends.
+func (it *Iterator[T]) All(ctx context.Context) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		defer it.Close()
+		for {
+			item, ok, err := it.Next(ctx)
+			if err != nil {
+				yield(item, err)
+				return
+			}
+			if !ok || !yield(item, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Close stops the stream. It may be called more than once.
+func (it *Iterator[T]) Close() {
+	it.once.Do(it.stop)
+}
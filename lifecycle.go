@@ -0,0 +1,44 @@
+package irpc
+
+import (
+	"context"
+	"errors"
+)
+
+// Shutdown stops the registry's background machinery, e.g. the async
+// executor, waiting for queued work to finish until ctx is done, and then
+// closes the streams still open. Calls waiting to be redelivered fail with
+// Unavailable right away, except durable ones, which stay in the deferred
+// store for Redispatch. Calls made after Shutdown that need that
+// machinery fail with Unavailable.
+func (r *Registry) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	r.closed = true
+	hooks := r.shutdownHooks
+	r.shutdownHooks = nil
+	r.mu.Unlock()
+
+	for _, t := range r.redeliveries.close() {
+		if t.ack != nil {
+			continue
+		}
+		r.settle(t, nil, &Error{Code: Unavailable, Key: t.key, Message: "registry shut down before redelivery"})
+	}
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i](ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := r.streams.closeAll(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// onShutdown registers fn to run on Shutdown, in reverse registration order.
+// The caller must hold r.mu.
+func (r *Registry) onShutdown(fn func(context.Context) error) {
+	r.shutdownHooks = append(r.shutdownHooks, fn)
+}
@@ -0,0 +1,211 @@
+package irpc
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"sync"
+	"time"
+)
+
+// RateLimit is a token bucket: Rate tokens per second are added up to
+// Burst, and every call takes one. A positive Rate needs a Burst of at
+// least 1, or no call would ever be let through.
+type RateLimit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+// Limits is the runtime configuration of the registry's limiters.
+type Limits struct {
+	// RateLimits maps key patterns to their rate limit.
+	RateLimits map[string]RateLimit `json:"rate_limits"`
+	// Bulkheads maps services to their maximum number of calls in flight.
+	Bulkheads map[string]int `json:"bulkheads"`
+	// MaxConcurrency and ConcurrencyQueue are set with SetMaxConcurrency.
+	MaxConcurrency   int `json:"max_concurrency"`
+	ConcurrencyQueue int `json:"concurrency_queue"`
+}
+
+type tokenBucket struct {
+	pattern string
+
+	mu     sync.Mutex
+	limit  RateLimit
+	tokens float64
+	last   time.Time
+}
+
+// take takes a token, returning how long until one is available if none
+// is.
+func (b *tokenBucket) take(now time.Time) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = math.Min(float64(b.limit.Burst), b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+	if b.limit.Rate <= 0 {
+		return 0, false
+	}
+	return time.Duration((1 - b.tokens) / b.limit.Rate * float64(time.Second)), false
+}
+
+type bulkhead struct {
+	mu       sync.Mutex
+	max      int
+	inFlight int
+}
+
+func (b *bulkhead) enter() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.inFlight >= b.max {
+		return false
+	}
+	b.inFlight++
+	return true
+}
+
+func (b *bulkhead) leave() {
+	b.mu.Lock()
+	b.inFlight--
+	b.mu.Unlock()
+}
+
+// SetRateLimit limits the calls to keys matching pattern, using path.Match
+// syntax. All matching keys share one bucket, so "Exam.*" limits the whole
+// service. Calls over the limit fail with ResourceExhausted and a
+// RetryInfo detail. The limit can be changed at any time; the bucket keeps
+// its tokens, up to the new burst. A zero RateLimit removes it.
+func (r *Registry) SetRateLimit(pattern string, limit RateLimit) error {
+	if err := checkRateLimit(pattern, limit); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.setRateLimit(pattern, limit)
+	return nil
+}
+
+func checkRateLimit(pattern string, limit RateLimit) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return err
+	}
+	if limit.Rate < 0 || limit.Burst < 0 {
+		return fmt.Errorf("irpc: negative rate limit for %s", pattern)
+	}
+	if limit.Rate > 0 && limit.Burst < 1 {
+		return fmt.Errorf("irpc: rate limit for %s has a burst below 1", pattern)
+	}
+	return nil
+}
+
+// setRateLimit is SetRateLimit with r.mu held.
+func (r *Registry) setRateLimit(pattern string, limit RateLimit) {
+	buckets := make([]*tokenBucket, 0, len(r.rateLimits)+1)
+	for _, b := range r.rateLimits {
+		if b.pattern != pattern {
+			buckets = append(buckets, b)
+			continue
+		}
+		if limit != (RateLimit{}) {
+			b.mu.Lock()
+			b.limit = limit
+			b.tokens = math.Min(b.tokens, float64(limit.Burst))
+			b.mu.Unlock()
+			buckets = append(buckets, b)
+		}
+		limit = RateLimit{}
+	}
+	if limit != (RateLimit{}) {
+		buckets = append(buckets, &tokenBucket{
+			pattern: pattern,
+			limit:   limit,
+			tokens:  float64(limit.Burst),
+			last:    time.Now(),
+		})
+	}
+	r.rateLimits = buckets
+}
+
+// SetBulkhead caps the number of calls to service running at once. Calls
+// over the cap fail immediately with ResourceExhausted, isolating the
+// rest of the application from a saturated service. A max <= 0 removes
+// the bulkhead.
+func (r *Registry) SetBulkhead(service string, max int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.setBulkhead(service, max)
+}
+
+// setBulkhead is SetBulkhead with r.mu held.
+func (r *Registry) setBulkhead(service string, max int) {
+	if max <= 0 {
+		delete(r.bulkheads, service)
+		return
+	}
+	if r.bulkheads == nil {
+		r.bulkheads = make(map[string]*bulkhead)
+	}
+	b := r.bulkheads[service]
+	if b == nil {
+		b = &bulkhead{}
+		r.bulkheads[service] = b
+	}
+	b.mu.Lock()
+	b.max = max
+	b.mu.Unlock()
+}
+
+// Limits returns the current limiter configuration.
+func (r *Registry) Limits() Limits {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := Limits{
+		RateLimits: make(map[string]RateLimit, len(r.rateLimits)),
+		Bulkheads:  make(map[string]int, len(r.bulkheads)),
+	}
+	for _, b := range r.rateLimits {
+		b.mu.Lock()
+		out.RateLimits[b.pattern] = b.limit
+		b.mu.Unlock()
+	}
+	for service, b := range r.bulkheads {
+		b.mu.Lock()
+		out.Bulkheads[service] = b.max
+		b.mu.Unlock()
+	}
+	if l := r.limiter; l != nil {
+		l.mu.Lock()
+		out.MaxConcurrency, out.ConcurrencyQueue = l.limit, l.queue
+		l.mu.Unlock()
+	}
+	return out
+}
+
+// takeTokens takes a token from every bucket matching key.
+func takeTokens(buckets []*tokenBucket, key string) error {
+	now := time.Now()
+	for _, b := range buckets {
+		if ok, _ := path.Match(b.pattern, key); !ok {
+			continue
+		}
+		if wait, ok := b.take(now); !ok {
+			return &Error{
+				Code:    ResourceExhausted,
+				Key:     key,
+				Message: key + ": rate limit exceeded",
+				Details: []any{RetryInfo{Delay: wait}},
+			}
+		}
+	}
+	return nil
+}
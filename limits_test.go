@@ -0,0 +1,35 @@
+package irpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRateLimitRejectsZeroBurst(t *testing.T) {
+	r := NewRegistry(Config{})
+	tests := []struct {
+		limit RateLimit
+		ok    bool
+	}{
+		{RateLimit{}, true},
+		{RateLimit{Rate: 100, Burst: 1}, true},
+		{RateLimit{Rate: 100}, false},
+		{RateLimit{Rate: -1, Burst: 1}, false},
+	}
+	for _, tt := range tests {
+		if err := r.SetRateLimit("Exam.*", tt.limit); (err == nil) != tt.ok {
+			t.Errorf("SetRateLimit(%+v) = %v", tt.limit, err)
+		}
+		err := r.ApplySettings(Settings{RateLimits: map[string]RateLimit{"Exam.*": tt.limit}})
+		if (err == nil) != tt.ok {
+			t.Errorf("ApplySettings(%+v) = %v", tt.limit, err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	r.AdminHandler().ServeHTTP(rec, httptest.NewRequest("POST", "/limits/rate?pattern=Exam.*&rate=100&burst=0", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("admin endpoint answered %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,88 @@
+package irpc
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// Responder produces the result of a call that is not dispatched to its
+// handler, e.g. while its service is in maintenance mode.
+type Responder func(ctx context.Context, key string, req any) (any, error)
+
+// UnavailableResponder returns a Responder failing every call with
+// Unavailable and message.
+func UnavailableResponder(message string) Responder {
+	if message == "" {
+		message = "temporarily unavailable"
+	}
+	return func(ctx context.Context, key string, req any) (any, error) {
+		return nil, &Error{Code: Unavailable, Key: key, Message: key + ": " + message}
+	}
+}
+
+// StaticResponder returns a Responder answering every call with res.
+func StaticResponder(res any) Responder {
+	return func(ctx context.Context, key string, req any) (any, error) {
+		return res, nil
+	}
+}
+
+// SetMaintenance puts every key of service into maintenance mode: calls are
+// answered by responder instead of the registered handlers. A nil
+// responder fails calls with Unavailable "temporarily unavailable".
+//
+// Nested services are covered too: while Billing is in maintenance, so is
+// Billing.Invoices, unless it has a maintenance responder of its own. The
+// service with the longest matching name applies.
+func (r *Registry) SetMaintenance(service string, responder Responder) {
+	if responder == nil {
+		responder = UnavailableResponder("")
+	}
+
+	r.mu.Lock()
+	if r.maintenance == nil {
+		r.maintenance = make(map[string]Responder)
+	}
+	r.maintenance[service] = responder
+	r.mu.Unlock()
+}
+
+// ClearMaintenance takes service out of maintenance mode.
+func (r *Registry) ClearMaintenance(service string) {
+	r.mu.Lock()
+	delete(r.maintenance, service)
+	r.mu.Unlock()
+}
+
+// maintenanceFor returns the responder of the maintenance covering service:
+// that of service itself, or else of the closest service it is nested in.
+// r.mu must be held.
+func (r *Registry) maintenanceFor(service string) (Responder, bool) {
+	if len(r.maintenance) == 0 {
+		return nil, false
+	}
+	for {
+		if responder, ok := r.maintenance[service]; ok {
+			return responder, true
+		}
+		i := strings.LastIndex(service, ".")
+		if i < 0 {
+			return nil, false
+		}
+		service = service[:i]
+	}
+}
+
+// MaintenanceServices returns the services currently in maintenance mode.
+func (r *Registry) MaintenanceServices() []string {
+	r.mu.RLock()
+	services := make([]string, 0, len(r.maintenance))
+	for s := range r.maintenance {
+		services = append(services, s)
+	}
+	r.mu.RUnlock()
+
+	sort.Strings(services)
+	return services
+}
@@ -0,0 +1,44 @@
+package irpc
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMaintenanceNestedServices(t *testing.T) {
+	r := NewRegistry(Config{})
+	for _, key := range []string{"Billing.Charge", "Billing.Invoices.Get", "Billing.Invoices.Archive.List", "BillingReports.Get"} {
+		r.Register(key, func(ctx context.Context, req any) (any, error) { return "handler", nil })
+	}
+	r.SetMaintenance("Billing", StaticResponder("billing"))
+	r.SetMaintenance("Billing.Invoices", StaticResponder("invoices"))
+
+	tests := []struct {
+		key  string
+		want any
+	}{
+		{"Billing.Charge", "billing"},
+		{"Billing.Invoices.Get", "invoices"},
+		{"Billing.Invoices.Archive.List", "invoices"},
+		{"BillingReports.Get", "handler"},
+	}
+	for _, tt := range tests {
+		res, err := r.Call(context.Background(), tt.key, nil)
+		if err != nil || res != tt.want {
+			t.Errorf("Call(%s) = %v, %v, want %v", tt.key, res, err, tt.want)
+		}
+	}
+
+	if st := r.CheckHealth(context.Background()).Services["Billing.Invoices.Archive"]; st.State != Degraded {
+		t.Errorf("Billing.Invoices.Archive state = %s, want degraded", st.State)
+	}
+
+	r.ClearMaintenance("Billing.Invoices")
+	if res, _ := r.Call(context.Background(), "Billing.Invoices.Get", nil); res != "billing" {
+		t.Errorf("after clearing Billing.Invoices: Call() = %v, want billing", res)
+	}
+	r.ClearMaintenance("Billing")
+	if res, _ := r.Call(context.Background(), "Billing.Invoices.Get", nil); res != "handler" {
+		t.Errorf("after clearing Billing: Call() = %v, want handler", res)
+	}
+}
@@ -0,0 +1,208 @@
+package irpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// ManifestFormat is the format version of manifests written by Manifest.
+const ManifestFormat = 1
+
+// Manifest is a machine-readable description of a registry's services,
+// for contract governance across teams.
+type Manifest struct {
+	Format   int               `json:"format"`
+	Services []ServiceManifest `json:"services"`
+}
+
+// ServiceManifest describes one service of a Manifest.
+type ServiceManifest struct {
+	Name    string           `json:"name"`
+	Methods []MethodManifest `json:"methods"`
+}
+
+// MethodManifest describes one method of a ServiceManifest.
+type MethodManifest struct {
+	Name         string     `json:"name"`
+	Key          string     `json:"key"`
+	Contract     string     `json:"contract,omitempty"`
+	NoContext    bool       `json:"no_context,omitempty"`
+	RequestType  string     `json:"request_type,omitempty"`
+	ResponseType string     `json:"response_type,omitempty"`
+	Request      JSONSchema `json:"request,omitempty"`
+	Response     JSONSchema `json:"response,omitempty"`
+	Versions     []string   `json:"versions,omitempty"`
+	Tags         []Tag      `json:"tags,omitempty"`
+	Impls        []string   `json:"impls"`
+}
+
+// Manifest describes every registered key, grouped by service.
+func (r *Registry) Manifest() Manifest {
+	m := Manifest{Format: ManifestFormat}
+	for _, s := range r.Schemas() {
+		service, method := SplitKey(s.Key)
+		if n := len(m.Services); n == 0 || m.Services[n-1].Name != service {
+			m.Services = append(m.Services, ServiceManifest{Name: service})
+		}
+		svc := &m.Services[len(m.Services)-1]
+		svc.Methods = append(svc.Methods, MethodManifest{
+			Name:         method,
+			Key:          s.Key,
+			Contract:     s.Contract,
+			NoContext:    s.NoContext,
+			RequestType:  s.RequestType,
+			ResponseType: s.ResponseType,
+			Request:      normalizeSchema(s.Request),
+			Response:     normalizeSchema(s.Response),
+			Versions:     s.Versions,
+			Tags:         r.Tags(s.Key),
+			Impls:        r.Impls(s.Key),
+		})
+	}
+	return m
+}
+
+// WriteManifest writes the manifest of the registry as indented JSON.
+func (r *Registry) WriteManifest(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r.Manifest())
+}
+
+// ReadManifest reads a manifest written by WriteManifest.
+func ReadManifest(rd io.Reader) (Manifest, error) {
+	var m Manifest
+	if err := json.NewDecoder(rd).Decode(&m); err != nil {
+		return Manifest{}, err
+	}
+	if m.Format != ManifestFormat {
+		return Manifest{}, fmt.Errorf("irpc: unsupported manifest format %d", m.Format)
+	}
+	return m, nil
+}
+
+// ManifestError lists the ways a registry breaks a manifest.
+type ManifestError struct {
+	Problems []string
+}
+
+func (e *ManifestError) Error() string {
+	return "irpc: registry does not satisfy manifest:\n\t" + strings.Join(e.Problems, "\n\t")
+}
+
+// ValidateManifest checks the registry against a previously exported
+// manifest, returning a *ManifestError if a method was removed, lost a tag
+// or version, or changed its types incompatibly: a request that requires a
+// new field, or a response missing a field or changing its type. Methods
+// added since the manifest was written are allowed.
+func (r *Registry) ValidateManifest(m Manifest) error {
+	current := make(map[string]MethodManifest)
+	for _, s := range r.Manifest().Services {
+		for _, mm := range s.Methods {
+			current[mm.Key] = mm
+		}
+	}
+
+	var problems []string
+	for _, s := range m.Services {
+		for _, want := range s.Methods {
+			got, ok := current[want.Key]
+			if !ok {
+				problems = append(problems, want.Key+": method removed")
+				continue
+			}
+			for _, tag := range want.Tags {
+				if !slices.Contains(got.Tags, tag) {
+					problems = append(problems, fmt.Sprintf("%s: tag %s removed", want.Key, tag))
+				}
+			}
+			for _, v := range want.Versions {
+				if !slices.Contains(got.Versions, v) {
+					problems = append(problems, fmt.Sprintf("%s: version %s removed", want.Key, v))
+				}
+			}
+			problems = append(problems, compareSchemas(want.Key+" request", want.Request, got.Request, true)...)
+			problems = append(problems, compareSchemas(want.Key+" response", want.Response, got.Response, false)...)
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ManifestError{Problems: problems}
+	}
+	return nil
+}
+
+// normalizeSchema converts s to the generic JSON representation, so that
+// schemas built in memory compare equal to schemas read from a manifest.
+func normalizeSchema(s JSONSchema) JSONSchema {
+	if s == nil {
+		return nil
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return s
+	}
+	var out JSONSchema
+	if err := json.Unmarshal(data, &out); err != nil {
+		return s
+	}
+	return out
+}
+
+// compareSchemas reports the incompatible changes from old to cur. For
+// requests, newly required properties break old callers; for responses,
+// removed properties do.
+func compareSchemas(path string, old, cur JSONSchema, request bool) []string {
+	if old == nil {
+		return nil
+	}
+	if cur == nil {
+		return []string{path + ": type removed"}
+	}
+	if old["type"] != cur["type"] {
+		return []string{fmt.Sprintf("%s: type changed from %v to %v", path, old["type"], cur["type"])}
+	}
+
+	var problems []string
+	oldProps, _ := old["properties"].(map[string]any)
+	curProps, _ := cur["properties"].(map[string]any)
+	for name, o := range oldProps {
+		c, ok := curProps[name]
+		if !ok {
+			if !request {
+				problems = append(problems, fmt.Sprintf("%s: field %s removed", path, name))
+			}
+			continue
+		}
+		oldField, _ := o.(map[string]any)
+		curField, _ := c.(map[string]any)
+		problems = append(problems, compareSchemas(path+"."+name, oldField, curField, request)...)
+	}
+	if request {
+		oldRequired := stringSet(old["required"])
+		for name := range stringSet(cur["required"]) {
+			if !oldRequired[name] {
+				problems = append(problems, fmt.Sprintf("%s: field %s is now required", path, name))
+			}
+		}
+	}
+	if items, ok := old["items"].(map[string]any); ok {
+		curItems, _ := cur["items"].(map[string]any)
+		problems = append(problems, compareSchemas(path+"[]", items, curItems, request)...)
+	}
+	return problems
+}
+
+func stringSet(v any) map[string]bool {
+	set := make(map[string]bool)
+	list, _ := v.([]any)
+	for _, s := range list {
+		if s, ok := s.(string); ok {
+			set[s] = true
+		}
+	}
+	return set
+}
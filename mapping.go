@@ -0,0 +1,78 @@
+package irpc
+
+import "net/http"
+
+// CodeMapping translates irpc codes into the status codes of external
+// transports. Missing entries fall back to the defaults.
+type CodeMapping struct {
+	HTTP map[Code]int
+	GRPC map[Code]uint32
+}
+
+// DefaultHTTPStatus is the default mapping from codes to HTTP status
+// codes, following the gRPC HTTP gateway conventions.
+var DefaultHTTPStatus = map[Code]int{
+	OK:                 http.StatusOK,
+	Canceled:           499,
+	Unknown:            http.StatusInternalServerError,
+	InvalidArgument:    http.StatusBadRequest,
+	DeadlineExceeded:   http.StatusGatewayTimeout,
+	NotFound:           http.StatusNotFound,
+	AlreadyExists:      http.StatusConflict,
+	PermissionDenied:   http.StatusForbidden,
+	ResourceExhausted:  http.StatusTooManyRequests,
+	FailedPrecondition: http.StatusBadRequest,
+	Aborted:            http.StatusConflict,
+	OutOfRange:         http.StatusBadRequest,
+	Unimplemented:      http.StatusNotImplemented,
+	Internal:           http.StatusInternalServerError,
+	Unavailable:        http.StatusServiceUnavailable,
+	DataLoss:           http.StatusInternalServerError,
+	Unauthenticated:    http.StatusUnauthorized,
+}
+
+// SetCodeMapping overrides the status mapping of a service, or of every
+// service if service is empty. Per-service entries win over registry-wide
+// ones, which win over the defaults.
+func (r *Registry) SetCodeMapping(service string, m CodeMapping) {
+	r.mu.Lock()
+	if r.codeMappings == nil {
+		r.codeMappings = make(map[string]CodeMapping)
+	}
+	r.codeMappings[service] = m
+	r.mu.Unlock()
+}
+
+// HTTPStatus returns the HTTP status code to report err from key with.
+func (r *Registry) HTTPStatus(key string, err error) int {
+	code := CodeOf(err)
+	service, _ := SplitKey(key)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, s := range [...]string{service, ""} {
+		if status, ok := r.codeMappings[s].HTTP[code]; ok {
+			return status
+		}
+	}
+	if status, ok := DefaultHTTPStatus[code]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// GRPCCode returns the gRPC status code to report err from key with. By
+// default it is the numeric value of the irpc code.
+func (r *Registry) GRPCCode(key string, err error) uint32 {
+	code := CodeOf(err)
+	service, _ := SplitKey(key)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, s := range [...]string{service, ""} {
+		if c, ok := r.codeMappings[s].GRPC[code]; ok {
+			return c
+		}
+	}
+	return uint32(code)
+}
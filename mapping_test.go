@@ -0,0 +1,107 @@
+package irpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCodeMappingDefaults(t *testing.T) {
+	tests := []struct {
+		code Code
+		http int
+		grpc uint32
+	}{
+		{OK, http.StatusOK, 0},
+		{Canceled, 499, 1},
+		{Unknown, http.StatusInternalServerError, 2},
+		{InvalidArgument, http.StatusBadRequest, 3},
+		{DeadlineExceeded, http.StatusGatewayTimeout, 4},
+		{NotFound, http.StatusNotFound, 5},
+		{AlreadyExists, http.StatusConflict, 6},
+		{PermissionDenied, http.StatusForbidden, 7},
+		{ResourceExhausted, http.StatusTooManyRequests, 8},
+		{FailedPrecondition, http.StatusBadRequest, 9},
+		{Aborted, http.StatusConflict, 10},
+		{OutOfRange, http.StatusBadRequest, 11},
+		{Unimplemented, http.StatusNotImplemented, 12},
+		{Internal, http.StatusInternalServerError, 13},
+		{Unavailable, http.StatusServiceUnavailable, 14},
+		{DataLoss, http.StatusInternalServerError, 15},
+		{Unauthenticated, http.StatusUnauthorized, 16},
+	}
+	if len(tests) != len(codeNames) {
+		t.Fatalf("table covers %d codes, want all %d", len(tests), len(codeNames))
+	}
+
+	r := NewRegistry(Config{})
+	for _, tt := range tests {
+		t.Run(tt.code.String(), func(t *testing.T) {
+			var err error
+			if tt.code != OK {
+				err = &Error{Code: tt.code, Key: "Billing.Charge"}
+			}
+			if got := r.HTTPStatus("Billing.Charge", err); got != tt.http {
+				t.Errorf("HTTPStatus = %d, want %d", got, tt.http)
+			}
+			if got := r.GRPCCode("Billing.Charge", err); got != tt.grpc {
+				t.Errorf("GRPCCode = %d, want %d", got, tt.grpc)
+			}
+		})
+	}
+
+	if got := r.HTTPStatus("Billing.Charge", errors.New("plain")); got != http.StatusInternalServerError {
+		t.Errorf("HTTPStatus of a plain error = %d, want 500", got)
+	}
+}
+
+func TestCodeMappingOverrides(t *testing.T) {
+	r := NewRegistry(Config{})
+	r.SetCodeMapping("", CodeMapping{HTTP: map[Code]int{NotFound: http.StatusGone, Aborted: http.StatusBadRequest}})
+	r.SetCodeMapping("Billing", CodeMapping{HTTP: map[Code]int{NotFound: http.StatusUnprocessableEntity}, GRPC: map[Code]uint32{NotFound: 9}})
+
+	notFound := &Error{Code: NotFound}
+	if got := r.HTTPStatus("Billing.Charge", notFound); got != http.StatusUnprocessableEntity {
+		t.Errorf("service mapping: HTTPStatus = %d, want 422", got)
+	}
+	if got := r.HTTPStatus("Search.Query", notFound); got != http.StatusGone {
+		t.Errorf("registry mapping: HTTPStatus = %d, want 410", got)
+	}
+	if got := r.HTTPStatus("Billing.Charge", &Error{Code: Aborted}); got != http.StatusBadRequest {
+		t.Errorf("registry mapping under a service one: HTTPStatus = %d, want 400", got)
+	}
+	if got := r.GRPCCode("Billing.Charge", notFound); got != 9 {
+		t.Errorf("GRPCCode = %d, want 9", got)
+	}
+	if got := r.GRPCCode("Search.Query", notFound); got != uint32(NotFound) {
+		t.Errorf("GRPCCode = %d, want %d", got, NotFound)
+	}
+}
+
+func TestPlaygroundStatus(t *testing.T) {
+	r := NewRegistry(Config{})
+	r.Register("Billing.Charge", func(ctx context.Context, req any) (any, error) {
+		return nil, &Error{Code: PermissionDenied, Message: "no"}
+	})
+	r.Register("Billing.Refund", func(ctx context.Context, req any) (any, error) {
+		return "ok", nil
+	})
+	r.SetCodeMapping("Billing", CodeMapping{HTTP: map[Code]int{PermissionDenied: http.StatusNotFound}})
+	h := r.PlaygroundHandler()
+
+	for key, want := range map[string]int{
+		"Billing.Charge": http.StatusNotFound,
+		"Billing.Refund": http.StatusOK,
+		"Search.Query":   http.StatusNotFound,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/call?key="+key, bytes.NewBufferString(`{"request":null}`))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Errorf("%s: status %d, want %d", key, rec.Code, want)
+		}
+	}
+}
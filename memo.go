@@ -0,0 +1,120 @@
+package irpc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Memo memoizes the results of deterministic methods, e.g. pricing tables
+// or config parsing called constantly across modules. Unlike a TTL cache,
+// entries live until they are explicitly invalidated. Errors are never
+// memoized, and concurrent calls for the same entry share one execution.
+//
+// Memoized responses are shared between callers, which must not modify
+// them.
+type Memo struct {
+	extract func(req any) string
+
+	mu      sync.Mutex
+	entries map[string]map[string]*batch
+}
+
+// NewMemo returns a Memo identifying requests by the string extract
+// returns for them. A nil extract formats the request with %#v.
+func NewMemo(extract func(req any) string) *Memo {
+	if extract == nil {
+		extract = func(req any) string { return fmt.Sprintf("%#v", req) }
+	}
+	return &Memo{extract: extract, entries: make(map[string]map[string]*batch)}
+}
+
+// Middleware returns the middleware memoizing the keys it is attached to.
+//
+//	memo := irpc.NewMemo(func(req any) string { return req.(PriceReq).Sku })
+//	registry.UseFor("Pricing.Table", memo.Middleware())
+func (m *Memo) Middleware() Middleware {
+	return func(key string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, req any) (any, error) {
+			id := m.extract(req)
+
+			m.mu.Lock()
+			byReq := m.entries[key]
+			if byReq == nil {
+				byReq = make(map[string]*batch)
+				m.entries[key] = byReq
+			}
+			b, hit := byReq[id]
+			if !hit {
+				b = newBatch()
+				byReq[id] = b
+			}
+			m.mu.Unlock()
+
+			if hit {
+				return b.wait(ctx)
+			}
+
+			// A panic is passed on to the registry once the callers
+			// waiting on b have been given it as an error, and, like an
+			// error, is not memoized.
+			defer close(b.done)
+			defer func() {
+				if v := recover(); v != nil {
+					b.res, b.err = nil, panicError(key, v)
+					m.forget(key, id, b)
+					panic(v)
+				}
+			}()
+
+			b.res, b.err = next(ctx, req)
+			if b.err != nil {
+				m.forget(key, id, b)
+			}
+			return b.res, b.err
+		}
+	}
+}
+
+// forget drops b, the execution of key for id, unless it was replaced.
+func (m *Memo) forget(key, id string, b *batch) {
+	m.mu.Lock()
+	if m.entries[key][id] == b {
+		delete(m.entries[key], id)
+	}
+	m.mu.Unlock()
+}
+
+// Invalidate drops the memoized result of key for the request identified
+// by id, as returned by the extractor.
+func (m *Memo) Invalidate(key, id string) {
+	m.mu.Lock()
+	delete(m.entries[key], id)
+	m.mu.Unlock()
+}
+
+// InvalidateKey drops every memoized result of key.
+func (m *Memo) InvalidateKey(key string) {
+	m.mu.Lock()
+	delete(m.entries, key)
+	m.mu.Unlock()
+}
+
+// Reset drops every memoized result.
+func (m *Memo) Reset() {
+	m.mu.Lock()
+	m.entries = make(map[string]map[string]*batch)
+	m.mu.Unlock()
+}
+
+// Len returns the number of memoized results.
+func (m *Memo) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := 0
+	for _, byReq := range m.entries {
+		n += len(byReq)
+	}
+	return n
+}
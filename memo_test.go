@@ -0,0 +1,51 @@
+package irpc
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoLeaderPanic(t *testing.T) {
+	m := NewMemo(nil)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	calls := 0
+	h := m.Middleware()("Pricing.Table", func(context.Context, any) (any, error) {
+		calls++
+		if calls == 1 {
+			close(started)
+			<-release
+			panic("boom")
+		}
+		return "table", nil
+	})
+
+	leader := make(chan any)
+	go func() {
+		defer func() { leader <- recover() }()
+		h(context.Background(), "sku")
+	}()
+	<-started
+
+	waiter := make(chan error)
+	go func() {
+		_, err := h(context.Background(), "sku")
+		waiter <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	if v := <-leader; v != "boom" {
+		t.Fatalf("leader recovered %v, want the handler's panic", v)
+	}
+	if err := <-waiter; CodeOf(err) != Internal {
+		t.Fatalf("waiter got %v, want an Internal error", err)
+	}
+	if m.Len() != 0 {
+		t.Fatalf("panicked call was memoized")
+	}
+	if res, err := h(context.Background(), "sku"); err != nil || res != "table" {
+		t.Fatalf("got %v, %v after the panic", res, err)
+	}
+}
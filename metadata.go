@@ -0,0 +1,47 @@
+package irpc
+
+import "context"
+
+// Metadata carries string key/value pairs alongside a call. It travels in
+// the context, so a handler that passes its ctx to a nested Call forwards
+// the metadata automatically.
+type Metadata map[string]string
+
+// Copy returns a shallow copy of md.
+func (md Metadata) Copy() Metadata {
+	out := make(Metadata, len(md))
+	for k, v := range md {
+		out[k] = v
+	}
+	return out
+}
+
+type metadataKey struct{}
+
+// WithMetadata returns a copy of ctx carrying md merged over any metadata
+// already present.
+func WithMetadata(ctx context.Context, md Metadata) context.Context {
+	merged := MetadataFromContext(ctx).Copy()
+	for k, v := range md {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, metadataKey{}, merged)
+}
+
+// WithMetadataValue returns a copy of ctx with a single metadata entry set.
+func WithMetadataValue(ctx context.Context, key, value string) context.Context {
+	return WithMetadata(ctx, Metadata{key: value})
+}
+
+// MetadataFromContext returns the metadata carried by ctx. The returned
+// map must not be modified; use WithMetadata to derive a new context.
+func MetadataFromContext(ctx context.Context) Metadata {
+	md, _ := ctx.Value(metadataKey{}).(Metadata)
+	return md
+}
+
+// MetadataValue returns a single metadata entry carried by ctx.
+func MetadataValue(ctx context.Context, key string) (string, bool) {
+	v, ok := MetadataFromContext(ctx)[key]
+	return v, ok
+}
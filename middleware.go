@@ -0,0 +1,108 @@
+package irpc
+
+import (
+	"path"
+	"strings"
+)
+
+// Middleware wraps the handler registered for key. Middleware added with
+// Use runs on every Call, in the order it was added.
+type Middleware func(key string, next HandlerFunc) HandlerFunc
+
+type scopedMiddleware struct {
+	pattern string
+	tag     Tag
+	mw      Middleware
+}
+
+// Use appends middleware to the registry's call chain.
+func (r *Registry) Use(mw ...Middleware) {
+	r.mu.Lock()
+	for _, m := range mw {
+		r.middleware = append(r.middleware, scopedMiddleware{mw: m})
+	}
+	r.chainGen++
+	r.mu.Unlock()
+}
+
+// UseFor appends middleware that only applies to keys matching pattern,
+// using path.Match syntax, e.g. "Exam.*" or "Stats.Recompute". It keeps
+// its position relative to middleware added with Use.
+func (r *Registry) UseFor(pattern string, mw ...Middleware) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	for _, m := range mw {
+		r.middleware = append(r.middleware, scopedMiddleware{pattern: pattern, mw: m})
+	}
+	r.chainGen++
+	r.mu.Unlock()
+	return nil
+}
+
+// UseTagged appends middleware that only applies to keys carrying tag,
+// e.g. caching for ReadOnly methods or retries for Idempotent ones.
+func (r *Registry) UseTagged(tag Tag, mw ...Middleware) {
+	r.mu.Lock()
+	for _, m := range mw {
+		r.middleware = append(r.middleware, scopedMiddleware{tag: tag, mw: m})
+	}
+	r.chainGen++
+	r.mu.Unlock()
+}
+
+func (m scopedMiddleware) applies(key string, tags []Tag) bool {
+	if m.pattern != "" {
+		if ok, _ := path.Match(m.pattern, key); !ok {
+			return false
+		}
+	}
+	if m.tag != "" {
+		return hasTag(tags, m.tag)
+	}
+	return true
+}
+
+// chainedHandler is the handler of an impl wrapped in the middleware
+// chain as it was at generation gen.
+type chainedHandler struct {
+	gen uint64
+	h   HandlerFunc
+}
+
+// chained returns the handler of im wrapped in the middleware chain of d.
+// The chain is built once per generation rather than on every call;
+// anything the middleware reads when wrapping, such as the tags of a key
+// or its retry policy, bumps the generation when it changes.
+func (im *impl) chained(d *dispatch) HandlerFunc {
+	if c := im.chain.Load(); c != nil && c.gen == d.chainGen {
+		return c.h
+	}
+	h := chain(d.key, d.tags, im.handler, d.mws)
+	for i := len(d.configMws) - 1; i >= 0; i-- {
+		h = d.configMws[i](d.key, h)
+	}
+	im.chain.Store(&chainedHandler{gen: d.chainGen, h: h})
+	return h
+}
+
+func chain(key string, tags []Tag, h HandlerFunc, mws []scopedMiddleware) HandlerFunc {
+	for i := len(mws) - 1; i >= 0; i-- {
+		if mws[i].applies(key, tags) {
+			h = mws[i].mw(key, h)
+		}
+	}
+	return h
+}
+
+// SplitKey splits a key such as "Exam.FindExamById" into its service and
+// method parts. Keys without a dot have an empty service.
+func SplitKey(key string) (service, method string) {
+	i := strings.LastIndex(key, ".")
+	if i < 0 {
+		return "", key
+	}
+	return key[:i], key[i+1:]
+}
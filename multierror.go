@@ -0,0 +1,87 @@
+package irpc
+
+import (
+	"strconv"
+	"strings"
+)
+
+// CallError is the failure of one call of a batch or broadcast.
+type CallError struct {
+	// Index is the position of the call, or of the implementation for
+	// Broadcast.
+	Index int
+	Key   string
+	Impl  string
+	Err   error
+}
+
+// MultiError reports the calls of a batch or broadcast that failed. It
+// unwraps to the contained errors, so errors.Is and errors.As match any
+// of them.
+type MultiError struct {
+	// Total is the number of calls made, including the successful ones.
+	Total  int
+	Errors []CallError
+}
+
+func (m *MultiError) Error() string {
+	var b strings.Builder
+	b.WriteString("irpc: ")
+	b.WriteString(strconv.Itoa(len(m.Errors)))
+	b.WriteString(" of ")
+	b.WriteString(strconv.Itoa(m.Total))
+	b.WriteString(" calls failed")
+	for i, e := range m.Errors {
+		if i == 0 {
+			b.WriteString(": ")
+		} else {
+			b.WriteString("; ")
+		}
+		b.WriteString(strings.TrimPrefix(e.Err.Error(), "irpc: "))
+		if e.Impl != "" {
+			b.WriteString(" (impl " + e.Impl + ")")
+		}
+	}
+	return b.String()
+}
+
+func (m *MultiError) Unwrap() []error {
+	errs := make([]error, len(m.Errors))
+	for i, e := range m.Errors {
+		errs[i] = e.Err
+	}
+	return errs
+}
+
+// At returns the error of the call at index i, or nil if it succeeded.
+func (m *MultiError) At(i int) error {
+	for _, e := range m.Errors {
+		if e.Index == i {
+			return e.Err
+		}
+	}
+	return nil
+}
+
+// For returns the errors of the calls to key.
+func (m *MultiError) For(key string) []error {
+	var errs []error
+	for _, e := range m.Errors {
+		if e.Key == key {
+			errs = append(errs, e.Err)
+		}
+	}
+	return errs
+}
+
+func (m *MultiError) add(i int, key, impl string, err error) {
+	m.Errors = append(m.Errors, CallError{Index: i, Key: key, Impl: impl, Err: err})
+}
+
+// orNil returns m as an error if any call failed.
+func (m *MultiError) orNil() error {
+	if len(m.Errors) == 0 {
+		return nil
+	}
+	return m
+}
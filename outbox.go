@@ -0,0 +1,280 @@
+package irpc
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// OutboxSchema is the table the Outbox expects, in portable SQL. Adjust
+// the column types to the database in use, e.g. BYTEA on PostgreSQL.
+// Tables created before next_attempt_at and dead_at were added need both
+// columns, next_attempt_at set to created_at.
+const OutboxSchema = `CREATE TABLE irpc_outbox (
+	id              VARCHAR(64)  PRIMARY KEY,
+	rpc_key         VARCHAR(255) NOT NULL,
+	payload         BLOB         NOT NULL,
+	metadata        TEXT         NOT NULL,
+	created_at      TIMESTAMP    NOT NULL,
+	attempts        INTEGER      NOT NULL DEFAULT 0,
+	last_error      TEXT,
+	next_attempt_at TIMESTAMP    NOT NULL,
+	dead_at         TIMESTAMP
+)`
+
+// DefaultOutboxRetryPolicy is the retry policy of an Outbox unless set
+// with WithOutboxRetry: ten attempts, backing off from a second to ten
+// minutes.
+var DefaultOutboxRetryPolicy = RetryPolicy{
+	MaxAttempts: 10,
+	Backoff:     time.Second,
+	MaxBackoff:  10 * time.Minute,
+}
+
+// Execer is satisfied by *sql.Tx, *sql.DB and *sql.Conn.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// OutboxOption configures an Outbox.
+type OutboxOption func(*Outbox)
+
+// WithOutboxTable sets the table name. The default is irpc_outbox.
+func WithOutboxTable(name string) OutboxOption {
+	return func(o *Outbox) {
+		o.table = name
+	}
+}
+
+// WithDollarPlaceholders makes the Outbox use $1, $2, ... placeholders as
+// required by PostgreSQL, instead of ?.
+func WithDollarPlaceholders() OutboxOption {
+	return func(o *Outbox) {
+		o.dollar = true
+	}
+}
+
+// WithPollInterval sets how often the relay looks for committed calls.
+// The default is one second.
+func WithPollInterval(d time.Duration) OutboxOption {
+	return func(o *Outbox) {
+		o.poll = d
+	}
+}
+
+// WithBatchSize sets how many calls the relay dispatches per round. The
+// default is 100.
+func WithBatchSize(n int) OutboxOption {
+	return func(o *Outbox) {
+		o.batch = n
+	}
+}
+
+// WithOutboxRetry sets how failed calls are retried. A call is attempted
+// again after the backoff of p, and is dead once it has failed
+// p.MaxAttempts times, zero meaning never. p.Codes is ignored: every
+// failure is retried. The default is DefaultOutboxRetryPolicy.
+func WithOutboxRetry(p RetryPolicy) OutboxOption {
+	return func(o *Outbox) {
+		o.retry = p
+	}
+}
+
+// Outbox stages irpc calls inside a database transaction so that they are
+// only dispatched once the transaction has committed: a rolled-back
+// business operation never sends its internal notifications.
+//
+// Calls are dispatched at least once, in creation order among the calls
+// that are due, by a single relay; run one relay per database. A failed
+// call is retried with backoff until it runs out of attempts, and then
+// stays in the table as dead, with its last error, until Requeue.
+type Outbox struct {
+	registry *Registry
+	db       *sql.DB
+	table    string
+	dollar   bool
+	poll     time.Duration
+	batch    int
+	retry    RetryPolicy
+
+	wake chan struct{}
+}
+
+// NewOutbox returns an Outbox storing calls in db and dispatching them on
+// registry.
+func NewOutbox(registry *Registry, db *sql.DB, opts ...OutboxOption) *Outbox {
+	o := &Outbox{
+		registry: registry,
+		db:       db,
+		table:    "irpc_outbox",
+		poll:     time.Second,
+		batch:    100,
+		retry:    DefaultOutboxRetryPolicy,
+		wake:     make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Enqueue stages a call to key with req in tx. It becomes visible to the
+// relay when tx commits; call Kick after committing to dispatch it without
+// waiting for the next poll.
+//
+//	tx, _ := db.BeginTx(ctx, nil)
+//	// ... business writes ...
+//	outbox.Enqueue(ctx, tx, "Mail.SendReceipt", ReceiptReq{OrderId: id})
+//	tx.Commit()
+//	outbox.Kick()
+func (o *Outbox) Enqueue(ctx context.Context, tx Execer, key string, req any) error {
+	payload, md, err := o.registry.encodeRequest(ctx, key, req)
+	if err != nil {
+		return err
+	}
+	mdJSON, err := json.Marshal(md)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	_, err = tx.ExecContext(ctx, o.query(
+		"INSERT INTO %s (id, rpc_key, payload, metadata, created_at, attempts, next_attempt_at) VALUES (?, ?, ?, ?, ?, 0, ?)"),
+		newID(), key, payload, string(mdJSON), now, now)
+	return err
+}
+
+// Requeue makes the dead call id due again, with its attempts reset.
+func (o *Outbox) Requeue(ctx context.Context, id string) error {
+	_, err := o.db.ExecContext(ctx, o.query(
+		"UPDATE %s SET attempts = 0, dead_at = NULL, next_attempt_at = ? WHERE id = ?"), time.Now().UTC(), id)
+	return err
+}
+
+// Kick asks a running relay to look for committed calls immediately.
+func (o *Outbox) Kick() {
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run relays committed calls until ctx is done.
+func (o *Outbox) Run(ctx context.Context) error {
+	ticker := time.NewTicker(o.poll)
+	defer ticker.Stop()
+
+	for {
+		for {
+			n, err := o.Flush(ctx)
+			if err != nil && ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil || n < o.batch {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		case <-o.wake:
+		}
+	}
+}
+
+// Flush dispatches one batch of committed calls that are due and returns
+// how many of them succeeded. Successful calls are deleted; failed ones
+// stay in the table with their attempt count and last error, and are
+// retried by a flush once their backoff has passed, or marked dead.
+func (o *Outbox) Flush(ctx context.Context) (int, error) {
+	rows, err := o.db.QueryContext(ctx, o.query(
+		"SELECT id, rpc_key, payload, metadata, attempts FROM %s WHERE dead_at IS NULL AND next_attempt_at <= ? ORDER BY created_at LIMIT ")+fmt.Sprint(o.batch),
+		time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	type staged struct {
+		id, key  string
+		payload  []byte
+		metadata string
+		attempts int
+	}
+	var calls []staged
+	for rows.Next() {
+		var c staged
+		if err := rows.Scan(&c.id, &c.key, &c.payload, &c.metadata, &c.attempts); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		calls = append(calls, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	done := 0
+	for _, c := range calls {
+		var md Metadata
+		_ = json.Unmarshal([]byte(c.metadata), &md)
+		callCtx := WithMetadata(ctx, md)
+
+		req, err := o.registry.decodeRequest(c.key, md, c.payload)
+		if err == nil {
+			_, err = o.registry.Call(callCtx, c.key, req)
+		}
+
+		if err == nil {
+			done++
+			_, err = o.db.ExecContext(ctx, o.query("DELETE FROM %s WHERE id = ?"), c.id)
+		} else {
+			err = o.failed(ctx, c.id, c.attempts+1, err)
+		}
+		if err != nil {
+			return done, err
+		}
+	}
+	return done, nil
+}
+
+// failed records the failure of attempt of the call id: it is retried
+// after the backoff, or marked dead if that was its last attempt.
+func (o *Outbox) failed(ctx context.Context, id string, attempt int, callErr error) error {
+	now := time.Now().UTC()
+	if o.retry.MaxAttempts > 0 && attempt >= o.retry.MaxAttempts {
+		_, err := o.db.ExecContext(ctx, o.query(
+			"UPDATE %s SET attempts = ?, last_error = ?, dead_at = ? WHERE id = ?"), attempt, callErr.Error(), now, id)
+		return err
+	}
+	_, err := o.db.ExecContext(ctx, o.query(
+		"UPDATE %s SET attempts = ?, last_error = ?, next_attempt_at = ? WHERE id = ?"),
+		attempt, callErr.Error(), now.Add(o.retry.delay(attempt-1)), id)
+	return err
+}
+
+// query fills in the table name and rewrites placeholders for the
+// configured dialect.
+func (o *Outbox) query(q string) string {
+	q = fmt.Sprintf(q, o.table)
+	if !o.dollar {
+		return q
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, c := range q {
+		if c == '?' {
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
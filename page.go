@@ -0,0 +1,198 @@
+package irpc
+
+import (
+	"context"
+	"iter"
+	"reflect"
+)
+
+// PageRequest is the standard request of a paginated list method. Embed it
+// in the method's request type:
+//
+//	type FindAllExamsReq struct {
+//		irpc.PageRequest
+//		Subject string
+//	}
+type PageRequest struct {
+	// PageSize is the maximum number of items to return. Zero lets the
+	// method choose.
+	PageSize int `json:"page_size,omitempty"`
+	// PageToken is the NextPageToken of the previous page, or empty for
+	// the first page.
+	PageToken string `json:"page_token,omitempty"`
+}
+
+func (p *PageRequest) pageRequest() *PageRequest { return p }
+
+// PageResponse is the standard response of a paginated list method,
+// returned as is or embedded in the method's response type.
+type PageResponse[T any] struct {
+	Items []T `json:"items"`
+	// NextPageToken is the token of the next page, or empty on the last
+	// page.
+	NextPageToken string `json:"next_page_token,omitempty"`
+}
+
+func (p PageResponse[T]) pageItems() []T        { return p.Items }
+func (p PageResponse[T]) nextPageToken() string { return p.NextPageToken }
+
+type pager interface{ pageRequest() *PageRequest }
+
+type pageResponse interface{ nextPageToken() string }
+
+type page[T any] interface {
+	pageResponse
+	pageItems() []T
+}
+
+var (
+	pagerType        = reflect.TypeOf((*pager)(nil)).Elem()
+	pageResponseType = reflect.TypeOf((*pageResponse)(nil)).Elem()
+)
+
+// isPaginated reports whether a contract method with these request and
+// response types follows the pagination convention.
+func isPaginated(req, res reflect.Type) bool {
+	if req == nil || res == nil {
+		return false
+	}
+	if req.Kind() != reflect.Pointer {
+		req = reflect.PointerTo(req)
+	}
+	return req.Implements(pagerType) && res.Implements(pageResponseType)
+}
+
+// Paginator calls a paginated list method page after page until it
+// returns an empty NextPageToken.
+//
+//	pages := irpc.NewPaginator[Exam](registry, "Exam.FindAllExams", FindAllExamsReq{Subject: "math"})
+//	for exam, err := range pages.All(ctx) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+type Paginator[T any] struct {
+	registry *Registry
+	key      string
+	req      any
+	token    string
+	done     bool
+}
+
+// NewPaginator returns a Paginator calling key with req, a PageRequest or
+// a struct embedding one, or a pointer to either. Its PageToken is the
+// first page's. Responses must be a PageResponse[T] or embed one.
+func NewPaginator[T any](r *Registry, key string, req any) *Paginator[T] {
+	p := &Paginator[T]{registry: r, key: key, req: req}
+	if pr, ok := pageRequestOf(req); ok {
+		p.token = pr.pageRequest().PageToken
+	}
+	return p
+}
+
+// More reports whether there are pages left to fetch.
+func (p *Paginator[T]) More() bool {
+	return !p.done
+}
+
+// NextPage fetches the next page. After the last page it returns nil and
+// More reports false.
+func (p *Paginator[T]) NextPage(ctx context.Context) ([]T, error) {
+	if p.done {
+		return nil, nil
+	}
+	req, ok := withPageToken(p.req, p.token)
+	if !ok {
+		return nil, Errorf(InvalidArgument, "Paginator %s: %T has no PageRequest", p.key, p.req)
+	}
+
+	res, err := p.registry.Call(ctx, p.key, req)
+	if err != nil {
+		return nil, err
+	}
+	pg, ok := asPage[T](res)
+	if !ok {
+		return nil, Errorf(Internal, "%s returned %T, want a PageResponse[%s]", p.key, res, reflect.TypeFor[T]())
+	}
+	p.token = pg.nextPageToken()
+	p.done = p.token == ""
+	return pg.pageItems(), nil
+}
+
+// All iterates over the items of every remaining page. It stops after
+// yielding the first error.
+func (p *Paginator[T]) All(ctx context.Context) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for p.More() {
+			items, err := p.NextPage(ctx)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Collect returns the items of every remaining page.
+func (p *Paginator[T]) Collect(ctx context.Context) ([]T, error) {
+	var out []T
+	for item, err := range p.All(ctx) {
+		if err != nil {
+			return out, err
+		}
+		out = append(out, item)
+	}
+	return out, nil
+}
+
+// withPageToken returns a copy of req with its page token set. req itself
+// is not modified.
+func withPageToken(req any, token string) (any, bool) {
+	pg, ok := pageRequestOf(req)
+	if !ok {
+		return nil, false
+	}
+	pg.pageRequest().PageToken = token
+	if reflect.TypeOf(req).Kind() == reflect.Pointer {
+		return pg, true
+	}
+	return reflect.ValueOf(pg).Elem().Interface(), true
+}
+
+// pageRequestOf returns a pointer to a copy of req, or of the value req
+// points to, if it carries a PageRequest.
+func pageRequestOf(req any) (pager, bool) {
+	v := reflect.ValueOf(req)
+	if !v.IsValid() {
+		return nil, false
+	}
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil, false
+		}
+		v = v.Elem()
+	}
+
+	c := reflect.New(v.Type())
+	c.Elem().Set(v)
+	pg, ok := c.Interface().(pager)
+	return pg, ok
+}
+
+func asPage[T any](res any) (page[T], bool) {
+	if pg, ok := res.(page[T]); ok {
+		return pg, true
+	}
+	if v := reflect.ValueOf(res); v.Kind() == reflect.Pointer && !v.IsNil() {
+		pg, ok := v.Elem().Interface().(page[T])
+		return pg, ok
+	}
+	return nil, false
+}
@@ -0,0 +1,175 @@
+package irpc
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// PanicMode is what happens when a handler panics.
+type PanicMode int
+
+const (
+	// PanicCrash lets the panic propagate, crashing the process unless the
+	// caller recovers. The panic is still counted and emitted first. It is
+	// the default.
+	PanicCrash PanicMode = iota
+	// PanicRecover turns the panic into an Internal error.
+	PanicRecover
+	// PanicRestart turns the panic into an Internal error and restarts the
+	// service: its keys are unavailable until the policy's Restart returns.
+	PanicRestart
+)
+
+// PanicPolicy is the panic behavior of a service.
+type PanicPolicy struct {
+	Mode PanicMode
+	// Restart rebuilds the service for PanicRestart, typically by
+	// registering a fresh implementation. It runs on its own goroutine; a
+	// panic of Restart is recovered and emitted as EventRestartPanic.
+	Restart func(service string)
+}
+
+// PanicInfo is the error detail of a recovered panic.
+type PanicInfo struct {
+	Value any    `json:"value"`
+	Stack string `json:"stack"`
+}
+
+// SetPanicPolicy sets the panic behavior of service, or the default of
+// every service if service is empty.
+//
+// Nested services are covered too: the policy of Billing applies to
+// Billing.Invoices, unless it has a policy of its own. The service with the
+// longest matching name applies.
+func (r *Registry) SetPanicPolicy(service string, p PanicPolicy) {
+	r.mu.Lock()
+	if r.panicPolicies == nil {
+		r.panicPolicies = make(map[string]PanicPolicy)
+	}
+	r.panicPolicies[service] = p
+	r.mu.Unlock()
+}
+
+// PanicCounts returns the number of handler panics per service.
+func (r *Registry) PanicCounts() map[string]uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make(map[string]uint64, len(r.panics))
+	for service, n := range r.panics {
+		out[service] = n.Load()
+	}
+	return out
+}
+
+// RestartingServices returns the services currently being restarted after
+// a panic.
+func (r *Registry) RestartingServices() []string {
+	r.mu.RLock()
+	services := make([]string, 0, len(r.restarting))
+	for service := range r.restarting {
+		services = append(services, service)
+	}
+	r.mu.RUnlock()
+
+	sort.Strings(services)
+	return services
+}
+
+// panicPolicy returns the policy of service, or else of the closest
+// service it is nested in, or else the default. The caller must hold r.mu.
+func (r *Registry) panicPolicy(service string) PanicPolicy {
+	for service != "" {
+		if p, ok := r.panicPolicies[service]; ok {
+			return p
+		}
+		i := strings.LastIndex(service, ".")
+		if i < 0 {
+			break
+		}
+		service = service[:i]
+	}
+	return r.panicPolicies[""]
+}
+
+// recoverPanic handles a panic of im, a handler of key, according to p. It
+// is deferred by invoke.
+func (r *Registry) recoverPanic(key string, im *impl, p PanicPolicy, err *error) {
+	v := recover()
+	if v == nil {
+		return
+	}
+
+	service, _ := SplitKey(key)
+	r.countPanic(service)
+	r.emit(context.Background(), panicEvent(key, v, p.Mode != PanicCrash))
+	if p.Mode == PanicCrash {
+		panic(v)
+	}
+
+	prov := r.provenance(key, im)
+	msg := fmt.Sprintf("panic: %v", v)
+	if s := prov.String(); s != "" {
+		msg += " (" + s + ")"
+	}
+	*err = &Error{
+		Code:    Internal,
+		Key:     key,
+		Message: msg,
+		Details: []any{PanicInfo{Value: v, Stack: string(debug.Stack())}, prov},
+	}
+
+	if p.Mode == PanicRestart {
+		r.restart(service, p)
+	}
+}
+
+func (r *Registry) countPanic(service string) {
+	r.mu.RLock()
+	n := r.panics[service]
+	r.mu.RUnlock()
+
+	if n == nil {
+		r.mu.Lock()
+		if r.panics == nil {
+			r.panics = make(map[string]*atomic.Uint64)
+		}
+		if n = r.panics[service]; n == nil {
+			n = new(atomic.Uint64)
+			r.panics[service] = n
+		}
+		r.mu.Unlock()
+	}
+	n.Add(1)
+}
+
+func (r *Registry) restart(service string, p PanicPolicy) {
+	r.mu.Lock()
+	if r.restarting[service] {
+		r.mu.Unlock()
+		return
+	}
+	if r.restarting == nil {
+		r.restarting = make(map[string]bool)
+	}
+	r.restarting[service] = true
+	r.mu.Unlock()
+
+	go func() {
+		defer func() {
+			if v := recover(); v != nil {
+				r.emit(context.Background(), restartPanicEvent(service, v))
+			}
+			r.mu.Lock()
+			delete(r.restarting, service)
+			r.mu.Unlock()
+		}()
+		if p.Restart != nil {
+			p.Restart(service)
+		}
+	}()
+}
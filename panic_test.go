@@ -0,0 +1,119 @@
+package irpc
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// eventRecorder is a TelemetryExporter recording the events it gets.
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []TelemetryEvent
+}
+
+func (e *eventRecorder) StartSpan(ctx context.Context, key string) (context.Context, func(error)) {
+	return ctx, func(error) {}
+}
+func (e *eventRecorder) RecordMetric(ctx context.Context, m Metric)                               {}
+func (e *eventRecorder) Log(ctx context.Context, level slog.Level, msg string, attrs []slog.Attr) {}
+
+func (e *eventRecorder) Event(ctx context.Context, ev TelemetryEvent) {
+	e.mu.Lock()
+	e.events = append(e.events, ev)
+	e.mu.Unlock()
+}
+
+func (e *eventRecorder) named(name string) []TelemetryEvent {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var out []TelemetryEvent
+	for _, ev := range e.events {
+		if ev.Name == name {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
+
+func TestPanicCrashCountedAndEmitted(t *testing.T) {
+	r := NewRegistry(Config{})
+	events := &eventRecorder{}
+	r.ExportTelemetry(events)
+	r.Register("Exam.Grade", func(ctx context.Context, req any) (any, error) {
+		panic("boom")
+	})
+
+	func() {
+		defer func() {
+			if v := recover(); v != "boom" {
+				t.Errorf("recovered %v, want the handler's panic", v)
+			}
+		}()
+		_, _ = r.Call(context.Background(), "Exam.Grade", nil)
+		t.Error("Call returned under PanicCrash")
+	}()
+
+	if n := r.PanicCounts()["Exam"]; n != 1 {
+		t.Errorf("PanicCounts = %d, want 1", n)
+	}
+	evs := events.named(EventPanic)
+	if len(evs) != 1 || evs[0].Key != "Exam.Grade" {
+		t.Fatalf("panic events = %+v, want one for Exam.Grade", evs)
+	}
+	for _, a := range evs[0].Attrs {
+		if a.Key == "recovered" && a.Value.Bool() {
+			t.Error("event of a crashing panic marked recovered")
+		}
+	}
+}
+
+func TestPanicRestartRecoversRestart(t *testing.T) {
+	r := NewRegistry(Config{})
+	events := &eventRecorder{}
+	r.ExportTelemetry(events)
+	r.Register("Exam.Grade", func(ctx context.Context, req any) (any, error) {
+		panic("boom")
+	})
+	r.SetPanicPolicy("Exam", PanicPolicy{Mode: PanicRestart, Restart: func(service string) {
+		panic("restart failed")
+	}})
+
+	if _, err := r.Call(context.Background(), "Exam.Grade", nil); CodeOf(err) != Internal {
+		t.Fatalf("Call() = %v, want Internal", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(events.named(EventRestartPanic)) == 0 || len(r.RestartingServices()) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("restart panic not emitted, restarting %v", r.RestartingServices())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if evs := events.named(EventRestartPanic); evs[0].Key != "Exam" {
+		t.Errorf("restart panic event key = %q, want Exam", evs[0].Key)
+	}
+}
+
+func TestPanicPolicyNestedServices(t *testing.T) {
+	r := NewRegistry(Config{})
+	r.SetPanicPolicy("Billing", PanicPolicy{Mode: PanicRecover})
+	r.SetPanicPolicy("Billing.Refunds", PanicPolicy{Mode: PanicCrash})
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for service, want := range map[string]PanicMode{
+		"Billing":              PanicRecover,
+		"Billing.Invoices":     PanicRecover,
+		"Billing.Invoices.Pdf": PanicRecover,
+		"Billing.Refunds":      PanicCrash,
+		"Billings":             PanicCrash,
+		"Exam":                 PanicCrash,
+	} {
+		if got := r.panicPolicy(service).Mode; got != want {
+			t.Errorf("panicPolicy(%q) = %v, want %v", service, got, want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package irpc
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+//go:embed playground/index.html
+var playgroundHTML []byte
+
+type playgroundCall struct {
+	Request  json.RawMessage `json:"request"`
+	Metadata Metadata        `json:"metadata"`
+}
+
+type playgroundResult struct {
+	Response any           `json:"response,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Code     string        `json:"code,omitempty"`
+	Trailers Metadata      `json:"trailers,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// PlaygroundHandler returns an http.Handler serving an interactive page
+// that renders forms from request schemas and invokes methods of the
+// running binary. A failed call is answered with the HTTP status of its
+// error, as mapped by HTTPStatus. It executes arbitrary calls, so it must only be mounted
+// in development or on an internal, authenticated mux. Mount it on a path
+// ending in a slash:
+//
+//	mux.Handle("/debug/irpc/playground/", http.StripPrefix("/debug/irpc/playground", registry.PlaygroundHandler()))
+func (r *Registry) PlaygroundHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/api/schemas"):
+			writeJSON(w, http.StatusOK, r.Schemas())
+
+		case strings.HasSuffix(req.URL.Path, "/api/call"):
+			if req.Method != http.MethodPost {
+				writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "POST required"})
+				return
+			}
+			var call playgroundCall
+			if err := json.NewDecoder(req.Body).Decode(&call); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			key := req.URL.Query().Get("key")
+			res, err := r.playgroundCall(req.Context(), key, call)
+			status := http.StatusOK
+			if err != nil {
+				status = r.HTTPStatus(key, err)
+			}
+			writeJSON(w, status, res)
+
+		default:
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write(playgroundHTML)
+		}
+	})
+}
+
+// playgroundCall makes call to key, and returns its result along with the
+// error it failed with, if any.
+func (r *Registry) playgroundCall(ctx context.Context, key string, call playgroundCall) (playgroundResult, error) {
+	ctx, trailer := WithTrailer(WithMetadata(ctx, call.Metadata))
+	if len(call.Request) == 0 {
+		call.Request = json.RawMessage("null")
+	}
+
+	start := time.Now()
+	res, err := r.CallJSON(ctx, key, call.Request)
+	out := playgroundResult{Response: res, Trailers: trailer.Metadata(), Duration: time.Since(start)}
+	if err != nil {
+		out.Error, out.Code = err.Error(), CodeOf(err).String()
+	}
+	return out, err
+}
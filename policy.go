@@ -0,0 +1,79 @@
+package irpc
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync/atomic"
+)
+
+// Candidate describes one implementation of a key to a Policy.
+type Candidate struct {
+	Name     string
+	InFlight int64
+}
+
+// Policy spreads the calls of a key across all of its implementations.
+// Select returns the index of the candidate that serves the call.
+type Policy interface {
+	Select(ctx context.Context, candidates []Candidate) int
+}
+
+// PolicyFunc adapts a function to a Policy.
+type PolicyFunc func(ctx context.Context, candidates []Candidate) int
+
+func (f PolicyFunc) Select(ctx context.Context, candidates []Candidate) int {
+	return f(ctx, candidates)
+}
+
+// RoundRobin returns a Policy cycling through the implementations in
+// registration order.
+func RoundRobin() Policy {
+	var next atomic.Uint64
+	return PolicyFunc(func(ctx context.Context, candidates []Candidate) int {
+		return int((next.Add(1) - 1) % uint64(len(candidates)))
+	})
+}
+
+// Random returns a Policy picking an implementation uniformly at random.
+func Random() Policy {
+	return PolicyFunc(func(ctx context.Context, candidates []Candidate) int {
+		return rand.IntN(len(candidates))
+	})
+}
+
+// LeastInFlight returns a Policy picking the implementation with the
+// fewest calls currently running, preferring the earliest registered one
+// on ties.
+func LeastInFlight() Policy {
+	return PolicyFunc(func(ctx context.Context, candidates []Candidate) int {
+		best := 0
+		for i, c := range candidates {
+			if c.InFlight < candidates[best].InFlight {
+				best = i
+			}
+		}
+		return best
+	})
+}
+
+// SetPolicy makes dispatch of key spread calls across all its registered
+// implementations according to p, e.g. to balance load between several
+// shard-bound instances of the same service.
+//
+//	registry.RegisterImpl("Exam.FindExamById", "shard-1", shard1)
+//	registry.RegisterImpl("Exam.FindExamById", "shard-2", shard2)
+//	registry.SetPolicy("Exam.FindExamById", irpc.LeastInFlight())
+func (r *Registry) SetPolicy(key string, p Policy) {
+	r.setRoute(key, func(ctx context.Context, impls []*impl) (context.Context, *impl) {
+		candidates := make([]Candidate, len(impls))
+		for i, im := range impls {
+			candidates[i] = Candidate{Name: im.name, InFlight: im.inFlight.Load()}
+		}
+
+		i := p.Select(ctx, candidates)
+		if i < 0 || i >= len(impls) {
+			return ctx, nil
+		}
+		return ctx, impls[i]
+	})
+}
@@ -0,0 +1,104 @@
+package irpc
+
+import (
+	"context"
+	"sync"
+)
+
+// Poolable is implemented by request and response types that can be
+// recycled with a Pool. Reset clears the value before it is reused.
+type Poolable interface {
+	Reset()
+}
+
+// Pool recycles values of T through a sync.Pool.
+//
+// irpc passes requests and responses by reference and never copies them,
+// so recycling is only safe with a strict ownership hand-off:
+//   - a caller gives up its request when it calls, and must not touch it
+//     after Call returns if the key uses ReleaseRequests;
+//   - a handler must not keep the request, or anything pointing into it,
+//     after it returns, e.g. in a goroutine;
+//   - a caller owns the response and Puts it back once done with it.
+//
+// Do not pool the requests of keys that are hedged, retried or degraded,
+// which may run the handler more than once or past the end of the call,
+// nor the responses of cached keys, unless they use IsolatePooled.
+type Pool[T Poolable] struct {
+	pool sync.Pool
+}
+
+// NewPool returns a Pool allocating new values with newFn.
+func NewPool[T Poolable](newFn func() T) *Pool[T] {
+	p := &Pool[T]{}
+	p.pool.New = func() any { return newFn() }
+	return p
+}
+
+// Get returns a value from the pool, allocating one if it is empty.
+func (p *Pool[T]) Get() T {
+	return p.pool.Get().(T)
+}
+
+// Put resets v and returns it to the pool.
+func (p *Pool[T]) Put(v T) {
+	v.Reset()
+	p.pool.Put(v)
+}
+
+// ReleaseRequests returns middleware putting requests of type T back in p
+// once the handler has returned, so that callers of high-frequency methods
+// can take their requests from p without giving them back:
+//
+//	reqs := irpc.NewPool(func() *ScoreReq { return new(ScoreReq) })
+//	registry.UseFor("Score.Record", irpc.ReleaseRequests(reqs))
+//
+//	req := reqs.Get()
+//	req.ExamId, req.Score = id, score
+//	registry.Call(ctx, "Score.Record", req)
+//
+// A request the handler returns as its response is not released. Add it
+// before any retry middleware so that it wraps every attempt.
+func ReleaseRequests[T Poolable](p *Pool[T]) Middleware {
+	return func(key string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, req any) (any, error) {
+			res, err := next(ctx, req)
+			if v, ok := req.(T); ok && req != res {
+				p.Put(v)
+			}
+			return res, err
+		}
+	}
+}
+
+// IsolatePooled returns middleware enforcing the ownership hand-off of
+// pooled values by copying them, so that a caller and a handler never
+// share one: a request of type T is copied with copyFn into a value from p
+// before it reaches the handler, and a response of type T into another
+// before it reaches the caller. The caller keeps its request and may Put
+// it back once Call returns; it owns the copy of the response. The
+// handler owns the copy of the request, and keeps its response, which may
+// thus be cached or shared by hedged and degraded calls.
+//
+//	registry.UseFor("Score.*", irpc.IsolatePooled(scores, func(dst, src *Score) { *dst = *src }))
+//
+// Add it before any cache, retry or ReleaseRequests middleware so that it
+// wraps them.
+func IsolatePooled[T Poolable](p *Pool[T], copyFn func(dst, src T)) Middleware {
+	return func(key string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, req any) (any, error) {
+			if v, ok := req.(T); ok {
+				c := p.Get()
+				copyFn(c, v)
+				req = c
+			}
+			res, err := next(ctx, req)
+			if v, ok := res.(T); ok {
+				c := p.Get()
+				copyFn(c, v)
+				res = c
+			}
+			return res, err
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package irpc
+
+import (
+	"context"
+	"testing"
+)
+
+type scoreReq struct {
+	ExamID string
+	Score  int
+}
+
+func (s *scoreReq) Reset() { *s = scoreReq{} }
+
+// countingPool returns a Pool of scoreReqs and a func reporting how many
+// were allocated.
+func countingPool() (*Pool[*scoreReq], func() int) {
+	n := 0
+	return NewPool(func() *scoreReq { n++; return new(scoreReq) }), func() int { return n }
+}
+
+func TestReleaseRequests(t *testing.T) {
+	reqs, _ := countingPool()
+	r := NewRegistry(Config{})
+	r.Register("Score.Record", func(ctx context.Context, req any) (any, error) {
+		return nil, nil
+	})
+	r.Register("Score.Echo", func(ctx context.Context, req any) (any, error) {
+		return req, nil
+	})
+	if err := r.UseFor("Score.*", ReleaseRequests(reqs)); err != nil {
+		t.Fatal(err)
+	}
+
+	req := reqs.Get()
+	req.ExamID, req.Score = "EX-1", 90
+	if _, err := r.Call(context.Background(), "Score.Record", req); err != nil {
+		t.Fatal(err)
+	}
+	if *req != (scoreReq{}) {
+		t.Errorf("released request = %+v, want it reset", *req)
+	}
+
+	req = reqs.Get()
+	req.ExamID, req.Score = "EX-2", 80
+	res, err := r.Call(context.Background(), "Score.Echo", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.(*scoreReq); got != req || *got != (scoreReq{"EX-2", 80}) {
+		t.Errorf("response = %+v, want the request, not released", *got)
+	}
+}
+
+func TestIsolatePooled(t *testing.T) {
+	scores, allocated := countingPool()
+	kept := &scoreReq{"EX-1", 90}
+	var handled *scoreReq
+	r := NewRegistry(Config{})
+	r.Register("Score.Record", func(ctx context.Context, req any) (any, error) {
+		handled = req.(*scoreReq)
+		return kept, nil
+	})
+	if err := r.UseFor("Score.*", IsolatePooled(scores, func(dst, src *scoreReq) { *dst = *src })); err != nil {
+		t.Fatal(err)
+	}
+
+	req := &scoreReq{"EX-2", 80}
+	res, err := r.Call(context.Background(), "Score.Record", req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if handled == req || *handled != *req {
+		t.Errorf("handler got %p %+v, want a copy of %p", handled, *handled, req)
+	}
+	got := res.(*scoreReq)
+	if got == kept || *got != *kept {
+		t.Errorf("caller got %p %+v, want a copy of %p", got, *got, kept)
+	}
+	scores.Put(got)
+	if *kept != (scoreReq{"EX-1", 90}) {
+		t.Errorf("handler's response = %+v after the caller put its copy back", *kept)
+	}
+	if n := allocated(); n != 2 {
+		t.Errorf("allocated %d values from the pool, want 2", n)
+	}
+}
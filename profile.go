@@ -0,0 +1,80 @@
+package irpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"runtime/pprof"
+	"time"
+)
+
+// ProfileLabel is the pprof label naming the key a sample was taken in
+// while a CPU profile started with StartCPUProfile is running.
+const ProfileLabel = "irpc_key"
+
+// StartCPUProfile starts a CPU profile written to w, labelling the samples
+// taken while serving keys matching pattern (path.Match syntax) with
+// ProfileLabel. The profile is process-wide, as every Go CPU profile is;
+// focus it on the key with
+//
+//	go tool pprof -tagfocus irpc_key=Exam.FindExamById profile.out
+//
+// Only one CPU profile can run at a time.
+func (r *Registry) StartCPUProfile(pattern string, w io.Writer) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.profiling != "" {
+		return errors.New("irpc: a CPU profile is already running")
+	}
+	if err := pprof.StartCPUProfile(w); err != nil {
+		return err
+	}
+	r.profiling = pattern
+	return nil
+}
+
+// StopCPUProfile stops the profile started with StartCPUProfile, once
+// its last samples are written.
+func (r *Registry) StopCPUProfile() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.profiling == "" {
+		return
+	}
+	pprof.StopCPUProfile()
+	r.profiling = ""
+}
+
+// ProfileCPU profiles keys matching pattern for d, or until ctx is done,
+// writing the profile to w.
+func (r *Registry) ProfileCPU(ctx context.Context, pattern string, d time.Duration, w io.Writer) error {
+	if err := r.StartCPUProfile(pattern, w); err != nil {
+		return err
+	}
+	defer r.StopCPUProfile()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	return nil
+}
+
+// profiled reports whether calls of key are labelled for the running CPU
+// profile. The caller must hold r.mu.
+func (r *Registry) profiled(key string) bool {
+	if r.profiling == "" {
+		return false
+	}
+	ok, _ := path.Match(r.profiling, key)
+	return ok
+}
@@ -0,0 +1,240 @@
+package irpc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ServiceInfo describes who owns a service or key and what it is, so that
+// the people debugging a call know who to ask. Every field is optional.
+type ServiceInfo struct {
+	// Owner is the team owning the service, e.g. "payments".
+	Owner string `json:"owner,omitempty" yaml:"owner,omitempty"`
+	// Contact is where to reach the owner, e.g. a channel or an address.
+	Contact     string `json:"contact,omitempty" yaml:"contact,omitempty"`
+	Version     string `json:"version,omitempty" yaml:"version,omitempty"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// over returns i with the fields set in o replaced by them.
+func (i ServiceInfo) over(o ServiceInfo) ServiceInfo {
+	if o.Owner != "" {
+		i.Owner = o.Owner
+	}
+	if o.Contact != "" {
+		i.Contact = o.Contact
+	}
+	if o.Version != "" {
+		i.Version = o.Version
+	}
+	if o.Description != "" {
+		i.Description = o.Description
+	}
+	return i
+}
+
+// owner describes who owns i for an error message, e.g. "owner payments,
+// contact #payments-oncall", or returns "" if i does not say.
+func (i ServiceInfo) owner() string {
+	var parts []string
+	if i.Owner != "" {
+		parts = append(parts, "owner "+i.Owner)
+	}
+	if i.Contact != "" {
+		parts = append(parts, "contact "+i.Contact)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// SetServiceInfo sets the info of every key of service, replacing any
+// earlier info. The info of a key set with SetKeyInfo overrides it field
+// by field.
+//
+//	registry.SetServiceInfo("Billing", irpc.ServiceInfo{Owner: "payments", Contact: "#payments-oncall"})
+func (r *Registry) SetServiceInfo(service string, info ServiceInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.setServiceInfo(service, info)
+}
+
+// setServiceInfo is SetServiceInfo with r.mu held.
+func (r *Registry) setServiceInfo(service string, info ServiceInfo) {
+	if r.serviceInfo == nil {
+		r.serviceInfo = make(map[string]ServiceInfo)
+	}
+	r.serviceInfo[service] = info
+	for key, e := range r.entries {
+		if s, _ := SplitKey(key); s == service {
+			r.updateOwner(key, e)
+		}
+	}
+}
+
+// SetKeyInfo sets the info of key, replacing any earlier info of the key
+// but not that of its service.
+func (r *Registry) SetKeyInfo(key string, info ServiceInfo) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e := r.ensureEntry(key)
+	e.info = info
+	r.updateOwner(key, e)
+}
+
+// updateOwner recomputes the owner of e, the entry of key. r.mu must be
+// held.
+func (r *Registry) updateOwner(key string, e *entry) {
+	e.owner = nil
+	if info := r.info(key); info != (ServiceInfo{}) {
+		e.owner = &info
+	}
+}
+
+// WithServiceInfo sets the info of the service being registered, as
+// SetServiceInfo does.
+//
+//	registry.RegisterContract("Billing", (*BillingContract)(nil), impl,
+//		irpc.WithServiceInfo(irpc.ServiceInfo{Owner: "payments", Version: "2.3.0"}))
+func WithServiceInfo(info ServiceInfo) RegisterOption {
+	return func(o *registerOptions) {
+		o.info = &info
+	}
+}
+
+// ServiceInfo returns the info of key: that of its service, overridden by
+// that of the key itself. key may also be a service name.
+func (r *Registry) ServiceInfo(key string) ServiceInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.info(key)
+}
+
+// info is ServiceInfo with r.mu held.
+func (r *Registry) info(key string) ServiceInfo {
+	if info, ok := r.serviceInfo[key]; ok {
+		return info
+	}
+	service, _ := SplitKey(key)
+	info := r.serviceInfo[service]
+	if e := r.entries[key]; e != nil {
+		info = info.over(e.info)
+	}
+	return info
+}
+
+// Registration records where and when an implementation was registered.
+type Registration struct {
+	Impl string `json:"impl"`
+	// Source is the file:line of the code that registered the
+	// implementation.
+	Source string    `json:"source,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// Registrations returns the registrations of the implementations of key,
+// the default one first.
+func (r *Registry) Registrations(key string) []Registration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	e := r.entries[key]
+	if e == nil {
+		return nil
+	}
+	out := make([]Registration, len(e.impls))
+	for i, im := range e.impls {
+		out[i] = im.registration()
+	}
+	return out
+}
+
+func (im *impl) registration() Registration {
+	return Registration{Impl: im.name, Source: im.source, At: im.registeredAt}
+}
+
+// Provenance is the error detail of handler not found and panic errors,
+// telling who owns the key and where it was registered. For a key that is
+// not registered, Registration is the first registration of its service,
+// if it has any.
+type Provenance struct {
+	Key          string       `json:"key"`
+	Info         ServiceInfo  `json:"info"`
+	Registration Registration `json:"registration"`
+}
+
+// String describes p for an error message, e.g. "owner payments,
+// registered at billing/register.go:31", or returns "" if p says nothing.
+func (p Provenance) String() string {
+	var parts []string
+	if s := p.Info.owner(); s != "" {
+		parts = append(parts, s)
+	}
+	if p.Registration.Source != "" {
+		parts = append(parts, "registered at "+p.Registration.Source)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// provenance returns the provenance of im, an implementation of key, or
+// of the default implementation if im is nil.
+func (r *Registry) provenance(key string, im *impl) Provenance {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p := Provenance{Key: key, Info: r.info(key)}
+	if im == nil {
+		if e := r.entries[key]; e != nil && len(e.impls) > 0 {
+			im = e.impls[0]
+		}
+	}
+	if im != nil {
+		p.Registration = im.registration()
+	}
+	return p
+}
+
+// missingProvenance returns the provenance of key, which has no
+// implementation. r.mu must be held.
+func (r *Registry) missingProvenance(key string) *Provenance {
+	service, _ := SplitKey(key)
+	return &Provenance{Key: key, Info: r.info(key), Registration: r.registered[service]}
+}
+
+// ServiceInfoFromContext returns the info of the key served with ctx, as
+// ServiceInfo does, e.g. for the logs of a handler or middleware.
+func ServiceInfoFromContext(ctx context.Context) ServiceInfo {
+	if c, ok := ctx.Value(chainKey{}).(*chainCtx); ok && c.owner != nil {
+		return *c.owner
+	}
+	return ServiceInfo{}
+}
+
+// OwnerOf returns the info of the key err is attributed to: the Owner of
+// a HandlerError, or the ServiceInfo or Provenance detail the registry
+// adds to the errors it produces for a key, such as rate limit or panic
+// errors.
+func OwnerOf(err error) (ServiceInfo, bool) {
+	var he *HandlerError
+	if errors.As(err, &he) && he.Owner != (ServiceInfo{}) {
+		return he.Owner, true
+	}
+	if info, ok := ErrorDetail[ServiceInfo](err); ok {
+		return info, true
+	}
+	if p, ok := ErrorDetail[Provenance](err); ok && p.Info != (ServiceInfo{}) {
+		return p.Info, true
+	}
+	return ServiceInfo{}, false
+}
+
+// withOwner adds owner as a detail to err if the registry produced it.
+func withOwner(err error, owner *ServiceInfo) error {
+	if e, ok := err.(*Error); ok && owner != nil {
+		return e.WithDetails(*owner)
+	}
+	return err
+}
@@ -0,0 +1,173 @@
+package irpc
+
+import (
+	"fmt"
+	"path"
+	"sync"
+	"time"
+)
+
+// QuotaUsage reports the state of one caller's quota.
+type QuotaUsage struct {
+	Caller   string        `json:"caller"`
+	Target   string        `json:"target"`
+	Limit    int           `json:"limit"`
+	Window   time.Duration `json:"window_ns"`
+	Used     int           `json:"used"`
+	Allowed  uint64        `json:"allowed"`
+	Rejected uint64        `json:"rejected"`
+}
+
+type quotaRule struct {
+	caller string
+	target string
+	limit  int
+	window time.Duration
+
+	mu     sync.Mutex
+	usages map[string]*quotaWindow
+}
+
+// quotaWindow is a sliding window counter: the count of the previous
+// fixed window is weighted by how much of it still overlaps the sliding
+// window.
+type quotaWindow struct {
+	start    time.Time
+	cur      int
+	prev     int
+	allowed  uint64
+	rejected uint64
+}
+
+func (w *quotaWindow) advance(now time.Time, window time.Duration) {
+	switch elapsed := now.Sub(w.start); {
+	case elapsed >= 2*window:
+		w.start, w.cur, w.prev = now, 0, 0
+	case elapsed >= window:
+		w.start, w.prev, w.cur = w.start.Add(window), w.cur, 0
+	}
+}
+
+func (w *quotaWindow) used(now time.Time, window time.Duration) float64 {
+	overlap := 1 - float64(now.Sub(w.start))/float64(window)
+	return float64(w.prev)*overlap + float64(w.cur)
+}
+
+// allow counts a call of caller against the quota, returning how long to
+// wait before retrying if the quota is exhausted.
+func (q *quotaRule) allow(caller string, now time.Time) (time.Duration, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	w := q.usages[caller]
+	if w == nil {
+		w = &quotaWindow{start: now}
+		q.usages[caller] = w
+	}
+	w.advance(now, q.window)
+
+	if w.used(now, q.window)+1 > float64(q.limit) {
+		w.rejected++
+		return w.start.Add(q.window).Sub(now), false
+	}
+	w.cur++
+	w.allowed++
+	return 0, true
+}
+
+// SetQuota limits the calls caller makes to keys matching target, using
+// path.Match syntax (e.g. "Exam.FindAllExams" for a key, "Exam.*" for a
+// service), to limit per sliding window. A caller of "*" gives every caller
+// its own quota. Calls over quota fail with ResourceExhausted, carrying
+// QuotaFailure and RetryInfo details.
+//
+// Callers are identified with CallerFromContext. A limit <= 0 removes the
+// quota.
+func (r *Registry) SetQuota(caller, target string, limit int, window time.Duration) error {
+	if _, err := path.Match(caller, ""); err != nil {
+		return err
+	}
+	if _, err := path.Match(target, ""); err != nil {
+		return err
+	}
+	if limit > 0 && window <= 0 {
+		return fmt.Errorf("irpc: quota window must be positive")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	quotas := make([]*quotaRule, 0, len(r.quotas)+1)
+	for _, q := range r.quotas {
+		if q.caller != caller || q.target != target {
+			quotas = append(quotas, q)
+		}
+	}
+	if limit > 0 {
+		quotas = append(quotas, &quotaRule{
+			caller: caller,
+			target: target,
+			limit:  limit,
+			window: window,
+			usages: make(map[string]*quotaWindow),
+		})
+	}
+	r.quotas = quotas
+	return nil
+}
+
+// QuotaUsages returns the usage of every quota by every caller seen.
+func (r *Registry) QuotaUsages() []QuotaUsage {
+	r.mu.RLock()
+	quotas := r.quotas
+	r.mu.RUnlock()
+
+	now := time.Now()
+	var out []QuotaUsage
+	for _, q := range quotas {
+		q.mu.Lock()
+		for caller, w := range q.usages {
+			w.advance(now, q.window)
+			out = append(out, QuotaUsage{
+				Caller:   caller,
+				Target:   q.target,
+				Limit:    q.limit,
+				Window:   q.window,
+				Used:     int(w.used(now, q.window)),
+				Allowed:  w.allowed,
+				Rejected: w.rejected,
+			})
+		}
+		q.mu.Unlock()
+	}
+	return out
+}
+
+// checkQuotas counts the call against every quota that applies to it.
+func checkQuotas(quotas []*quotaRule, key, caller string) error {
+	if len(quotas) == 0 || caller == "" {
+		return nil
+	}
+
+	now := time.Now()
+	for _, q := range quotas {
+		if ok, _ := path.Match(q.caller, caller); !ok {
+			continue
+		}
+		if ok, _ := path.Match(q.target, key); !ok {
+			continue
+		}
+		if wait, ok := q.allow(caller, now); !ok {
+			return &Error{
+				Code:    ResourceExhausted,
+				Key:     key,
+				Message: fmt.Sprintf("%s: quota of %s exceeded (%d calls per %s)", key, caller, q.limit, q.window),
+				Details: []any{
+					QuotaFailure{Subject: caller, Description: q.target},
+					RetryInfo{Delay: wait},
+				},
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package irpc
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Implementation names used by RouteReadWrite.
+const (
+	PrimaryImpl = "primary"
+	ReplicaImpl = "replica"
+)
+
+// RouteReadWrite routes the keys of service between a primary-bound and a
+// replica-bound implementation of the same contract: methods tagged
+// ReadOnly go to ReplicaImpl while lag reports a replication lag of at
+// most maxLag, every other call goes to PrimaryImpl. A nil lag never falls
+// back. Keys registered after this call are not routed.
+//
+//	registry.RegisterContractImpl("Exam", irpc.PrimaryImpl, (*ExamContract)(nil), primary)
+//	registry.RegisterContractImpl("Exam", irpc.ReplicaImpl, (*ExamContract)(nil), replica)
+//	registry.RouteReadWrite("Exam", 2*time.Second, replicaLag)
+func (r *Registry) RouteReadWrite(service string, maxLag time.Duration, lag func(ctx context.Context) time.Duration) {
+	prefix := service + "."
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, e := range r.entries {
+		if !strings.HasPrefix(key, prefix) || strings.Contains(key[len(prefix):], ".") {
+			continue
+		}
+
+		read := hasTag(e.tags, ReadOnly)
+		e.route = func(ctx context.Context, impls []*impl) (context.Context, *impl) {
+			if read && (lag == nil || lag(ctx) <= maxLag) {
+				if im := findImpl(impls, ReplicaImpl); im != nil {
+					return ctx, im
+				}
+			}
+			return ctx, findImpl(impls, PrimaryImpl)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package irpc
+
+import (
+	"context"
+	"path"
+	"sync"
+	"time"
+)
+
+// AsyncRetryPolicy controls how failed async calls are redelivered. Unlike
+// the retry middleware, which retries within a single call, a redelivery
+// puts the call back on the executor after the backoff, so the worker is
+// free in the meantime and the delay can span minutes.
+type AsyncRetryPolicy struct {
+	// MaxAttempts, Backoff, MaxBackoff and Codes are as in RetryPolicy.
+	RetryPolicy
+	// MaxAge is how long after it was first queued a call may still be
+	// redelivered. Zero means no limit.
+	MaxAge time.Duration
+}
+
+// DefaultAsyncRetryPolicy redelivers transient failures for up to an hour.
+var DefaultAsyncRetryPolicy = AsyncRetryPolicy{
+	RetryPolicy: RetryPolicy{
+		MaxAttempts: 10,
+		Backoff:     time.Second,
+		MaxBackoff:  5 * time.Minute,
+		Codes:       []Code{Unavailable, Aborted, ResourceExhausted, DeadlineExceeded},
+	},
+	MaxAge: time.Hour,
+}
+
+// WithAsyncRetry sets the redelivery policy of a single async call,
+// overriding SetAsyncRetryPolicy.
+func WithAsyncRetry(p AsyncRetryPolicy) AsyncOption {
+	return func(t *asyncTask) {
+		t.retry = &p
+	}
+}
+
+// SetAsyncRetryPolicy redelivers failed CallAsync, Notify and NotifyDurable
+// calls to keys matching pattern, in the syntax of path.Match, according to
+// p. The first matching policy applies. A call that exhausts its attempts
+// or MaxAge completes with its last error; for notifications, that moves
+// it to the dead-letter store.
+//
+//	registry.SetAsyncRetryPolicy("Mail.*", irpc.DefaultAsyncRetryPolicy)
+func (r *Registry) SetAsyncRetryPolicy(pattern string, p AsyncRetryPolicy) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.asyncRetries = append(r.asyncRetries, scopedAsyncRetry{pattern: pattern, policy: p})
+	r.mu.Unlock()
+	return nil
+}
+
+type scopedAsyncRetry struct {
+	pattern string
+	policy  AsyncRetryPolicy
+}
+
+func (r *Registry) asyncRetryPolicy(key string) *AsyncRetryPolicy {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, s := range r.asyncRetries {
+		if ok, _ := path.Match(s.pattern, key); ok {
+			p := s.policy
+			return &p
+		}
+	}
+	return nil
+}
+
+// redeliver schedules another attempt of t after it failed with err. It
+// returns false if t's policy does not allow one.
+func (r *Registry) redeliver(t *asyncTask, err error) bool {
+	p := t.retry
+	if p == nil {
+		p = r.asyncRetryPolicy(t.key)
+	}
+	attempt := len(t.attempts)
+	if p == nil || attempt+1 >= p.MaxAttempts || !p.retryable(err) || t.ctx.Err() != nil {
+		return false
+	}
+
+	delay := p.delay(attempt)
+	if info, ok := ErrorDetail[RetryInfo](err); ok && info.Delay > delay {
+		delay = info.Delay
+	}
+	if p.MaxAge > 0 && time.Since(t.created)+delay > p.MaxAge {
+		return false
+	}
+	if !t.deadline.IsZero() && time.Now().Add(delay).After(t.deadline) {
+		return false
+	}
+
+	t.attempts = append(t.attempts, newAttempt(err))
+	if t.extend != nil {
+		t.extend(delay)
+	}
+	t.state.Store(int32(asyncWaiting))
+	release := r.pending.add(t.key)
+	scheduled := r.redeliveries.schedule(t, delay, release, func() {
+		defer release()
+		if t.ctx.Err() != nil {
+			r.settle(t, nil, context.Cause(t.ctx))
+		} else if err := r.submit(t); err != nil {
+			r.settle(t, nil, err)
+		}
+	})
+	if !scheduled {
+		t.attempts = t.attempts[:attempt]
+		return false
+	}
+	r.asyncStats.track(t, asyncWaiting)
+	return true
+}
+
+// redeliverySet holds the timers of calls waiting to be redelivered.
+type redeliverySet struct {
+	mu      sync.Mutex
+	pending map[*asyncTask]redelivery
+	closed  bool
+}
+
+type redelivery struct {
+	timer   *time.Timer
+	release func()
+}
+
+// schedule runs fn after delay, unless the set is closed first. release
+// drops t from the registry's pending calls; it is called right away if
+// the set is already closed, or by close.
+func (s *redeliverySet) schedule(t *asyncTask, delay time.Duration, release, fn func()) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		release()
+		return false
+	}
+	if s.pending == nil {
+		s.pending = make(map[*asyncTask]redelivery)
+	}
+	timer := time.AfterFunc(delay, func() {
+		s.mu.Lock()
+		_, ok := s.pending[t]
+		delete(s.pending, t)
+		s.mu.Unlock()
+		if ok {
+			fn()
+		}
+	})
+	s.pending[t] = redelivery{timer: timer, release: release}
+	return true
+}
+
+// remove cancels the redelivery of t, reporting false if it was not
+// pending.
+func (s *redeliverySet) remove(t *asyncTask) bool {
+	s.mu.Lock()
+	d, ok := s.pending[t]
+	delete(s.pending, t)
+	s.mu.Unlock()
+
+	if ok {
+		d.timer.Stop()
+		d.release()
+	}
+	return ok
+}
+
+// close cancels the pending redeliveries and returns their tasks.
+func (s *redeliverySet) close() []*asyncTask {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	tasks := make([]*asyncTask, 0, len(s.pending))
+	for t, d := range s.pending {
+		d.timer.Stop()
+		d.release()
+		tasks = append(tasks, t)
+	}
+	s.pending = nil
+	return tasks
+}
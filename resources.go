@@ -0,0 +1,118 @@
+package irpc
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Resource is something created through the registry that holds
+// goroutines or work until it is released, reported by OpenResources.
+type Resource struct {
+	// Kind is "executor", "schedule", "watchdog", "async call", "call",
+	// "stream" or "subscription".
+	Kind string `json:"kind"`
+	// Key is the key the resource was created for, if any.
+	Key string `json:"key,omitempty"`
+	// ID identifies the resource among those of its kind and key, e.g. a
+	// schedule ID.
+	ID string `json:"id,omitempty"`
+	// Count is the number of identical resources, e.g. queued async calls
+	// of the key.
+	Count int `json:"count"`
+}
+
+// pendingSet counts the async calls of every key that were queued but
+// have not finished.
+type pendingSet struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// add counts a call of key and returns the func releasing it, which may be
+// called more than once.
+func (p *pendingSet) add(key string) func() {
+	p.mu.Lock()
+	if p.counts == nil {
+		p.counts = make(map[string]int)
+	}
+	p.counts[key]++
+	p.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			p.mu.Lock()
+			if p.counts[key]--; p.counts[key] == 0 {
+				delete(p.counts, key)
+			}
+			p.mu.Unlock()
+		})
+	}
+}
+
+// OpenResources returns the resources the registry currently holds: its
+// own executor until Shutdown, schedules, a running watchdog, async calls
+// that have not finished, calls being served, streams that were not
+// closed and active subscriptions. Tests can check it is
+// empty once they are done; see irpctest.VerifyNoLeaks.
+func (r *Registry) OpenResources() []Resource {
+	var out []Resource
+
+	r.mu.RLock()
+	if r.asyncOwned && !r.closed {
+		out = append(out, Resource{Kind: "executor", Count: 1})
+	}
+	if r.watchdog != nil {
+		out = append(out, Resource{Kind: "watchdog", Count: 1})
+	}
+	for key, e := range r.entries {
+		n := 0
+		for _, im := range e.impls {
+			n += int(im.inFlight.Load())
+		}
+		if n > 0 {
+			out = append(out, Resource{Kind: "call", Key: key, Count: n})
+		}
+	}
+	sch := r.sched
+	r.mu.RUnlock()
+
+	if sch != nil && !sch.stopped() {
+		sch.mu.Lock()
+		for id, s := range sch.schedules {
+			out = append(out, Resource{Kind: "schedule", Key: s.key, ID: id, Count: 1})
+		}
+		sch.mu.Unlock()
+	}
+
+	r.pending.mu.Lock()
+	for key, n := range r.pending.counts {
+		out = append(out, Resource{Kind: "async call", Key: key, Count: n})
+	}
+	r.pending.mu.Unlock()
+
+	for _, s := range r.Subscriptions() {
+		out = append(out, Resource{Kind: "subscription", Key: s.Key, ID: s.ID, Count: 1})
+	}
+	for key, n := range r.streams.counts() {
+		out = append(out, Resource{Kind: "stream", Key: key, Count: n})
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].String() < out[j].String()
+	})
+	return out
+}
+
+// String describes res, e.g. `2 async call(s) of Mail.Send`.
+func (res Resource) String() string {
+	s := strconv.Itoa(res.Count) + " " + res.Kind + "(s)"
+	if res.Key != "" {
+		s += " of " + res.Key
+	}
+	if res.ID != "" {
+		s += " (" + res.ID + ")"
+	}
+	return s
+}
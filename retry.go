@@ -0,0 +1,128 @@
+package irpc
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy controls how a failed call is retried.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	MaxAttempts int
+	// Backoff is the base delay before the first retry. It doubles with
+	// every attempt up to MaxBackoff; the actual delay is jittered.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Codes lists the retryable error codes.
+	Codes []Code
+}
+
+// DefaultRetryPolicy retries transient failures twice.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	Backoff:     50 * time.Millisecond,
+	MaxBackoff:  time.Second,
+	Codes:       []Code{Unavailable, Aborted, ResourceExhausted},
+}
+
+func (p RetryPolicy) retryable(err error) bool {
+	code := CodeOf(err)
+	for _, c := range p.Codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.Backoff << uint(attempt)
+	if d <= 0 || (p.MaxBackoff > 0 && d > p.MaxBackoff) {
+		d = p.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	// Equal jitter: half fixed, half random.
+	return d/2 + rand.N(d/2+1)
+}
+
+// Retry returns middleware retrying every call it wraps according to p.
+// Prefer EnableRetries, which only retries methods tagged Idempotent.
+func Retry(p RetryPolicy) Middleware {
+	return func(key string, next HandlerFunc) HandlerFunc {
+		return retryHandler(p, next)
+	}
+}
+
+func retryHandler(p RetryPolicy, next HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, req any) (any, error) {
+		var res any
+		var err error
+		for attempt := 0; ; attempt++ {
+			res, err = next(ctx, req)
+			if err == nil || attempt+1 >= p.MaxAttempts || !p.retryable(err) {
+				return res, err
+			}
+
+			delay := p.delay(attempt)
+			if info, ok := ErrorDetail[RetryInfo](err); ok && info.Delay > delay {
+				delay = info.Delay
+			}
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return res, err
+			}
+		}
+	}
+}
+
+// EnableRetries transparently retries calls to Idempotent methods that
+// fail with a retryable code, using p unless the key has its own policy
+// set with SetRetryPolicy. Methods tagged SideEffecting are never retried,
+// even if they are also tagged Idempotent.
+func (r *Registry) EnableRetries(p RetryPolicy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.retryPolicy = &p
+	r.chainGen++
+	if r.retriesEnabled {
+		return
+	}
+	r.retriesEnabled = true
+
+	r.middleware = append(r.middleware, scopedMiddleware{tag: Idempotent, mw: func(key string, next HandlerFunc) HandlerFunc {
+		r.mu.RLock()
+		policy, ok := r.retryPolicies[key]
+		if !ok && r.retryPolicy != nil {
+			policy, ok = *r.retryPolicy, true
+		}
+		sideEffecting := false
+		if e := r.entries[key]; e != nil {
+			sideEffecting = hasTag(e.tags, SideEffecting)
+		}
+		r.mu.RUnlock()
+
+		if !ok || sideEffecting || policy.MaxAttempts <= 1 {
+			return next
+		}
+		return retryHandler(policy, next)
+	}})
+}
+
+// SetRetryPolicy overrides the retry policy of an Idempotent key. A policy
+// with MaxAttempts <= 1 disables retries for it.
+func (r *Registry) SetRetryPolicy(key string, p RetryPolicy) {
+	r.mu.Lock()
+	if r.retryPolicies == nil {
+		r.retryPolicies = make(map[string]RetryPolicy)
+	}
+	r.retryPolicies[key] = p
+	r.chainGen++
+	r.mu.Unlock()
+}
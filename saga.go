@@ -0,0 +1,131 @@
+package irpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type sagaStep struct {
+	key           string
+	req           any
+	compensateKey string
+	compensateReq func(res any) any
+}
+
+// Saga runs a multi-module workflow as a sequence of calls, each paired
+// with a compensating call. When a step fails, the steps that already
+// completed are compensated in reverse order.
+//
+//	res, err := irpc.NewSaga(registry).
+//		Step("Billing.Charge", chargeReq, "Billing.Refund", func(res any) any {
+//			return RefundReq{ChargeId: res.(*ChargeRes).Id}
+//		}).
+//		Step("Exam.Enroll", enrollReq, "Exam.Unenroll", nil).
+//		Run(ctx)
+type Saga struct {
+	registry *Registry
+	steps    []sagaStep
+	retries  int
+	backoff  time.Duration
+}
+
+// NewSaga returns an empty saga dispatching on registry. Compensations are
+// retried 3 times with exponential backoff starting at 100ms by default.
+func NewSaga(registry *Registry) *Saga {
+	return &Saga{registry: registry, retries: 3, backoff: 100 * time.Millisecond}
+}
+
+// Step appends a call to key with req. If a later step fails,
+// compensateKey is called with compensate(res), where res is this step's
+// result; a nil compensate reuses req. An empty compensateKey marks a step
+// that needs no compensation.
+func (s *Saga) Step(key string, req any, compensateKey string, compensate func(res any) any) *Saga {
+	s.steps = append(s.steps, sagaStep{key: key, req: req, compensateKey: compensateKey, compensateReq: compensate})
+	return s
+}
+
+// WithCompensationRetries sets how many times a failed compensation is
+// retried and the initial delay between attempts, doubled every retry.
+func (s *Saga) WithCompensationRetries(retries int, backoff time.Duration) *Saga {
+	s.retries, s.backoff = retries, backoff
+	return s
+}
+
+// SagaError reports the step that failed a saga and any compensation that
+// could not be completed. It unwraps to the step's error.
+type SagaError struct {
+	Step          int
+	Key           string
+	Err           error
+	Compensations []error
+}
+
+func (e *SagaError) Error() string {
+	msg := fmt.Sprintf("irpc: saga step %d (%s) failed: %v", e.Step, e.Key, e.Err)
+	if len(e.Compensations) > 0 {
+		msg += fmt.Sprintf("; %d compensation(s) failed: %v", len(e.Compensations), errors.Join(e.Compensations...))
+	}
+	return msg
+}
+
+func (e *SagaError) Unwrap() error {
+	return e.Err
+}
+
+// Run executes the steps in order and returns their results. On failure
+// it compensates the completed steps and returns a *SagaError.
+// Compensations run even if ctx has been cancelled.
+func (s *Saga) Run(ctx context.Context) ([]any, error) {
+	results := make([]any, 0, len(s.steps))
+
+	for i, step := range s.steps {
+		res, err := s.registry.Call(ctx, step.key, step.req)
+		if err != nil {
+			return results, &SagaError{
+				Step:          i,
+				Key:           step.key,
+				Err:           err,
+				Compensations: s.compensate(context.WithoutCancel(ctx), results),
+			}
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+func (s *Saga) compensate(ctx context.Context, results []any) []error {
+	var errs []error
+	for i := len(results) - 1; i >= 0; i-- {
+		step := s.steps[i]
+		if step.compensateKey == "" {
+			continue
+		}
+
+		req := step.req
+		if step.compensateReq != nil {
+			req = step.compensateReq(results[i])
+		}
+
+		if err := s.retry(ctx, step.compensateKey, req); err != nil {
+			errs = append(errs, fmt.Errorf("compensate step %d (%s): %w", i, step.compensateKey, err))
+		}
+	}
+	return errs
+}
+
+func (s *Saga) retry(ctx context.Context, key string, req any) error {
+	delay := s.backoff
+	var err error
+	for attempt := 0; attempt <= s.retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+		if _, err = s.registry.Call(ctx, key, req); err == nil {
+			return nil
+		}
+	}
+	return err
+}
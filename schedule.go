@@ -0,0 +1,313 @@
+package irpc
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sort"
+	"sync"
+	"time"
+)
+
+// OverlapPolicy decides what happens when a scheduled run is due while the
+// previous run of the same schedule is still in progress.
+type OverlapPolicy int
+
+const (
+	// OverlapSkip skips the due run.
+	OverlapSkip OverlapPolicy = iota
+	// OverlapAllow starts the due run concurrently.
+	OverlapAllow
+)
+
+// ScheduleOption configures a schedule.
+type ScheduleOption func(*schedule)
+
+// WithJitter delays every run by a random duration in [0, d), spreading
+// schedules that share a spec.
+func WithJitter(d time.Duration) ScheduleOption {
+	return func(s *schedule) {
+		s.jitter = d
+	}
+}
+
+// WithOverlap sets the overlap policy of a schedule. The default is
+// OverlapSkip.
+func WithOverlap(p OverlapPolicy) ScheduleOption {
+	return func(s *schedule) {
+		s.overlap = p
+	}
+}
+
+// ScheduleInfo describes a schedule for introspection.
+type ScheduleInfo struct {
+	ID      string        `json:"id"`
+	Key     string        `json:"key"`
+	Spec    string        `json:"spec"`
+	Next    time.Time     `json:"next"`
+	LastRun time.Time     `json:"last_run"`
+	LastErr string        `json:"last_error,omitempty"`
+	Running int           `json:"running"`
+	Runs    uint64        `json:"runs"`
+	Skipped uint64        `json:"skipped"`
+	Jitter  time.Duration `json:"jitter_ns"`
+}
+
+type schedule struct {
+	id      string
+	key     string
+	req     any
+	specStr string
+	spec    *cronSpec
+	jitter  time.Duration
+	overlap OverlapPolicy
+
+	next    time.Time
+	lastRun time.Time
+	lastErr error
+	running int
+	runs    uint64
+	skipped uint64
+}
+
+type scheduler struct {
+	mu        sync.Mutex
+	schedules map[string]*schedule
+	wake      chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+	started   bool
+
+	// ctx is the context of the runs, canceled by shutdown.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Schedule invokes key with req periodically according to a cron spec:
+// five fields (minute hour day-of-month month day-of-week), a descriptor
+// such as "@hourly" or "@daily", or "@every 10m". Runs are dispatched on
+// the registry's executor like Notify. It returns the schedule's ID.
+//
+//	id, err := registry.Schedule("Exam.Reindex", ReindexReq{}, "*/15 * * * *", irpc.WithJitter(time.Minute))
+func (r *Registry) Schedule(key string, req any, spec string, opts ...ScheduleOption) (string, error) {
+	parsed, err := parseCron(spec)
+	if err != nil {
+		return "", err
+	}
+
+	s := &schedule{id: newID(), key: key, req: req, specStr: spec, spec: parsed}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.next = s.spec.next(time.Now())
+	if s.next.IsZero() {
+		return "", errors.New("irpc: cron spec " + spec + " never fires")
+	}
+
+	sch := r.scheduler()
+	sch.mu.Lock()
+	if !sch.started {
+		sch.started = true
+		go r.runScheduler(sch)
+	}
+	sch.schedules[s.id] = s
+	sch.mu.Unlock()
+	sch.poke()
+
+	return s.id, nil
+}
+
+// Unschedule removes a schedule. Runs already in progress are not
+// interrupted; those of every schedule are canceled when the registry
+// shuts down.
+func (r *Registry) Unschedule(id string) {
+	sch := r.scheduler()
+	sch.mu.Lock()
+	delete(sch.schedules, id)
+	sch.mu.Unlock()
+	sch.poke()
+}
+
+// Schedules returns every schedule ordered by its next run.
+func (r *Registry) Schedules() []ScheduleInfo {
+	sch := r.scheduler()
+	sch.mu.Lock()
+	out := make([]ScheduleInfo, 0, len(sch.schedules))
+	for _, s := range sch.schedules {
+		info := ScheduleInfo{
+			ID:      s.id,
+			Key:     s.key,
+			Spec:    s.specStr,
+			Next:    s.next,
+			LastRun: s.lastRun,
+			Running: s.running,
+			Runs:    s.runs,
+			Skipped: s.skipped,
+			Jitter:  s.jitter,
+		}
+		if s.lastErr != nil {
+			info.LastErr = s.lastErr.Error()
+		}
+		out = append(out, info)
+	}
+	sch.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Next.Before(out[j].Next)
+	})
+	return out
+}
+
+func (r *Registry) scheduler() *scheduler {
+	r.schedOnce.Do(func() {
+		r.sched = &scheduler{
+			schedules: make(map[string]*schedule),
+			wake:      make(chan struct{}, 1),
+			stop:      make(chan struct{}),
+			done:      make(chan struct{}),
+		}
+		r.sched.ctx, r.sched.cancel = context.WithCancel(context.Background())
+
+		r.mu.Lock()
+		r.onShutdown(r.sched.shutdown)
+		r.mu.Unlock()
+	})
+	return r.sched
+}
+
+func (s *scheduler) poke() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// stopped reports whether the scheduler was shut down.
+func (s *scheduler) stopped() bool {
+	select {
+	case <-s.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *scheduler) shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	started := s.started
+	s.started = true
+	s.mu.Unlock()
+
+	close(s.stop)
+	s.cancel()
+	if !started {
+		return nil
+	}
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// runScheduler sleeps until the earliest due schedule, fires every due
+// schedule and computes their next runs. Due runs are collected under
+// sch.mu and fired once it is released, since the executor may run them
+// inline or block.
+func (r *Registry) runScheduler(sch *scheduler) {
+	defer close(sch.done)
+
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		now := time.Now()
+		earliest := now.Add(time.Hour)
+
+		var due []*schedule
+		sch.mu.Lock()
+		for _, s := range sch.schedules {
+			if !s.next.After(now) {
+				if s.claim() {
+					due = append(due, s)
+				}
+				s.next = s.spec.next(now)
+			}
+			if !s.next.IsZero() && s.next.Before(earliest) {
+				earliest = s.next
+			}
+		}
+		sch.mu.Unlock()
+
+		for _, s := range due {
+			r.fire(sch, s)
+		}
+
+		timer.Reset(time.Until(earliest))
+		select {
+		case <-timer.C:
+		case <-sch.wake:
+		case <-sch.stop:
+			return
+		}
+	}
+}
+
+// claim starts a run of s, or counts it as skipped and returns false if
+// the overlap policy forbids it. The scheduler's mu must be held.
+func (s *schedule) claim() bool {
+	if s.running > 0 && s.overlap == OverlapSkip {
+		s.skipped++
+		return false
+	}
+	s.running++
+	s.runs++
+	return true
+}
+
+// fire dispatches a run of s claimed with claim, after its jitter. The
+// jitter is waited for with a timer rather than in the run, so that it
+// does not hold a worker of the executor.
+func (r *Registry) fire(sch *scheduler, s *schedule) {
+	if s.jitter <= 0 {
+		r.submitRun(sch, s)
+		return
+	}
+	time.AfterFunc(rand.N(s.jitter), func() { r.submitRun(sch, s) })
+}
+
+// submitRun submits a run of s to the executor. The run is canceled when
+// the scheduler is shut down.
+func (r *Registry) submitRun(sch *scheduler, s *schedule) {
+	if sch.stopped() {
+		sch.finish(s, false, context.Canceled)
+		return
+	}
+
+	err := r.executor().Submit(Task{
+		Key:      s.key,
+		Priority: PriorityNormal,
+		Ctx:      sch.ctx,
+		Run: func() {
+			_, err := r.Call(sch.ctx, s.key, s.req)
+			sch.finish(s, true, err)
+		},
+		Reject: func(err error) { sch.finish(s, false, err) },
+	})
+	if err != nil {
+		sch.finish(s, false, err)
+	}
+}
+
+// finish records the end of a run of s, which ran unless it was rejected.
+func (sch *scheduler) finish(s *schedule, ran bool, err error) {
+	sch.mu.Lock()
+	s.running--
+	if ran {
+		s.lastRun = time.Now()
+	}
+	s.lastErr = err
+	sch.mu.Unlock()
+}
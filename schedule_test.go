@@ -0,0 +1,119 @@
+package irpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitFor polls cond until it holds, failing t after a second.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// scheduleInfo returns the info of the schedule id, failing t if
+// Schedules does not return.
+func scheduleInfo(t *testing.T, r *Registry, id string) ScheduleInfo {
+	t.Helper()
+	infos := make(chan []ScheduleInfo, 1)
+	go func() { infos <- r.Schedules() }()
+	select {
+	case all := <-infos:
+		for _, info := range all {
+			if info.ID == id {
+				return info
+			}
+		}
+		t.Fatalf("no schedule %s", id)
+	case <-time.After(time.Second):
+		t.Fatal("Schedules() did not return")
+	}
+	return ScheduleInfo{}
+}
+
+func TestScheduleCallerRuns(t *testing.T) {
+	exec := NewPoolExecutor(PoolConfig{Size: 1, QueueLen: 1, Rejection: CallerRuns})
+	defer exec.Shutdown(context.Background())
+	r := NewRegistry(Config{Executor: exec})
+	defer r.Shutdown(context.Background())
+	r.Register("Exam.Reindex", func(ctx context.Context, req any) (any, error) { return nil, nil })
+
+	// Fill the worker and the queue, so that runs are made by the
+	// scheduler itself.
+	hold := make(chan struct{})
+	defer close(hold)
+	for i := range 2 {
+		if err := exec.Submit(Task{Key: "hold", Run: func() { <-hold }}); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			waitFor(t, "a busy worker", func() bool { return exec.Stats().Busy == 1 })
+		}
+	}
+
+	id, err := r.Schedule("Exam.Reindex", nil, "@every 5ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "two inline runs", func() bool { return scheduleInfo(t, r, id).Runs >= 2 })
+	if info := scheduleInfo(t, r, id); info.LastErr != "" || info.LastRun.IsZero() {
+		t.Errorf("schedule = %+v, want a successful run", info)
+	}
+}
+
+func TestScheduleJitterOutsideWorkers(t *testing.T) {
+	exec := NewPoolExecutor(PoolConfig{Size: 1})
+	defer exec.Shutdown(context.Background())
+	r := NewRegistry(Config{Executor: exec})
+	defer r.Shutdown(context.Background())
+	r.Register("Exam.Reindex", func(ctx context.Context, req any) (any, error) { return nil, nil })
+
+	id, err := r.Schedule("Exam.Reindex", nil, "@every 5ms", WithJitter(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "a run", func() bool { return scheduleInfo(t, r, id).Running == 1 })
+	if st := exec.Stats(); st.Busy != 0 || st.Depth() != 0 {
+		t.Errorf("executor busy %d, depth %d while the run waits for its jitter", st.Busy, st.Depth())
+	}
+}
+
+func TestScheduleShutdownCancelsRuns(t *testing.T) {
+	exec := NewPoolExecutor(PoolConfig{Size: 1})
+	defer exec.Shutdown(context.Background())
+	r := NewRegistry(Config{Executor: exec})
+
+	started, stopped := make(chan struct{}), make(chan error, 1)
+	r.Register("Exam.Reindex", func(ctx context.Context, req any) (any, error) {
+		close(started)
+		<-ctx.Done()
+		stopped <- ctx.Err()
+		return nil, ctx.Err()
+	})
+	if _, err := r.Schedule("Exam.Reindex", nil, "@every 5ms"); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := r.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-stopped:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("run stopped with %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("run not canceled by Shutdown")
+	}
+}
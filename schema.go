@@ -0,0 +1,217 @@
+package irpc
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// JSONSchema is a JSON Schema document describing a Go type.
+type JSONSchema map[string]any
+
+// MethodSchema describes the types of a registered key. Contract names
+// the interface declaring it; see WithSubContracts. NoContext flags legacy
+// methods adapted with AllowNoContext.
+type MethodSchema struct {
+	Key          string     `json:"key"`
+	Contract     string     `json:"contract,omitempty"`
+	NoContext    bool       `json:"no_context,omitempty"`
+	RequestType  string     `json:"request_type,omitempty"`
+	ResponseType string     `json:"response_type,omitempty"`
+	Request      JSONSchema `json:"request,omitempty"`
+	Response     JSONSchema `json:"response,omitempty"`
+	// Sources maps implementation names to the file:line that registered
+	// them.
+	Sources  map[string]string `json:"sources"`
+	Versions []string          `json:"versions,omitempty"`
+	// Registrations record where and when each implementation was
+	// registered, and Info who owns the key.
+	Registrations []Registration `json:"registrations"`
+	Info          ServiceInfo    `json:"info"`
+}
+
+// Schema returns the schema of key. Request and response types are only
+// known for keys registered with RegisterContract.
+func (r *Registry) Schema(key string) (MethodSchema, bool) {
+	r.mu.RLock()
+	e := r.entries[key]
+	if e == nil || len(e.impls) == 0 {
+		r.mu.RUnlock()
+		return MethodSchema{}, false
+	}
+	s := MethodSchema{Key: key, Sources: make(map[string]string, len(e.impls)), Info: r.info(key)}
+	for _, im := range e.impls {
+		s.Sources[im.name] = im.source
+		s.Registrations = append(s.Registrations, im.registration())
+	}
+	reqType, resType := e.reqType, e.resType
+	s.Contract, s.NoContext = e.contract, e.noContext
+	r.mu.RUnlock()
+
+	if reqType != nil {
+		s.RequestType = reqType.String()
+		s.Request = SchemaOf(reqType)
+	}
+	if resType != nil {
+		s.ResponseType = resType.String()
+		s.Response = SchemaOf(resType)
+	}
+	s.Versions = r.Versions(key)
+	return s, true
+}
+
+// Schemas returns the schema of every registered key, sorted by key.
+func (r *Registry) Schemas() []MethodSchema {
+	keys := r.Keys()
+	out := make([]MethodSchema, 0, len(keys))
+	for _, key := range keys {
+		if s, ok := r.Schema(key); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+	bytesType    = reflect.TypeOf([]byte(nil))
+)
+
+// SchemaOf returns the JSON Schema of t as encoded by encoding/json.
+func SchemaOf(t reflect.Type) JSONSchema {
+	return schemaOf(t, make(map[reflect.Type]bool))
+}
+
+func schemaOf(t reflect.Type, seen map[reflect.Type]bool) JSONSchema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return JSONSchema{"type": "string", "format": "date-time"}
+	case durationType:
+		return JSONSchema{"type": "integer", "description": "nanoseconds"}
+	case bytesType:
+		return JSONSchema{"type": "string", "contentEncoding": "base64"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return JSONSchema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return JSONSchema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return JSONSchema{"type": "number"}
+	case reflect.String:
+		return JSONSchema{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return JSONSchema{"type": "array", "items": schemaOf(t.Elem(), seen)}
+	case reflect.Map:
+		return JSONSchema{"type": "object", "additionalProperties": schemaOf(t.Elem(), seen)}
+	case reflect.Struct:
+		if seen[t] {
+			return JSONSchema{"type": "object", "title": t.Name()}
+		}
+		seen[t] = true
+		defer delete(seen, t)
+		return structSchema(t, seen)
+	default:
+		return JSONSchema{}
+	}
+}
+
+func structSchema(t reflect.Type, seen map[reflect.Type]bool) JSONSchema {
+	props := make(map[string]any)
+	var required []string
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() && !f.Anonymous {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			if embedded := schemaOf(f.Type, seen); embedded["properties"] != nil {
+				for k, v := range embedded["properties"].(map[string]any) {
+					props[k] = v
+				}
+				if req, ok := embedded["required"].([]string); ok {
+					required = append(required, req...)
+				}
+				continue
+			}
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		props[name] = schemaOf(f.Type, seen)
+		if !strings.Contains(opts, "omitempty") && !strings.Contains(opts, "omitzero") && f.Type.Kind() != reflect.Pointer {
+			required = append(required, name)
+		}
+	}
+
+	s := JSONSchema{"type": "object", "properties": props}
+	if t.Name() != "" {
+		s["title"] = t.Name()
+	}
+	if len(required) > 0 {
+		sort.Strings(required)
+		s["required"] = required
+	}
+	return s
+}
+
+var (
+	errorType    = reflect.TypeOf((*error)(nil)).Elem()
+	contextType  = reflect.TypeOf((*context.Context)(nil)).Elem()
+	anySliceType = reflect.TypeOf([]any(nil))
+)
+
+// takesContext reports whether a contract method takes a context first.
+func takesContext(m reflect.Type) bool {
+	return m.NumIn() > 0 && m.In(0) == contextType
+}
+
+// requestTypeOf returns the request type of a contract method, as passed
+// by makeHandler: nil without parameters, the parameter type for a single
+// one (the slice type if variadic) and []any for several.
+func requestTypeOf(m reflect.Type) reflect.Type {
+	n := 0
+	if takesContext(m) {
+		n = 1
+	}
+	switch m.NumIn() - n {
+	case 0:
+		return nil
+	case 1:
+		return m.In(n)
+	}
+	return anySliceType
+}
+
+// responseTypeOf returns the response type of a contract method: nil if
+// it only returns an error, the result type for a single result and []any
+// for several.
+func responseTypeOf(m reflect.Type) reflect.Type {
+	n := m.NumOut()
+	if n > 0 && m.Out(n-1) == errorType {
+		n--
+	}
+	switch n {
+	case 0:
+		return nil
+	case 1:
+		return m.Out(0)
+	}
+	return anySliceType
+}
@@ -0,0 +1,183 @@
+package irpc
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var (
+	jsonMarshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	binaryMarshalerType = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+)
+
+// CheckSerializable reports why values of t cannot be sent to another
+// process intact: it finds funcs, channels, unsafe pointers and interfaces,
+// whose concrete type a codec cannot recover, as well as structs whose
+// fields are all unexported. Types with their own JSON, text or binary
+// marshaling are trusted. Fields tagged `json:"-"` are ignored.
+//
+// Set Config.StrictTypes to check every contract this way at registration.
+func CheckSerializable(t reflect.Type) error {
+	return checkSerializable(t, t.String(), make(map[reflect.Type]bool))
+}
+
+func checkSerializable(t reflect.Type, path string, seen map[reflect.Type]bool) error {
+	if marshals(t) {
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+		return fmt.Errorf("%s: %s values cannot be serialized", path, t.Kind())
+	case reflect.Interface:
+		return fmt.Errorf("%s: %s loses its concrete type when decoded", path, t)
+	case reflect.Pointer, reflect.Slice, reflect.Array:
+		return checkSerializable(t.Elem(), path+"[]", seen)
+	case reflect.Map:
+		if err := checkSerializable(t.Key(), path+"[key]", seen); err != nil {
+			return err
+		}
+		return checkSerializable(t.Elem(), path+"[]", seen)
+	case reflect.Struct:
+		if seen[t] {
+			return nil
+		}
+		seen[t] = true
+		return checkStruct(t, path, seen)
+	}
+	return nil
+}
+
+func checkStruct(t reflect.Type, path string, seen map[reflect.Type]bool) error {
+	exported := false
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() && !f.Anonymous {
+			continue
+		}
+		if name, opts, _ := strings.Cut(f.Tag.Get("json"), ","); name == "-" && opts == "" {
+			continue
+		}
+		exported = true
+		if err := checkSerializable(f.Type, path+"."+f.Name, seen); err != nil {
+			return err
+		}
+	}
+	if t.NumField() > 0 && !exported {
+		return fmt.Errorf("%s: %s has only unexported fields", path, t)
+	}
+	return nil
+}
+
+// marshals reports whether t or *t serializes itself.
+func marshals(t reflect.Type) bool {
+	for _, m := range []reflect.Type{jsonMarshalerType, textMarshalerType, binaryMarshalerType} {
+		if t.Implements(m) || (t.Kind() != reflect.Pointer && reflect.PointerTo(t).Implements(m)) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkContractTypes panics if a parameter or result of m, the method of
+// key, is not serializable. The parameters and results of methods with
+// several of them are checked one by one, rather than as the []any they
+// travel in.
+func checkContractTypes(key string, m reflect.Type) {
+	first := 0
+	if takesContext(m) {
+		first = 1
+	}
+	params := m.NumIn() - first
+	for i := first; i < m.NumIn(); i++ {
+		if err := CheckSerializable(m.In(i)); err != nil {
+			panic(fmt.Sprintf("irpc: %s of %s is not serializable: %v", partName("request", "parameter", i-first, params), key, err))
+		}
+	}
+
+	results := m.NumOut()
+	if results > 0 && m.Out(results-1) == errorType {
+		results--
+	}
+	for i := 0; i < results; i++ {
+		if err := CheckSerializable(m.Out(i)); err != nil {
+			panic(fmt.Sprintf("irpc: %s of %s is not serializable: %v", partName("response", "result", i, results), key, err))
+		}
+	}
+}
+
+// partName names the i-th of n parameters or results of a method: whole
+// if it is the only one, e.g. "request", or part and its position.
+func partName(whole, part string, i, n int) string {
+	if n == 1 {
+		return whole
+	}
+	return fmt.Sprintf("%s %d", part, i+1)
+}
+
+// errLossy is returned by roundTrip when a value decodes to something
+// that encodes differently.
+var errLossy = errors.New("value changed after a round trip through the codec")
+
+// roundTrip encodes v, decodes it into a new t and encodes the result
+// again, failing if the two encodings differ.
+func roundTrip(c Codec, t reflect.Type, v any) error {
+	first, err := c.Marshal(v)
+	if err != nil {
+		return err
+	}
+	decoded := reflect.New(t)
+	if err := c.Unmarshal(first, decoded.Interface()); err != nil {
+		return err
+	}
+	second, err := c.Marshal(decoded.Elem().Interface())
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(first, second) {
+		return fmt.Errorf("%w: %s became %s", errLossy, truncate(first), truncate(second))
+	}
+	return nil
+}
+
+func truncate(b []byte) string {
+	const max = 200
+	if len(b) > max {
+		return string(b[:max]) + "..."
+	}
+	return string(b)
+}
+
+// strictCall round-trips the requests and responses of a key at call
+// time; see Config.RoundTripCalls.
+type strictCall struct {
+	codec   Codec
+	reqType reflect.Type
+	resType reflect.Type
+}
+
+func (s *strictCall) checkRequest(key string, req any) error {
+	if s == nil || s.reqType == nil || req == nil {
+		return nil
+	}
+	if err := roundTrip(s.codec, s.reqType, req); err != nil {
+		return &Error{Code: InvalidArgument, Key: key, Message: key + ": request does not round-trip through the codec", Err: err}
+	}
+	return nil
+}
+
+func (s *strictCall) checkResponse(key string, res any) error {
+	if s == nil || s.resType == nil || res == nil {
+		return nil
+	}
+	if err := roundTrip(s.codec, s.resType, res); err != nil {
+		return &Error{Code: Internal, Key: key, Message: "response does not round-trip through the codec", Err: err}
+	}
+	return nil
+}
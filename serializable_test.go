@@ -0,0 +1,91 @@
+package irpc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type calcContract interface {
+	Add(ctx context.Context, a, b int) (int, error)
+	DivMod(ctx context.Context, a, b int) (int, int, error)
+	Sum(ctx context.Context, xs ...int) (int, error)
+}
+
+type calc struct{}
+
+func (*calc) Add(ctx context.Context, a, b int) (int, error)         { return a + b, nil }
+func (*calc) DivMod(ctx context.Context, a, b int) (int, int, error) { return a / b, a % b, nil }
+func (*calc) Sum(ctx context.Context, xs ...int) (int, error) {
+	n := 0
+	for _, x := range xs {
+		n += x
+	}
+	return n, nil
+}
+
+type lossyContract interface {
+	Apply(ctx context.Context, name string, fn func()) error
+	Lookup(ctx context.Context, name string) (string, any, error)
+}
+
+type lossy struct{}
+
+func (*lossy) Apply(ctx context.Context, name string, fn func()) error      { return nil }
+func (*lossy) Lookup(ctx context.Context, name string) (string, any, error) { return "", nil, nil }
+
+func TestStrictTypesMultiParameter(t *testing.T) {
+	r := NewRegistry(Config{StrictTypes: true})
+	r.RegisterContract("Calc", (*calcContract)(nil), &calc{})
+
+	res, err := r.Call(context.Background(), "Calc.Add", []any{1, 2})
+	if err != nil || res != 3 {
+		t.Fatalf("Add = %v, %v, want 3", res, err)
+	}
+	res, err = r.Call(context.Background(), "Calc.DivMod", []any{7, 2})
+	if err != nil || fmt.Sprint(res) != "[3 1]" {
+		t.Fatalf("DivMod = %v, %v, want [3 1]", res, err)
+	}
+}
+
+func TestStrictTypesRejectsParts(t *testing.T) {
+	defer func() {
+		msg := fmt.Sprint(recover())
+		if !strings.Contains(msg, "parameter 2 of Lossy.Apply") {
+			t.Errorf("panic %q, want it to name the func parameter", msg)
+		}
+	}()
+	r := NewRegistry(Config{StrictTypes: true})
+	r.RegisterContract("Lossy", (*lossyContract)(nil), &lossy{})
+	t.Error("registered a func parameter under StrictTypes")
+}
+
+func TestCheckContractTypes(t *testing.T) {
+	tests := []struct {
+		impl   any
+		method string
+		want   string // in the panic, or "" for none
+	}{
+		{&calc{}, "Add", ""},
+		{&calc{}, "DivMod", ""},
+		{&calc{}, "Sum", ""},
+		{&lossy{}, "Apply", "parameter 2 of X.Apply"},
+		{&lossy{}, "Lookup", "result 2 of X.Lookup"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			defer func() {
+				msg := ""
+				if v := recover(); v != nil {
+					msg = fmt.Sprint(v)
+				}
+				if (tt.want == "") != (msg == "") || !strings.Contains(msg, tt.want) {
+					t.Errorf("panic %q, want %q", msg, tt.want)
+				}
+			}()
+			checkContractTypes("X."+tt.method, reflect.ValueOf(tt.impl).MethodByName(tt.method).Type())
+		})
+	}
+}
@@ -0,0 +1,361 @@
+package irpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"time"
+)
+
+// Settings are the tunables of a registry that can change while it runs:
+// timeouts, limits and the weights of routes, e.g. of a canary. They are
+// read from a SettingsProvider by WatchSettings, or applied directly with
+// ApplySettings.
+type Settings struct {
+	// Timeouts, RateLimits and Bulkheads are applied with SetTimeout,
+	// SetRateLimit and SetBulkhead.
+	Timeouts   map[string]Duration  `json:"timeouts,omitempty" yaml:"timeouts,omitempty"`
+	RateLimits map[string]RateLimit `json:"rate_limits,omitempty" yaml:"rate_limits,omitempty"`
+	Bulkheads  map[string]int       `json:"bulkheads,omitempty" yaml:"bulkheads,omitempty"`
+	Routes     []RouteDeclaration   `json:"routes,omitempty" yaml:"routes,omitempty"`
+}
+
+// RouteDeclaration splits the callers of Key between implementations with
+// RunExperiment.
+type RouteDeclaration struct {
+	Key        string    `json:"key" yaml:"key"`
+	Experiment string    `json:"experiment" yaml:"experiment"`
+	AssignBy   string    `json:"assign_by" yaml:"assign_by"`
+	Variants   []Variant `json:"variants" yaml:"variants"`
+}
+
+func (rt RouteDeclaration) experiment() Experiment {
+	return Experiment{Name: rt.Experiment, AssignBy: rt.AssignBy, Variants: rt.Variants}
+}
+
+// Duration is a time.Duration written as a string such as "250ms" in
+// settings and declarations.
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (s Settings) check() error {
+	for pattern, t := range s.Timeouts {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("irpc: timeout %s: %w", pattern, err)
+		}
+		if t < 0 {
+			return fmt.Errorf("irpc: negative timeout for %s", pattern)
+		}
+	}
+	for pattern, l := range s.RateLimits {
+		if err := checkRateLimit(pattern, l); err != nil {
+			return err
+		}
+	}
+	for service, max := range s.Bulkheads {
+		if max < 0 {
+			return fmt.Errorf("irpc: negative bulkhead for %s", service)
+		}
+	}
+	keys := make(map[string]bool, len(s.Routes))
+	for _, rt := range s.Routes {
+		if keys[rt.Key] {
+			return fmt.Errorf("irpc: route %s declared twice", rt.Key)
+		}
+		keys[rt.Key] = true
+		if _, err := rt.experiment().check(); err != nil {
+			return fmt.Errorf("irpc: route %s: %w", rt.Key, err)
+		}
+	}
+	return nil
+}
+
+// setSettings applies checked settings on top of the current ones. r.mu
+// must be held.
+func (r *Registry) setSettings(s Settings) {
+	for pattern, t := range s.Timeouts {
+		r.setTimeout(pattern, time.Duration(t))
+	}
+	for pattern, l := range s.RateLimits {
+		r.setRateLimit(pattern, l)
+	}
+	for service, max := range s.Bulkheads {
+		r.setBulkhead(service, max)
+	}
+	for _, rt := range s.Routes {
+		exp := rt.experiment()
+		total, _ := exp.check()
+		r.runExperiment(rt.Key, exp, total)
+	}
+}
+
+// ApplySettings replaces the settings in effect, from earlier calls to it,
+// UpdateSettings or WatchSettings, with s, atomically: no call sees some of s applied and some not, and invalid
+// settings are rejected as a whole. Timeouts, limits and routes that were
+// in the previous settings but are missing from s are removed; settings
+// made in code with SetTimeout and the like are only changed where s sets
+// them too.
+func (r *Registry) ApplySettings(s Settings) error {
+	return r.UpdateSettings("", func(cur *Settings) { *cur = s })
+}
+
+// UpdateSettings passes a copy of the settings in effect to update and
+// applies the result as ApplySettings does. by identifies who made the
+// change in SettingsHistory and to OnSettingsChange hooks. Updates are
+// serialized, so concurrent ones do not lose each other's changes.
+//
+//	registry.UpdateSettings("alice", func(s *irpc.Settings) {
+//		s.Timeouts["Billing.*"] = irpc.Duration(500 * time.Millisecond)
+//	})
+func (r *Registry) UpdateSettings(by string, update func(*Settings)) error {
+	r.settingsMu.Lock()
+	defer r.settingsMu.Unlock()
+
+	r.mu.RLock()
+	old := r.settings
+	r.mu.RUnlock()
+
+	s := old.clone()
+	if s.Timeouts == nil {
+		s.Timeouts = make(map[string]Duration)
+	}
+	if s.RateLimits == nil {
+		s.RateLimits = make(map[string]RateLimit)
+	}
+	if s.Bulkheads == nil {
+		s.Bulkheads = make(map[string]int)
+	}
+	update(&s)
+	if err := s.check(); err != nil {
+		return err
+	}
+	s = s.clone()
+
+	r.mu.Lock()
+	for pattern := range old.Timeouts {
+		if _, ok := s.Timeouts[pattern]; !ok {
+			r.setTimeout(pattern, 0)
+		}
+	}
+	for pattern := range old.RateLimits {
+		if _, ok := s.RateLimits[pattern]; !ok {
+			r.setRateLimit(pattern, RateLimit{})
+		}
+	}
+	for service := range old.Bulkheads {
+		if _, ok := s.Bulkheads[service]; !ok {
+			r.setBulkhead(service, 0)
+		}
+	}
+	for _, rt := range old.Routes {
+		if !slices.ContainsFunc(s.Routes, func(n RouteDeclaration) bool { return n.Key == rt.Key }) {
+			r.ensureEntry(rt.Key).route = nil
+		}
+	}
+	r.setSettings(s)
+	r.settings = s
+	change, hooks := r.recordSettingsChange(by, old, s)
+	r.mu.Unlock()
+
+	if change != nil {
+		for _, fn := range hooks {
+			fn(*change)
+		}
+		r.emit(context.Background(), settingsChangeEvent(*change))
+	}
+	return nil
+}
+
+// Settings returns the settings in effect, as applied by ApplySettings,
+// UpdateSettings or WatchSettings. Settings made in code with SetTimeout
+// and the like are not included.
+func (r *Registry) Settings() Settings {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.settings.clone()
+}
+
+func (s Settings) clone() Settings {
+	s.Timeouts = maps.Clone(s.Timeouts)
+	s.RateLimits = maps.Clone(s.RateLimits)
+	s.Bulkheads = maps.Clone(s.Bulkheads)
+	s.Routes = slices.Clone(s.Routes)
+	for i := range s.Routes {
+		s.Routes[i].Variants = slices.Clone(s.Routes[i].Variants)
+	}
+	return s
+}
+
+// SettingsProvider supplies the settings of a registry, e.g. from a file.
+type SettingsProvider interface {
+	// Load returns the current settings.
+	Load(ctx context.Context) (Settings, error)
+	// Watch calls changed whenever the settings may have changed, until
+	// ctx is done.
+	Watch(ctx context.Context, changed func()) error
+}
+
+// OnSettingsError registers fn to be invoked when WatchSettings cannot
+// load or apply changed settings. The previous settings stay in effect.
+func (r *Registry) OnSettingsError(fn func(error)) {
+	r.mu.Lock()
+	r.onSettingsError = fn
+	r.mu.Unlock()
+}
+
+// WatchSettings applies the settings of p, then applies them again every
+// time p reports a change, until ctx is done. It returns early only if
+// the first settings cannot be loaded or applied; later failures are
+// reported to the OnSettingsError hook and leave the previous settings in
+// effect.
+//
+//	p := &irpc.FileSettings{Path: "/etc/app/irpc.json"}
+//	if err := registry.ApplySettingsFrom(ctx, p); err != nil { ... }
+//	go registry.WatchSettings(ctx, p)
+func (r *Registry) WatchSettings(ctx context.Context, p SettingsProvider) error {
+	if err := r.ApplySettingsFrom(ctx, p); err != nil {
+		return err
+	}
+	return p.Watch(ctx, func() {
+		if err := r.ApplySettingsFrom(ctx, p); err != nil {
+			r.mu.RLock()
+			fn := r.onSettingsError
+			r.mu.RUnlock()
+			if fn != nil {
+				fn(err)
+			}
+		}
+	})
+}
+
+// ApplySettingsFrom loads the settings of p and applies them with
+// ApplySettings. Changes made this way are recorded as made by p, if it
+// is a fmt.Stringer.
+func (r *Registry) ApplySettingsFrom(ctx context.Context, p SettingsProvider) error {
+	s, err := p.Load(ctx)
+	if err != nil {
+		return err
+	}
+	by := ""
+	if str, ok := p.(fmt.Stringer); ok {
+		by = str.String()
+	}
+	return r.UpdateSettings(by, func(cur *Settings) { *cur = s })
+}
+
+// DefaultSettingsInterval is how often FileSettings checks its file when
+// Interval is zero.
+const DefaultSettingsInterval = 5 * time.Second
+
+// FileSettings reads settings from a JSON file, or from a file in another
+// format when Unmarshal is set, e.g. to yaml.Unmarshal. It watches the
+// file by checking its modification time and size every Interval, which
+// also notices files replaced by a rename, as configuration mounts are.
+type FileSettings struct {
+	Path      string
+	Interval  time.Duration
+	Unmarshal func(data []byte, v any) error
+}
+
+func (f *FileSettings) String() string {
+	return "file:" + f.Path
+}
+
+func (f *FileSettings) Load(ctx context.Context) (Settings, error) {
+	data, err := os.ReadFile(f.Path)
+	if err != nil {
+		return Settings{}, err
+	}
+	s, err := decodeSettings(data, f.Unmarshal)
+	if err != nil {
+		return Settings{}, fmt.Errorf("irpc: reading settings from %s: %w", f.Path, err)
+	}
+	return s, nil
+}
+
+func (f *FileSettings) Watch(ctx context.Context, changed func()) error {
+	interval := f.Interval
+	if interval <= 0 {
+		interval = DefaultSettingsInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last, _ := os.Stat(f.Path)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		fi, err := os.Stat(f.Path)
+		if err != nil {
+			continue
+		}
+		if last == nil || !fi.ModTime().Equal(last.ModTime()) || fi.Size() != last.Size() {
+			last = fi
+			changed()
+		}
+	}
+}
+
+// EnvSettings reads settings from the environment variable Name, holding
+// them in the same format as FileSettings. The environment of a process
+// does not change from outside, so Watch only waits for ctx to be done.
+type EnvSettings struct {
+	Name      string
+	Unmarshal func(data []byte, v any) error
+}
+
+func (e *EnvSettings) String() string {
+	return "env:" + e.Name
+}
+
+func (e *EnvSettings) Load(ctx context.Context) (Settings, error) {
+	v, ok := os.LookupEnv(e.Name)
+	if !ok {
+		return Settings{}, nil
+	}
+	s, err := decodeSettings([]byte(v), e.Unmarshal)
+	if err != nil {
+		return Settings{}, fmt.Errorf("irpc: reading settings from $%s: %w", e.Name, err)
+	}
+	return s, nil
+}
+
+func (e *EnvSettings) Watch(ctx context.Context, changed func()) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// decodeSettings decodes data with unmarshal, or as JSON rejecting unknown
+// fields if unmarshal is nil.
+func decodeSettings(data []byte, unmarshal func([]byte, any) error) (Settings, error) {
+	var s Settings
+	if unmarshal != nil {
+		err := unmarshal(data, &s)
+		return s, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(&s)
+	return s, err
+}
@@ -0,0 +1,116 @@
+package irpc
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SettingsHistoryLimit is the number of changes SettingsHistory keeps.
+const SettingsHistoryLimit = 100
+
+// SettingsChange records a change to the settings of a registry. Old and
+// New are shared with the registry and must not be modified.
+type SettingsChange struct {
+	At time.Time `json:"at"`
+	// By identifies who made the change: the by of UpdateSettings, the
+	// provider of WatchSettings, or the user of an AdminHandler request.
+	By  string   `json:"by,omitempty"`
+	Old Settings `json:"old"`
+	New Settings `json:"new"`
+	// Diff describes what changed, one setting per line, e.g.
+	// "timeouts[Billing.*]: 2s -> 500ms".
+	Diff []string `json:"diff"`
+}
+
+// OnSettingsChange registers fn to be invoked after every change to the
+// settings, in addition to the functions registered before, e.g. so that
+// middleware depending on them can re-initialize. Hooks run one change at
+// a time, in order, and must not change the settings themselves.
+func (r *Registry) OnSettingsChange(fn func(SettingsChange)) {
+	r.mu.Lock()
+	r.onSettingsChange = append(r.onSettingsChange, fn)
+	r.mu.Unlock()
+}
+
+// SettingsHistory returns the last SettingsHistoryLimit changes to the
+// settings, oldest first.
+func (r *Registry) SettingsHistory() []SettingsChange {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return slices.Clone(r.settingsHistory)
+}
+
+// recordSettingsChange adds the change from old to s to the history and
+// returns it with the hooks to notify, or nil if nothing changed. r.mu
+// must be held.
+func (r *Registry) recordSettingsChange(by string, old, s Settings) (*SettingsChange, []func(SettingsChange)) {
+	diff := diffSettings(old, s)
+	if len(diff) == 0 {
+		return nil, nil
+	}
+	change := SettingsChange{At: time.Now(), By: by, Old: old, New: s, Diff: diff}
+	if len(r.settingsHistory) >= SettingsHistoryLimit {
+		r.settingsHistory = slices.Delete(r.settingsHistory, 0, len(r.settingsHistory)-SettingsHistoryLimit+1)
+	}
+	r.settingsHistory = append(r.settingsHistory, change)
+	return &change, r.onSettingsChange
+}
+
+func diffSettings(old, s Settings) []string {
+	var diff []string
+	diff = diffMaps(diff, "timeouts", old.Timeouts, s.Timeouts, func(d Duration) string {
+		return time.Duration(d).String()
+	})
+	diff = diffMaps(diff, "rate_limits", old.RateLimits, s.RateLimits, func(l RateLimit) string {
+		return fmt.Sprintf("%g/s burst %d", l.Rate, l.Burst)
+	})
+	diff = diffMaps(diff, "bulkheads", old.Bulkheads, s.Bulkheads, func(max int) string {
+		return fmt.Sprint(max)
+	})
+	diff = diffMaps(diff, "routes", routesByKey(old.Routes), routesByKey(s.Routes), func(rt RouteDeclaration) string {
+		variants := make([]string, len(rt.Variants))
+		for i, v := range rt.Variants {
+			variants[i] = fmt.Sprintf("%s=%d", v.Impl, v.Weight)
+		}
+		return fmt.Sprintf("%s by %s: %s", rt.Experiment, rt.AssignBy, strings.Join(variants, " "))
+	})
+	return diff
+}
+
+func routesByKey(routes []RouteDeclaration) map[string]RouteDeclaration {
+	out := make(map[string]RouteDeclaration, len(routes))
+	for _, rt := range routes {
+		out[rt.Key] = rt
+	}
+	return out
+}
+
+// diffMaps appends to diff a line for every key whose value differs
+// between old and cur, in the order of the keys.
+func diffMaps[V any](diff []string, name string, old, cur map[string]V, format func(V) string) []string {
+	keys := slices.Sorted(maps.Keys(old))
+	for k := range cur {
+		if _, ok := old[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		from, to := "none", "none"
+		if v, ok := old[k]; ok {
+			from = format(v)
+		}
+		if v, ok := cur[k]; ok {
+			to = format(v)
+		}
+		if from != to {
+			diff = append(diff, fmt.Sprintf("%s[%s]: %s -> %s", name, k, from, to))
+		}
+	}
+	return diff
+}
@@ -0,0 +1,108 @@
+package irpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// ErrSLAExceeded is the cause of the context of a handler cancelled by
+// LatencySLA, so that the handler can tell it from a deadline of the
+// caller:
+//
+//	if errors.Is(context.Cause(ctx), irpc.ErrSLAExceeded) { ... }
+var ErrSLAExceeded = errors.New("irpc: latency SLA exceeded")
+
+// SLAViolation describes a call that did not finish within its latency
+// SLA. It is also the error detail of the DeadlineExceeded error such a
+// call fails with.
+type SLAViolation struct {
+	Key     string        `json:"key"`
+	SLA     time.Duration `json:"sla_ns"`
+	Elapsed time.Duration `json:"elapsed_ns"`
+}
+
+// LatencySLA returns middleware bounding every call it wraps to sla: the
+// handler's context is cancelled with ErrSLAExceeded once sla has passed,
+// and onViolation, if not nil, is called once the handler returns. A call
+// whose handler fails after the SLA has passed fails with
+// DeadlineExceeded and an SLAViolation detail instead; one that succeeds
+// anyway keeps its result, since its effects have happened, but still
+// counts as a violation. Deadlines of the caller and timeouts set with
+// SetTimeout that fire first are not violations.
+//
+// Use it with UseFor to bound some keys; EnforceLatencySLA also records
+// the violations in the registry.
+func LatencySLA(sla time.Duration, onViolation func(SLAViolation)) Middleware {
+	return func(key string, next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, req any) (any, error) {
+			start := time.Now()
+			ctx, cancel := context.WithTimeoutCause(ctx, sla, ErrSLAExceeded)
+			defer cancel()
+
+			res, err := next(ctx, req)
+			if !errors.Is(context.Cause(ctx), ErrSLAExceeded) {
+				return res, err
+			}
+			v := SLAViolation{Key: key, SLA: sla, Elapsed: time.Since(start)}
+			if onViolation != nil {
+				onViolation(v)
+			}
+			if err != nil {
+				err = &Error{
+					Code:    DeadlineExceeded,
+					Key:     key,
+					Message: fmt.Sprintf("latency SLA of %s exceeded", sla),
+					Err:     err,
+					Details: []any{v},
+				}
+			}
+			return res, err
+		}
+	}
+}
+
+// EnforceLatencySLA applies LatencySLA to the keys matching pattern, in the
+// syntax of path.Match, at its position in the middleware chain as UseFor
+// does. Violations are counted in the SLAViolations of Stats, apart from the
+// DeadlineExceeded errors of other timeouts, reported to the
+// OnSLAViolation hook and sent to the exporters of ExportTelemetry.
+//
+//	registry.EnforceLatencySLA("Search.*", 150*time.Millisecond)
+func (r *Registry) EnforceLatencySLA(pattern string, sla time.Duration) error {
+	if sla <= 0 {
+		return fmt.Errorf("irpc: latency SLA must be positive")
+	}
+	return r.UseFor(pattern, LatencySLA(sla, r.slaViolated))
+}
+
+// OnSLAViolation registers fn to be invoked after every call that exceeds
+// a latency SLA set with EnforceLatencySLA. fn runs synchronously on the
+// caller's goroutine.
+func (r *Registry) OnSLAViolation(fn func(SLAViolation)) {
+	r.mu.Lock()
+	r.onSLAViolation = fn
+	r.mu.Unlock()
+}
+
+func (r *Registry) slaViolated(v SLAViolation) {
+	r.stats.recordSLAViolation(v.Key)
+
+	r.mu.RLock()
+	fn := r.onSLAViolation
+	r.mu.RUnlock()
+
+	if fn != nil {
+		fn(v)
+	}
+	r.emit(context.Background(), slaViolationEvent(v))
+}
+
+func slaViolationEvent(v SLAViolation) TelemetryEvent {
+	return TelemetryEvent{Name: EventSLAViolation, Key: v.Key, Attrs: []slog.Attr{
+		slog.Duration("sla", v.SLA),
+		slog.Duration("elapsed", v.Elapsed),
+	}}
+}
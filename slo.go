@@ -0,0 +1,309 @@
+package irpc
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultSLOWindow is the rolling window of an SLO whose Window is zero.
+const DefaultSLOWindow = time.Hour
+
+// SLO is the service level objective of a key: the share of its calls
+// that must succeed, and the share that must finish within a latency.
+// Either objective is disabled by leaving it zero.
+type SLO struct {
+	// Availability is the target fraction of calls that do not fail with a
+	// server error, in [0, 1), e.g. 0.999. Unknown, Internal, Unavailable,
+	// DataLoss, DeadlineExceeded and Unimplemented are server errors; the
+	// other codes are the caller's doing and do not count against it.
+	Availability float64 `json:"availability,omitempty"`
+	// Latency is the latency within which LatencyTarget of the calls must
+	// finish, or 0.99 of them if LatencyTarget is zero.
+	Latency       time.Duration `json:"latency_ns,omitempty"`
+	LatencyTarget float64       `json:"latency_target,omitempty"`
+	// Window is the rolling window compliance is computed over. Zero means
+	// DefaultSLOWindow.
+	Window time.Duration `json:"window_ns"`
+	// MinCalls is the number of calls in the window below which the budget
+	// is not considered exhausted, so a single failure does not exhaust it.
+	MinCalls int `json:"min_calls,omitempty"`
+}
+
+func (s SLO) check() error {
+	if s.Availability < 0 || s.Availability >= 1 {
+		return fmt.Errorf("irpc: SLO availability must be in [0, 1)")
+	}
+	if s.LatencyTarget < 0 || s.LatencyTarget >= 1 {
+		return fmt.Errorf("irpc: SLO latency target must be in [0, 1)")
+	}
+	if s.Latency < 0 || s.Window < 0 {
+		return fmt.Errorf("irpc: negative SLO latency or window")
+	}
+	return nil
+}
+
+// SLOStatus is the compliance of a key with its SLO over the current
+// window.
+type SLOStatus struct {
+	Key    string `json:"key"`
+	SLO    SLO    `json:"slo"`
+	Calls  uint64 `json:"calls"`
+	Failed uint64 `json:"failed"`
+	Slow   uint64 `json:"slow"`
+	// AvailabilityBurnRate and LatencyBurnRate are the rates at which the
+	// error budget of each objective is being spent: 1 spends exactly the
+	// budget over the window, 2 twice as fast.
+	AvailabilityBurnRate float64 `json:"availability_burn_rate"`
+	LatencyBurnRate      float64 `json:"latency_burn_rate"`
+	// AvailabilityBudget and LatencyBudget are the fractions of the error
+	// budgets left in the window: 1 untouched, 0 or less exhausted.
+	AvailabilityBudget float64 `json:"availability_budget"`
+	LatencyBudget      float64 `json:"latency_budget"`
+	// Exhausted is true if either budget is, with at least MinCalls calls.
+	Exhausted bool `json:"exhausted"`
+}
+
+// sloBuckets is the number of buckets a window is split into. The window
+// rolls one bucket at a time.
+const sloBuckets = 60
+
+type sloBucket struct {
+	epoch        int64
+	calls        uint64
+	failed, slow uint64
+}
+
+type sloState struct {
+	buckets   [sloBuckets]sloBucket
+	exhausted bool
+}
+
+type sloRule struct {
+	pattern string
+	slo     SLO
+
+	mu     sync.Mutex
+	states map[string]*sloState
+}
+
+// SetSLO sets the SLO of keys matching pattern, in the syntax of
+// path.Match, and starts tracking their compliance. The first matching
+// pattern applies. Setting a pattern again replaces its SLO in place and
+// restarts its windows; the zero SLO removes it.
+//
+//	registry.SetSLO("Billing.*", irpc.SLO{Availability: 0.999, Latency: 200 * time.Millisecond, MinCalls: 100})
+func (r *Registry) SetSLO(pattern string, slo SLO) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return err
+	}
+	if err := slo.check(); err != nil {
+		return err
+	}
+	if slo.Window == 0 {
+		slo.Window = DefaultSLOWindow
+	}
+	if slo.Latency > 0 && slo.LatencyTarget == 0 {
+		slo.LatencyTarget = 0.99
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	remove := slo.Availability == 0 && slo.Latency == 0
+	slos := make([]*sloRule, 0, len(r.slos)+1)
+	replaced := false
+	for _, rule := range r.slos {
+		if rule.pattern == pattern {
+			replaced = true
+			if remove {
+				continue
+			}
+			rule = &sloRule{pattern: pattern, slo: slo, states: make(map[string]*sloState)}
+		}
+		slos = append(slos, rule)
+	}
+	if !replaced && !remove {
+		slos = append(slos, &sloRule{pattern: pattern, slo: slo, states: make(map[string]*sloState)})
+	}
+	r.slos = slos
+	return nil
+}
+
+// WithMethodSLO sets the SLO of a method of the contract being registered,
+// as SetSLO does for its key. An SLO set earlier for a pattern matching
+// the key takes precedence.
+//
+//	registry.RegisterContract("Billing", (*BillingContract)(nil), impl,
+//		irpc.WithMethodSLO("Charge", irpc.SLO{Availability: 0.999}))
+func WithMethodSLO(method string, slo SLO) RegisterOption {
+	return func(o *registerOptions) {
+		if o.slos == nil {
+			o.slos = make(map[string]SLO)
+		}
+		o.slos[method] = slo
+	}
+}
+
+// OnBudgetExhausted registers fn to be invoked when a key exhausts the
+// error budget of its SLO, and again, with Exhausted false, once the
+// budget recovers as the window rolls on. fn runs synchronously on the
+// goroutine of the call that changed the state.
+func (r *Registry) OnBudgetExhausted(fn func(SLOStatus)) {
+	r.mu.Lock()
+	r.onBudget = fn
+	r.mu.Unlock()
+}
+
+// SLOs returns the status of every key that has an SLO and was called
+// within its window, sorted by key.
+func (r *Registry) SLOs() []SLOStatus {
+	r.mu.RLock()
+	slos := r.slos
+	r.mu.RUnlock()
+
+	now := time.Now()
+	var out []SLOStatus
+	for _, rule := range slos {
+		rule.mu.Lock()
+		for key, s := range rule.states {
+			if st := rule.status(key, s, now); st.Calls > 0 {
+				out = append(out, st)
+			}
+		}
+		rule.mu.Unlock()
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+// sloFor returns the SLO rule of key, or nil. r.mu must be held.
+func (r *Registry) sloFor(key string) *sloRule {
+	for _, rule := range r.slos {
+		if ok, _ := path.Match(rule.pattern, key); ok {
+			return rule
+		}
+	}
+	return nil
+}
+
+// sloStatus returns the status of key, if it has an SLO.
+func (r *Registry) sloStatus(key string) (SLOStatus, bool) {
+	r.mu.RLock()
+	rule := r.sloFor(key)
+	r.mu.RUnlock()
+
+	if rule == nil {
+		return SLOStatus{}, false
+	}
+	rule.mu.Lock()
+	defer rule.mu.Unlock()
+	s := rule.states[key]
+	if s == nil {
+		s = &sloState{}
+	}
+	return rule.status(key, s, time.Now()), true
+}
+
+// isServerError reports whether err counts against an availability
+// objective.
+func isServerError(err error) bool {
+	switch CodeOf(err) {
+	case Unknown, Internal, Unavailable, DataLoss, DeadlineExceeded, Unimplemented:
+		return true
+	}
+	return false
+}
+
+// record adds a call of key to its window and returns its status if that
+// made its budget exhausted or recovered.
+func (rule *sloRule) record(key string, elapsed time.Duration, err error) (SLOStatus, bool) {
+	now := time.Now()
+	epoch := now.UnixNano() / rule.bucketWidth()
+
+	rule.mu.Lock()
+	defer rule.mu.Unlock()
+
+	s := rule.states[key]
+	if s == nil {
+		s = &sloState{}
+		rule.states[key] = s
+	}
+	b := &s.buckets[epoch%sloBuckets]
+	if b.epoch != epoch {
+		*b = sloBucket{epoch: epoch}
+	}
+	b.calls++
+	if err != nil && isServerError(err) {
+		b.failed++
+	}
+	if rule.slo.Latency > 0 && elapsed > rule.slo.Latency {
+		b.slow++
+	}
+
+	st := rule.status(key, s, now)
+	if st.Exhausted == s.exhausted {
+		return SLOStatus{}, false
+	}
+	s.exhausted = st.Exhausted
+	return st, true
+}
+
+func (rule *sloRule) bucketWidth() int64 {
+	return max(int64(rule.slo.Window/sloBuckets), 1)
+}
+
+// status sums the buckets of s in the window ending at now. rule.mu must
+// be held.
+func (rule *sloRule) status(key string, s *sloState, now time.Time) SLOStatus {
+	st := SLOStatus{Key: key, SLO: rule.slo, AvailabilityBudget: 1, LatencyBudget: 1}
+	epoch := now.UnixNano() / rule.bucketWidth()
+	for _, b := range s.buckets {
+		if b.epoch > epoch-sloBuckets && b.epoch <= epoch {
+			st.Calls += b.calls
+			st.Failed += b.failed
+			st.Slow += b.slow
+		}
+	}
+	if st.Calls == 0 {
+		return st
+	}
+
+	calls := float64(st.Calls)
+	if rule.slo.Availability > 0 {
+		st.AvailabilityBurnRate = float64(st.Failed) / calls / (1 - rule.slo.Availability)
+		st.AvailabilityBudget = 1 - st.AvailabilityBurnRate
+	}
+	if rule.slo.Latency > 0 {
+		st.LatencyBurnRate = float64(st.Slow) / calls / (1 - rule.slo.LatencyTarget)
+		st.LatencyBudget = 1 - st.LatencyBurnRate
+	}
+	st.Exhausted = st.Calls >= uint64(rule.slo.MinCalls) && (st.AvailabilityBudget <= 0 || st.LatencyBudget <= 0)
+	return st
+}
+
+// budgetChanged notifies the OnBudgetExhausted hook and the telemetry
+// exporters of a change of st.Exhausted.
+func (r *Registry) budgetChanged(ctx context.Context, st SLOStatus) {
+	r.mu.RLock()
+	fn := r.onBudget
+	r.mu.RUnlock()
+
+	if fn != nil {
+		fn(st)
+	}
+	r.emit(ctx, budgetEvent(st))
+}
+
+func budgetEvent(st SLOStatus) TelemetryEvent {
+	return TelemetryEvent{Name: EventBudgetExhausted, Key: st.Key, Attrs: []slog.Attr{
+		slog.Bool("exhausted", st.Exhausted),
+		slog.Float64("availability_burn_rate", st.AvailabilityBurnRate),
+		slog.Float64("latency_burn_rate", st.LatencyBurnRate),
+		slog.Uint64("calls", st.Calls),
+	}}
+}
@@ -0,0 +1,24 @@
+package irpc
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// registrationSource returns the file:line of the first caller outside
+// this package, i.e. the code that registered a handler.
+func registrationSource() string {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, irpcPkgPath+".") {
+			return fmt.Sprintf("%s:%d", f.File, f.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
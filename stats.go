@@ -0,0 +1,210 @@
+package irpc
+
+import (
+	"sync"
+	"time"
+)
+
+// KeyStats is a snapshot of the calls observed for a single key.
+//
+// Latency percentiles are estimated from a log-linear histogram and are
+// accurate to within a few percent.
+type KeyStats struct {
+	Calls  uint64        `json:"calls"`
+	Errors uint64        `json:"errors"`
+	Total  time.Duration `json:"total_ns"`
+	Min    time.Duration `json:"min_ns"`
+	Max    time.Duration `json:"max_ns"`
+	P50    time.Duration `json:"p50_ns"`
+	P95    time.Duration `json:"p95_ns"`
+	P99    time.Duration `json:"p99_ns"`
+
+	// SLAViolations is the number of calls that exceeded a latency SLA set
+	// with EnforceLatencySLA. They also count as Errors if they failed.
+	SLAViolations uint64 `json:"sla_violations,omitempty"`
+
+	// Async describes the key's async calls, if it had any.
+	Async *AsyncKeyStats `json:"async,omitempty"`
+	// SLO is the compliance of the key with its SLO, if it has one.
+	SLO *SLOStatus `json:"slo,omitempty"`
+}
+
+// Mean returns the average latency of the recorded calls.
+func (s KeyStats) Mean() time.Duration {
+	if s.Calls == 0 {
+		return 0
+	}
+	return s.Total / time.Duration(s.Calls)
+}
+
+type keyStats struct {
+	mu     sync.Mutex
+	calls  uint64
+	errors uint64
+	total  time.Duration
+	min    time.Duration
+	max    time.Duration
+	hist   histogram
+
+	slaViolations uint64
+}
+
+func (s *keyStats) record(d time.Duration, err error) {
+	s.mu.Lock()
+	s.calls++
+	if err != nil {
+		s.errors++
+	}
+	s.total += d
+	if s.calls == 1 || d < s.min {
+		s.min = d
+	}
+	if d > s.max {
+		s.max = d
+	}
+	s.hist.record(d)
+	s.mu.Unlock()
+}
+
+func (s *keyStats) snapshot() KeyStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return KeyStats{
+		Calls:  s.calls,
+		Errors: s.errors,
+		Total:  s.total,
+		Min:    s.min,
+		Max:    s.max,
+		P50:    s.hist.quantile(0.50),
+		P95:    s.hist.quantile(0.95),
+		P99:    s.hist.quantile(0.99),
+
+		SLAViolations: s.slaViolations,
+	}
+}
+
+type statsCollector struct {
+	mu   sync.RWMutex
+	keys map[string]*keyStats
+}
+
+func newStatsCollector() *statsCollector {
+	return &statsCollector{keys: make(map[string]*keyStats)}
+}
+
+func (c *statsCollector) get(key string) *keyStats {
+	c.mu.RLock()
+	s := c.keys[key]
+	c.mu.RUnlock()
+	if s != nil {
+		return s
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s = c.keys[key]; s == nil {
+		s = &keyStats{}
+		c.keys[key] = s
+	}
+	return s
+}
+
+func (c *statsCollector) snapshot() map[string]KeyStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	out := make(map[string]KeyStats, len(c.keys))
+	for key, s := range c.keys {
+		out[key] = s.snapshot()
+	}
+	return out
+}
+
+func (c *statsCollector) record(key string, d time.Duration, err error) {
+	c.get(key).record(d, err)
+}
+
+func (c *statsCollector) recordSLAViolation(key string) {
+	s := c.get(key)
+	s.mu.Lock()
+	s.slaViolations++
+	s.mu.Unlock()
+}
+
+// Stats returns a snapshot of the call statistics of every key that has
+// been called at least once, or that had async calls queued.
+func (r *Registry) Stats() map[string]KeyStats {
+	out := r.stats.snapshot()
+	for key, async := range r.asyncStats.snapshot() {
+		s := out[key]
+		s.Async = &async
+		out[key] = s
+	}
+	for key, s := range out {
+		if st, ok := r.sloStatus(key); ok {
+			s.SLO = &st
+			out[key] = s
+		}
+	}
+	return out
+}
+
+// StatsFor returns the call statistics of a single key.
+func (r *Registry) StatsFor(key string) (KeyStats, bool) {
+	r.stats.mu.RLock()
+	s := r.stats.keys[key]
+	r.stats.mu.RUnlock()
+
+	var out KeyStats
+	if s != nil {
+		out = s.snapshot()
+	}
+	async, ok := r.asyncStats.snapshotKey(key)
+	if ok {
+		out.Async = &async
+	}
+	if st, ok := r.sloStatus(key); ok {
+		out.SLO = &st
+	}
+	return out, s != nil || ok
+}
+
+// Quantile returns the estimated latency of key at quantile q, e.g. 0.999.
+func (r *Registry) Quantile(key string, q float64) time.Duration {
+	r.stats.mu.RLock()
+	s := r.stats.keys[key]
+	r.stats.mu.RUnlock()
+
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hist.quantile(q)
+}
+
+// quantile is Quantile, reporting false if fewer than min calls of key
+// were recorded.
+func (r *Registry) quantile(key string, q float64, min uint64) (time.Duration, bool) {
+	r.stats.mu.RLock()
+	s := r.stats.keys[key]
+	r.stats.mu.RUnlock()
+
+	if s == nil {
+		return 0, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.hist.total < min {
+		return 0, false
+	}
+	return s.hist.quantile(q), true
+}
+
+// ResetStats discards all recorded call statistics.
+func (r *Registry) ResetStats() {
+	r.stats.mu.Lock()
+	r.stats.keys = make(map[string]*keyStats)
+	r.stats.mu.Unlock()
+}
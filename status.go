@@ -0,0 +1,92 @@
+package irpc
+
+import (
+	"errors"
+	"time"
+)
+
+// State is the state of a Status, from best to worst: Serving, Degraded,
+// NotServing.
+type State string
+
+const (
+	// Serving means calls are served normally.
+	Serving State = "serving"
+	// Degraded means calls are served, but not all of them as usual, e.g.
+	// by maintenance responders or with a firing alert.
+	Degraded State = "degraded"
+	// NotServing means calls should not be sent, e.g. because a health
+	// check fails or the registry is shut down.
+	NotServing State = "not_serving"
+)
+
+func (s State) rank() int {
+	switch s {
+	case Serving:
+		return 0
+	case Degraded:
+		return 1
+	}
+	return 2
+}
+
+// Status is the health of a registry or of a part of it, in the same shape
+// wherever the registry reports health: health checks and readiness, alerts
+// and the admin API. Details holds what the reporter adds, e.g. the number
+// of stuck calls; its values must encode as JSON.
+type Status struct {
+	State     State          `json:"state"`
+	Message   string         `json:"message,omitempty"`
+	CheckedAt time.Time      `json:"checked_at"`
+	Details   map[string]any `json:"details,omitempty"`
+}
+
+// OK reports whether calls can be sent, i.e. whether s is not NotServing.
+func (s Status) OK() bool {
+	return s.State == Serving || s.State == Degraded
+}
+
+// worsen moves s to state if that is worse, with message. The message of
+// the first cause of a state is kept.
+func (s *Status) worsen(state State, message string) {
+	if state.rank() > s.State.rank() {
+		s.State, s.Message = state, message
+	} else if state.rank() == s.State.rank() && s.Message == "" {
+		s.Message = message
+	}
+}
+
+func (s *Status) setDetail(key string, v any) {
+	if s.Details == nil {
+		s.Details = make(map[string]any)
+	}
+	s.Details[key] = v
+}
+
+type degradedError struct{ err error }
+
+func (e degradedError) Error() string { return e.err.Error() }
+func (e degradedError) Unwrap() error { return e.err }
+
+// Degrade marks an error returned by a HealthCheck as leaving the service
+// Degraded rather than NotServing, e.g. when a cache it can do without is
+// down.
+//
+//	if err := cache.Ping(ctx); err != nil {
+//		return irpc.Degrade(err)
+//	}
+func Degrade(err error) error {
+	if err == nil {
+		return nil
+	}
+	return degradedError{err}
+}
+
+// checkState is the State a HealthCheck error puts its service in.
+func checkState(err error) State {
+	var d degradedError
+	if errors.As(err, &d) {
+		return Degraded
+	}
+	return NotServing
+}
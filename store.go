@@ -0,0 +1,286 @@
+package irpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store is the key/value storage shared by the durable features: wrap it
+// with NewDeferredStore, NewDeadLetterStore and NewIdempotencyStore to back
+// durable calls, dead letters and idempotency records with one backend.
+// Backends such as Redis or SQL implement Store once instead of the three
+// specialised interfaces, and can be checked with irpctest.TestStore.
+//
+// Implementations must be safe for concurrent use, must not return
+// expired entries, and must not retain or let callers modify the slices
+// they are given or return.
+type Store interface {
+	// Get returns the value of key, and false if it is absent or expired.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key, replacing any previous value. A ttl of
+	// zero keeps it until it is deleted.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes key. Deleting an absent key is not an error.
+	Delete(ctx context.Context, key string) error
+	// CompareAndSwap replaces the value of key with value, keeping its ttl,
+	// if its current value is old, and reports whether it did. A nil old
+	// means key must be absent; the new entry then never expires.
+	CompareAndSwap(ctx context.Context, key string, old, value []byte) (bool, error)
+	// List returns the entries whose key starts with prefix, sorted by
+	// key.
+	List(ctx context.Context, prefix string) ([]StoreEntry, error)
+}
+
+// StoreEntry is a key/value pair returned by Store.List.
+type StoreEntry struct {
+	Key   string
+	Value []byte
+}
+
+// Key prefixes of the durable features in a shared Store.
+const (
+	deferredPrefix    = "deferred/"
+	deadLetterPrefix  = "deadletter/"
+	idempotencyPrefix = "idempotency/"
+)
+
+// NewDeferredStore returns a DeferredStore, and DeferredLeaser, keeping
+// durable calls in s as JSON.
+func NewDeferredStore(s Store) DeferredStore {
+	return storeDeferred{s}
+}
+
+type storeDeferred struct {
+	s Store
+}
+
+func (d storeDeferred) Save(ctx context.Context, call DeferredCall) error {
+	data, err := json.Marshal(call)
+	if err != nil {
+		return err
+	}
+	return d.s.Set(ctx, deferredPrefix+call.ID, data, 0)
+}
+
+func (d storeDeferred) Delete(ctx context.Context, id string) error {
+	return d.s.Delete(ctx, deferredPrefix+id)
+}
+
+// List returns the stored calls, oldest first.
+func (d storeDeferred) List(ctx context.Context) ([]DeferredCall, error) {
+	entries, err := d.s.List(ctx, deferredPrefix)
+	if err != nil {
+		return nil, err
+	}
+	calls := make([]DeferredCall, 0, len(entries))
+	for _, e := range entries {
+		var call DeferredCall
+		if err := json.Unmarshal(e.Value, &call); err != nil {
+			return nil, err
+		}
+		calls = append(calls, call)
+	}
+	sort.SliceStable(calls, func(i, j int) bool {
+		return calls[i].CreatedAt.Before(calls[j].CreatedAt)
+	})
+	return calls, nil
+}
+
+func (d storeDeferred) Lease(ctx context.Context, id string, until time.Time) (bool, error) {
+	old, ok, err := d.s.Get(ctx, deferredPrefix+id)
+	if err != nil || !ok {
+		return false, err
+	}
+	var call DeferredCall
+	if err := json.Unmarshal(old, &call); err != nil {
+		return false, err
+	}
+	if call.VisibleAt.After(time.Now()) {
+		return false, nil
+	}
+
+	call.Deliveries++
+	call.VisibleAt = until
+	data, err := json.Marshal(call)
+	if err != nil {
+		return false, err
+	}
+	return d.s.CompareAndSwap(ctx, deferredPrefix+id, old, data)
+}
+
+// NewDeadLetterStore returns a DeadLetterStore keeping dead letters in s
+// as JSON. Their Request is not kept; Redrive decodes Payload instead.
+func NewDeadLetterStore(s Store) DeadLetterStore {
+	return storeDeadLetters{s}
+}
+
+type storeDeadLetters struct {
+	s Store
+}
+
+func (d storeDeadLetters) Add(ctx context.Context, letter DeadLetter) error {
+	data, err := json.Marshal(letter)
+	if err != nil {
+		return err
+	}
+	return d.s.Set(ctx, deadLetterPrefix+letter.ID, data, 0)
+}
+
+func (d storeDeadLetters) Delete(ctx context.Context, id string) error {
+	return d.s.Delete(ctx, deadLetterPrefix+id)
+}
+
+// List returns the stored dead letters, oldest first.
+func (d storeDeadLetters) List(ctx context.Context) ([]DeadLetter, error) {
+	entries, err := d.s.List(ctx, deadLetterPrefix)
+	if err != nil {
+		return nil, err
+	}
+	letters := make([]DeadLetter, 0, len(entries))
+	for _, e := range entries {
+		var l DeadLetter
+		if err := json.Unmarshal(e.Value, &l); err != nil {
+			return nil, err
+		}
+		letters = append(letters, l)
+	}
+	sort.SliceStable(letters, func(i, j int) bool {
+		return letters[i].CreatedAt.Before(letters[j].CreatedAt)
+	})
+	return letters, nil
+}
+
+// NewIdempotencyStore returns an IdempotencyStore keeping records in s as
+// JSON, until they expire. Their Response is not kept; it is decoded from
+// Payload into the response type of the key's contract.
+func NewIdempotencyStore(s Store) IdempotencyStore {
+	return storeIdempotency{s}
+}
+
+type storeIdempotency struct {
+	s Store
+}
+
+func (d storeIdempotency) Get(ctx context.Context, id string) (IdempotencyRecord, bool, error) {
+	data, ok, err := d.s.Get(ctx, idempotencyPrefix+id)
+	if err != nil || !ok {
+		return IdempotencyRecord{}, false, err
+	}
+	var rec IdempotencyRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return IdempotencyRecord{}, false, err
+	}
+	return rec, true, nil
+}
+
+func (d storeIdempotency) Put(ctx context.Context, record IdempotencyRecord) error {
+	var ttl time.Duration
+	if !record.ExpiresAt.IsZero() {
+		if ttl = time.Until(record.ExpiresAt); ttl <= 0 {
+			return nil
+		}
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return d.s.Set(ctx, idempotencyPrefix+record.ID, data, ttl)
+}
+
+// MemoryStore is an in-memory Store. It does not survive restarts and is
+// meant for tests and development.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]storedValue
+}
+
+type storedValue struct {
+	value   []byte
+	expires time.Time
+}
+
+func (v storedValue) expired(now time.Time) bool {
+	return !v.expires.IsZero() && !now.Before(v.expires)
+}
+
+func expiry(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]storedValue)}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.lookup(key)
+	if !ok {
+		return nil, false, nil
+	}
+	return bytes.Clone(v.value), true, nil
+}
+
+func (s *MemoryStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	s.entries[key] = storedValue{value: bytes.Clone(value), expires: expiry(ttl)}
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	delete(s.entries, key)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryStore) CompareAndSwap(ctx context.Context, key string, old, value []byte) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.lookup(key)
+	if ok != (old != nil) || (ok && !bytes.Equal(v.value, old)) {
+		return false, nil
+	}
+	s.entries[key] = storedValue{value: bytes.Clone(value), expires: v.expires}
+	return true, nil
+}
+
+func (s *MemoryStore) List(ctx context.Context, prefix string) ([]StoreEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var out []StoreEntry
+	for k := range s.entries {
+		if !strings.HasPrefix(k, prefix) {
+			continue
+		}
+		if v, ok := s.lookup(k); ok {
+			out = append(out, StoreEntry{Key: k, Value: bytes.Clone(v.value)})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out, nil
+}
+
+// lookup returns the live entry of key, dropping it if it expired. s.mu
+// must be held.
+func (s *MemoryStore) lookup(key string) (storedValue, bool) {
+	v, ok := s.entries[key]
+	if ok && v.expired(time.Now()) {
+		delete(s.entries, key)
+		return storedValue{}, false
+	}
+	return v, ok
+}
@@ -0,0 +1,24 @@
+package irpc_test
+
+import (
+	"testing"
+
+	"github.com/khunfloat/irpc"
+	"github.com/khunfloat/irpc/irpctest"
+)
+
+func TestMemoryStore(t *testing.T) {
+	irpctest.TestStore(t, func(t *testing.T) irpc.Store {
+		return irpc.NewMemoryStore()
+	})
+}
+
+func TestFileStore(t *testing.T) {
+	irpctest.TestStore(t, func(t *testing.T) irpc.Store {
+		s, err := irpc.NewFileStore(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	})
+}
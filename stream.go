@@ -0,0 +1,216 @@
+package irpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	readerType     = reflect.TypeOf((*io.Reader)(nil)).Elem()
+	readCloserType = reflect.TypeOf((*io.ReadCloser)(nil)).Elem()
+)
+
+// ErrStreamClosed is returned by reads from a stream that was closed,
+// unless it was closed because its call's context was done, in which case
+// the context's cause is returned.
+var ErrStreamClosed = errors.New("irpc: stream closed")
+
+// isStream reports whether a contract parameter or result of type t is an
+// io.Reader, io.ReadCloser or Blob managed by the registry.
+func isStream(t reflect.Type) bool {
+	return t == readerType || t == readCloserType || t == blobType || t == blobPtrType
+}
+
+// stream is the io.ReadCloser returned for contract results declared as
+// an io.Reader or io.ReadCloser. Closing it closes the handler's reader and
+// the request's, and it is closed when the caller's context is done or the
+// registry shuts down.
+type stream struct {
+	io.Reader
+	set     *streamSet
+	closers []io.Closer
+	stop    func() bool
+
+	once   sync.Once
+	closed atomic.Bool
+	cause  error
+	err    error
+}
+
+func (s *stream) Read(p []byte) (int, error) {
+	if s.closed.Load() {
+		return 0, s.cause
+	}
+	return s.Reader.Read(p)
+}
+
+// Close closes the stream. It may be called more than once.
+func (s *stream) Close() error {
+	s.close(ErrStreamClosed)
+	return s.err
+}
+
+func (s *stream) close(cause error) {
+	s.once.Do(func() {
+		s.cause = cause
+		s.closed.Store(true)
+		if s.stop != nil {
+			s.stop()
+		}
+		var errs []error
+		for _, c := range s.closers {
+			if err := c.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		s.err = errors.Join(errs...)
+		s.set.remove(s)
+	})
+}
+
+// streamSet holds the open streams of a registry and their keys.
+type streamSet struct {
+	mu      sync.Mutex
+	streams map[io.Closer]string
+}
+
+func (s *streamSet) add(key string, c io.Closer) {
+	s.mu.Lock()
+	if s.streams == nil {
+		s.streams = make(map[io.Closer]string)
+	}
+	s.streams[c] = key
+	s.mu.Unlock()
+}
+
+func (s *streamSet) remove(c io.Closer) {
+	s.mu.Lock()
+	delete(s.streams, c)
+	s.mu.Unlock()
+}
+
+// counts returns the number of open streams per key.
+func (s *streamSet) counts() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make(map[string]int)
+	for _, key := range s.streams {
+		out[key]++
+	}
+	return out
+}
+
+// closeAll closes every open stream, e.g. on Shutdown.
+func (s *streamSet) closeAll() error {
+	s.mu.Lock()
+	open := make([]io.Closer, 0, len(s.streams))
+	for c := range s.streams {
+		open = append(open, c)
+	}
+	s.mu.Unlock()
+
+	var errs []error
+	for _, c := range open {
+		if err := c.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// manageStreams takes over the request and response streams of a call to
+// key, including Blob bodies. A request stream is closed when the handler
+// returns, or together with the response stream if it returned one, since
+// that may still be reading it. A response stream is wrapped so that it is
+// closed when ctx is done.
+func (r *Registry) manageStreams(ctx context.Context, d *dispatch, req, res any, err error) any {
+	var reqCloser io.Closer
+	if d.streamReq {
+		reqCloser = closerOf(req)
+	}
+	if err == nil && d.streamRes {
+		switch v := res.(type) {
+		case Blob:
+			if v.Body != nil {
+				v.Body = r.newStream(ctx, d.key, v.Body, reqCloser)
+				return v
+			}
+		case *Blob:
+			if v != nil && v.Body != nil {
+				b := *v
+				b.Body = r.newStream(ctx, d.key, v.Body, reqCloser)
+				return &b
+			}
+		case io.Reader:
+			return r.newStream(ctx, d.key, v, reqCloser)
+		}
+	}
+	if reqCloser != nil {
+		reqCloser.Close()
+	}
+	return res
+}
+
+// newStream wraps reader in a stream of key that also closes reqCloser,
+// if not nil, and is closed when ctx is done.
+func (r *Registry) newStream(ctx context.Context, key string, reader io.Reader, reqCloser io.Closer) *stream {
+	s := &stream{Reader: reader, set: &r.streams}
+	if c, ok := reader.(io.Closer); ok {
+		s.closers = append(s.closers, c)
+	}
+	if reqCloser != nil && !sameValue(reqCloser, reader) {
+		s.closers = append(s.closers, reqCloser)
+	}
+	r.streams.add(key, s)
+	s.stop = context.AfterFunc(ctx, func() { s.close(context.Cause(ctx)) })
+	return s
+}
+
+// discardResults closes the response streams of up to n results still to
+// come on results, from calls whose outcome is no longer wanted. Their
+// streams are bound to the caller's context, which may outlive them.
+func discardResults(results <-chan Result, n int) {
+	if n == 0 {
+		return
+	}
+	go func() {
+		for range n {
+			res, ok := <-results
+			if !ok {
+				return
+			}
+			if c := closerOf(res.Res); c != nil {
+				c.Close()
+			}
+		}
+	}()
+}
+
+// closerOf returns the closer of a request stream or Blob, or nil.
+func closerOf(req any) io.Closer {
+	switch v := req.(type) {
+	case Blob:
+		if v.Body != nil {
+			return v.Body
+		}
+	case *Blob:
+		if v != nil && v.Body != nil {
+			return v.Body
+		}
+	case io.Closer:
+		return v
+	}
+	return nil
+}
+
+// sameValue reports whether a and b hold the same comparable value, e.g.
+// a handler returning the reader it was given.
+func sameValue(a, b any) bool {
+	t := reflect.TypeOf(a)
+	return t == reflect.TypeOf(b) && t.Comparable() && a == b
+}
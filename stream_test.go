@@ -0,0 +1,71 @@
+package irpc
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type downloadContract interface {
+	Get(ctx context.Context, name string) (io.Reader, error)
+}
+
+type downloads struct{}
+
+func (*downloads) Get(ctx context.Context, name string) (io.Reader, error) {
+	return io.NopCloser(strings.NewReader("contents of " + name)), nil
+}
+
+func TestResponseStreamOutlivesInternalContexts(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(r *Registry)
+	}{
+		{"timeout", func(r *Registry) {
+			if err := r.SetTimeout("DL.*", time.Minute); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"hedge", func(r *Registry) {
+			r.RegisterImpl("DL.Get", "mirror", func(ctx context.Context, req any) (any, error) {
+				return (&downloads{}).Get(ctx, req.(string))
+			})
+			r.SetHedge("DL.Get", Hedge{Delay: time.Hour})
+		}},
+		{"degradation", func(r *Registry) {
+			r.SetDegradation("DL.Get", Degradation{Timeout: time.Minute})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry(Config{})
+			r.RegisterContract("DL", (*downloadContract)(nil), &downloads{})
+			tt.setup(r)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			res, err := r.Call(ctx, "DL.Get", "report.csv")
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Closing a stream bound to a canceled context is asynchronous.
+			time.Sleep(10 * time.Millisecond)
+			data, err := io.ReadAll(res.(io.Reader))
+			if err != nil || string(data) != "contents of report.csv" {
+				t.Fatalf("ReadAll() = %q, %v, want the contents", data, err)
+			}
+
+			// The stream is still bound to the caller's context.
+			res, err = r.Call(ctx, "DL.Get", "report.csv")
+			if err != nil {
+				t.Fatal(err)
+			}
+			cancel()
+			waitFor(t, "the stream to close", func() bool { return r.streams.counts()["DL.Get"] == 0 })
+			if _, err := io.ReadAll(res.(io.Reader)); err == nil {
+				t.Error("read a stream whose caller's context was canceled")
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package irpc
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Subscription receives the updates pushed by a streaming handler, e.g. a
+// watch on a record. It ends when the handler returns, when it is closed,
+// when the context it was created with is done or on registry Shutdown.
+type Subscription struct {
+	id      string
+	key     string
+	started time.Time
+	updates chan any
+	done    chan struct{}
+	stream  *itemStream
+	set     *subscriptionSet
+	unbind  func() bool
+
+	delivered atomic.Uint64
+	once      sync.Once
+	err       error
+}
+
+// SubscriptionInfo describes an active subscription.
+type SubscriptionInfo struct {
+	ID        string    `json:"id"`
+	Key       string    `json:"key"`
+	Started   time.Time `json:"started"`
+	Delivered uint64    `json:"delivered"`
+}
+
+// SubscribeMethod calls key, a key registered with RegisterStream, with req
+// and delivers what its handler sends to the subscription's Updates:
+//
+//	sub, err := registry.SubscribeMethod(ctx, "Exam.Watch", WatchReq{Id: "EX-1"})
+//	if err != nil {
+//		return err
+//	}
+//	defer sub.Close()
+//	for update := range sub.Updates() {
+//		...
+//	}
+//	return sub.Err()
+func (r *Registry) SubscribeMethod(ctx context.Context, key string, req any) (*Subscription, error) {
+	res, err := r.Call(ctx, key, req)
+	if err != nil {
+		return nil, err
+	}
+	stream, ok := res.(*itemStream)
+	if !ok {
+		return nil, Errorf(FailedPrecondition, "%s is not a streaming method; register it with RegisterStream", key)
+	}
+
+	s := &Subscription{
+		id:      newID(),
+		key:     key,
+		started: time.Now(),
+		updates: make(chan any),
+		done:    make(chan struct{}),
+		stream:  stream,
+		set:     &r.subscriptions,
+	}
+	r.subscriptions.add(s)
+	s.unbind = context.AfterFunc(ctx, s.Close)
+	go s.run()
+	return s, nil
+}
+
+func (s *Subscription) run() {
+	defer s.set.remove(s)
+	defer close(s.updates)
+
+	ctx := context.Background()
+	for {
+		v, ok, err := s.stream.next(ctx)
+		if !ok {
+			s.err = err
+			return
+		}
+		select {
+		case s.updates <- v:
+			s.delivered.Add(1)
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// ID identifies the subscription in Subscriptions.
+func (s *Subscription) ID() string { return s.id }
+
+// Key returns the key the subscription calls.
+func (s *Subscription) Key() string { return s.key }
+
+// Updates returns the channel of updates. It is closed when the
+// subscription ends.
+func (s *Subscription) Updates() <-chan any { return s.updates }
+
+// Err returns the error the handler failed with, once Updates is closed.
+func (s *Subscription) Err() error { return s.err }
+
+// Close ends the subscription and stops its handler. It may be called more
+// than once.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		if s.unbind != nil {
+			s.unbind()
+		}
+		close(s.done)
+		s.stream.Close()
+	})
+}
+
+type subscriptionSet struct {
+	mu   sync.Mutex
+	subs map[string]*Subscription
+}
+
+func (s *subscriptionSet) add(sub *Subscription) {
+	s.mu.Lock()
+	if s.subs == nil {
+		s.subs = make(map[string]*Subscription)
+	}
+	s.subs[sub.id] = sub
+	s.mu.Unlock()
+}
+
+func (s *subscriptionSet) remove(sub *Subscription) {
+	s.mu.Lock()
+	delete(s.subs, sub.id)
+	s.mu.Unlock()
+}
+
+func (s *subscriptionSet) get(id string) *Subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.subs[id]
+}
+
+// Subscriptions returns the active subscriptions, oldest first.
+func (r *Registry) Subscriptions() []SubscriptionInfo {
+	r.subscriptions.mu.Lock()
+	out := make([]SubscriptionInfo, 0, len(r.subscriptions.subs))
+	for _, s := range r.subscriptions.subs {
+		out = append(out, SubscriptionInfo{ID: s.id, Key: s.key, Started: s.started, Delivered: s.delivered.Load()})
+	}
+	r.subscriptions.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Started.Before(out[j].Started)
+	})
+	return out
+}
+
+// CloseSubscription closes the subscription with the given ID and reports
+// whether it was active.
+func (r *Registry) CloseSubscription(id string) bool {
+	s := r.subscriptions.get(id)
+	if s == nil {
+		return false
+	}
+	s.Close()
+	return true
+}
@@ -0,0 +1,124 @@
+package irpc
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Tag describes the semantics of a method so that policies can be applied
+// by meaning instead of by hand-maintained key lists.
+type Tag string
+
+const (
+	// Idempotent methods can safely be invoked more than once with the
+	// same request, e.g. retried.
+	Idempotent Tag = "idempotent"
+	// ReadOnly methods do not change any state, so their results may be
+	// cached.
+	ReadOnly Tag = "read-only"
+	// SideEffecting methods change state and must not be repeated.
+	SideEffecting Tag = "side-effecting"
+	// Paginated methods take a PageRequest and return a PageResponse.
+	// RegisterContract adds it to the methods that do.
+	Paginated Tag = "paginated"
+)
+
+// RegisterOption configures RegisterContract and RegisterContractImpl.
+type RegisterOption func(*registerOptions)
+
+type registerOptions struct {
+	tags         map[string][]Tag
+	subContracts []reflect.Type
+	noContext    bool
+	info         *ServiceInfo
+	slos         map[string]SLO
+}
+
+func newRegisterOptions(opts []RegisterOption) *registerOptions {
+	o := &registerOptions{tags: make(map[string][]Tag)}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithMethodTags tags a method of the contract being registered.
+//
+//	registry.RegisterContract("Exam", (*ExamContract)(nil), impl,
+//		irpc.WithMethodTags("FindExamById", irpc.ReadOnly, irpc.Idempotent))
+func WithMethodTags(method string, tags ...Tag) RegisterOption {
+	return func(o *registerOptions) {
+		o.tags[method] = append(o.tags[method], tags...)
+	}
+}
+
+// Tag attaches tags to key.
+func (r *Registry) Tag(key string, tags ...Tag) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e := r.ensureEntry(key)
+	merged := append([]Tag(nil), e.tags...)
+	for _, t := range tags {
+		if !hasTag(merged, t) {
+			merged = append(merged, t)
+		}
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i] < merged[j] })
+	e.tags = merged
+	r.chainGen++
+}
+
+// Tags returns the tags of key, sorted.
+func (r *Registry) Tags(key string) []Tag {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if e := r.entries[key]; e != nil {
+		return append([]Tag(nil), e.tags...)
+	}
+	return nil
+}
+
+// HasTag reports whether key carries tag.
+func (r *Registry) HasTag(key string, tag Tag) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	e := r.entries[key]
+	return e != nil && hasTag(e.tags, tag)
+}
+
+// KeysWithTag returns the registered keys carrying tag, sorted.
+func (r *Registry) KeysWithTag(tag Tag) []string {
+	r.mu.RLock()
+	var keys []string
+	for key, e := range r.entries {
+		if len(e.impls) > 0 && hasTag(e.tags, tag) {
+			keys = append(keys, key)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Strings(keys)
+	return keys
+}
+
+func hasTag(tags []Tag, tag Tag) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowNoContext lets the contract being registered have methods that do
+// not take a context.Context first, as legacy services often do. Such
+// methods are called without the context of the call, so deadlines and
+// cancellation do not reach them; Schema flags them with NoContext.
+func AllowNoContext() RegisterOption {
+	return func(o *registerOptions) {
+		o.noContext = true
+	}
+}
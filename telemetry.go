@@ -0,0 +1,198 @@
+package irpc
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// TelemetryExporter receives all the telemetry of a registry, so that an
+// environment with its own pipeline implements one adapter instead of
+// wiring tracing, metrics and access logs separately. Embed
+// NopTelemetryExporter to implement only some of it. Methods run on the
+// goroutine of the call or event and must be safe for concurrent use.
+type TelemetryExporter interface {
+	// StartSpan starts a span for a call to key. The call runs with the
+	// returned ctx, and end is called with its error once it returns.
+	StartSpan(ctx context.Context, key string) (_ context.Context, end func(error))
+	// RecordMetric records a measurement.
+	RecordMetric(ctx context.Context, m Metric)
+	// Log records a log entry.
+	Log(ctx context.Context, level slog.Level, msg string, attrs []slog.Attr)
+	// Event records something that happened outside the calls themselves,
+	// e.g. a dead letter.
+	Event(ctx context.Context, ev TelemetryEvent)
+}
+
+// MetricKind is the kind of a Metric.
+type MetricKind int
+
+const (
+	// MetricCounter values are increments of a counter.
+	MetricCounter MetricKind = iota
+	// MetricHistogram values are observations of a distribution.
+	MetricHistogram
+)
+
+// Metric is one measurement. Calls record the metrics of
+// irpcotel.Metrics, with the same names, units and attributes:
+//
+//   - rpc.server.duration  histogram of call latency in milliseconds
+//   - rpc.server.requests  counter of calls
+//   - rpc.server.errors    counter of calls that returned an error
+type Metric struct {
+	Name  string
+	Kind  MetricKind
+	Unit  string
+	Value float64
+	Attrs []slog.Attr
+}
+
+// Names of the TelemetryEvents of a registry.
+const (
+	// EventDeadLetter is emitted when an async call becomes a dead letter.
+	EventDeadLetter = "irpc.dead_letter"
+	// EventPanic is emitted when a handler panics, before the panic is
+	// recovered or, under PanicCrash, propagated.
+	EventPanic = "irpc.panic"
+	// EventRestartPanic is emitted when the Restart of a PanicPolicy
+	// panics. Its Key is the service.
+	EventRestartPanic = "irpc.restart_panic"
+	// EventSettingsChange is emitted when the settings change.
+	EventSettingsChange = "irpc.settings_change"
+	// EventBudgetExhausted is emitted when a key exhausts the error budget
+	// of its SLO, and when the budget recovers.
+	EventBudgetExhausted = "irpc.budget_exhausted"
+	// EventSLAViolation is emitted when a call exceeds a latency SLA set
+	// with EnforceLatencySLA.
+	EventSLAViolation = "irpc.sla_violation"
+	// EventRedispatchError is emitted when a Redispatch of RunDeferred
+	// fails. The calls it did not queue are retried at the next interval.
+	EventRedispatchError = "irpc.redispatch_error"
+)
+
+// TelemetryEvent is something that happened in a registry outside the
+// calls themselves. Key is the key concerned, if any.
+type TelemetryEvent struct {
+	Name  string
+	Time  time.Time
+	Key   string
+	Attrs []slog.Attr
+}
+
+// NopTelemetryExporter is a TelemetryExporter that drops everything.
+type NopTelemetryExporter struct{}
+
+func (NopTelemetryExporter) StartSpan(ctx context.Context, key string) (context.Context, func(error)) {
+	return ctx, func(error) {}
+}
+
+func (NopTelemetryExporter) RecordMetric(context.Context, Metric) {}
+
+func (NopTelemetryExporter) Log(context.Context, slog.Level, string, []slog.Attr) {}
+
+func (NopTelemetryExporter) Event(context.Context, TelemetryEvent) {}
+
+// ExportTelemetry sends the telemetry of the registry to e: the spans,
+// metrics and access logs of Telemetry, added with Use, and the events of
+// the registry. Several exporters can be added.
+//
+//	registry.ExportTelemetry(pipeline)
+func (r *Registry) ExportTelemetry(e TelemetryExporter) {
+	r.mu.Lock()
+	r.telemetry = append(r.telemetry, e)
+	r.middleware = append(r.middleware, scopedMiddleware{mw: Telemetry(e)})
+	r.chainGen++
+	r.mu.Unlock()
+}
+
+// Telemetry returns middleware reporting every call it wraps to e: a
+// span, the metrics described on Metric, and the access log entry of
+// NewSlogAccessLogger. Use it with UseFor to limit telemetry to some keys;
+// ExportTelemetry adds it for all of them, together with the events.
+func Telemetry(e TelemetryExporter) Middleware {
+	return func(key string, next HandlerFunc) HandlerFunc {
+		service, method := SplitKey(key)
+		attrs := []slog.Attr{
+			slog.String("rpc.system", "irpc"),
+			slog.String("rpc.service", service),
+			slog.String("rpc.method", method),
+		}
+
+		return func(ctx context.Context, req any) (res any, err error) {
+			start := time.Now()
+			ctx, end := e.StartSpan(ctx, key)
+			panicked := true
+			defer func() {
+				if panicked {
+					// The registry recovers the panic, if its policy says
+					// so, outside the middleware chain.
+					err = &Error{Code: Internal, Key: key, Message: key + ": panic"}
+				}
+				exportCall(ctx, e, key, attrs, start, req, res, err, end)
+			}()
+			res, err = next(ctx, req)
+			panicked = false
+			return res, err
+		}
+	}
+}
+
+func exportCall(ctx context.Context, e TelemetryExporter, key string, attrs []slog.Attr, start time.Time, req, res any, err error, end func(error)) {
+	elapsed := time.Since(start)
+	end(err)
+
+	e.RecordMetric(ctx, Metric{Name: "rpc.server.duration", Kind: MetricHistogram, Unit: "ms",
+		Value: float64(elapsed) / float64(time.Millisecond), Attrs: attrs})
+	e.RecordMetric(ctx, Metric{Name: "rpc.server.requests", Kind: MetricCounter, Unit: "{call}", Value: 1, Attrs: attrs})
+	if err != nil {
+		e.RecordMetric(ctx, Metric{Name: "rpc.server.errors", Kind: MetricCounter, Unit: "{call}", Value: 1, Attrs: attrs})
+	}
+
+	rec := newAccessRecord(ctx, key, start, elapsed, req, res, err)
+	e.Log(ctx, rec.Level(), "irpc: call", rec.Attrs())
+}
+
+// emit sends ev to the exporters added with ExportTelemetry.
+func (r *Registry) emit(ctx context.Context, ev TelemetryEvent) {
+	r.mu.RLock()
+	exporters := r.telemetry
+	r.mu.RUnlock()
+
+	if len(exporters) == 0 {
+		return
+	}
+	ev.Time = time.Now()
+	for _, e := range exporters {
+		e.Event(ctx, ev)
+	}
+}
+
+func deadLetterEvent(l DeadLetter) TelemetryEvent {
+	return TelemetryEvent{Name: EventDeadLetter, Key: l.Key, Attrs: []slog.Attr{
+		slog.String("id", l.ID),
+		slog.Int("attempts", len(l.Attempts)),
+		slog.String("error", l.LastError()),
+	}}
+}
+
+func panicEvent(key string, v any, recovered bool) TelemetryEvent {
+	return TelemetryEvent{Name: EventPanic, Key: key, Attrs: []slog.Attr{
+		slog.String("value", fmt.Sprint(v)),
+		slog.Bool("recovered", recovered),
+	}}
+}
+
+func restartPanicEvent(service string, v any) TelemetryEvent {
+	return TelemetryEvent{Name: EventRestartPanic, Key: service, Attrs: []slog.Attr{
+		slog.String("value", fmt.Sprint(v)),
+	}}
+}
+
+func settingsChangeEvent(c SettingsChange) TelemetryEvent {
+	return TelemetryEvent{Name: EventSettingsChange, Attrs: []slog.Attr{
+		slog.String("by", c.By),
+		slog.Any("diff", c.Diff),
+	}}
+}
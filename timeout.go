@@ -0,0 +1,71 @@
+package irpc
+
+import (
+	"path"
+	"time"
+)
+
+type scopedTimeout struct {
+	pattern string
+	timeout time.Duration
+}
+
+// SetTimeout bounds calls to keys matching pattern, in the syntax of
+// path.Match, to d in addition to the caller's deadline. The first
+// matching pattern applies, and a handler that honours its ctx fails with
+// DeadlineExceeded once d has passed. Setting a pattern again changes its
+// timeout in place; d <= 0 removes it.
+//
+//	registry.SetTimeout("Billing.*", 2*time.Second)
+func (r *Registry) SetTimeout(pattern string, d time.Duration) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.setTimeout(pattern, d)
+	return nil
+}
+
+// setTimeout is SetTimeout with r.mu held.
+func (r *Registry) setTimeout(pattern string, d time.Duration) {
+	timeouts := make([]scopedTimeout, 0, len(r.timeouts)+1)
+	for _, t := range r.timeouts {
+		if t.pattern != pattern {
+			timeouts = append(timeouts, t)
+			continue
+		}
+		if d > 0 {
+			timeouts = append(timeouts, scopedTimeout{pattern: pattern, timeout: d})
+		}
+		d = 0
+	}
+	if d > 0 {
+		timeouts = append(timeouts, scopedTimeout{pattern: pattern, timeout: d})
+	}
+	r.timeouts = timeouts
+}
+
+// Timeouts returns the timeouts set with SetTimeout, by pattern.
+func (r *Registry) Timeouts() map[string]time.Duration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make(map[string]time.Duration, len(r.timeouts))
+	for _, t := range r.timeouts {
+		out[t.pattern] = t.timeout
+	}
+	return out
+}
+
+// timeout returns the timeout of key, or 0. r.mu must be held.
+func (r *Registry) timeout(key string) time.Duration {
+	for _, t := range r.timeouts {
+		if ok, _ := path.Match(t.pattern, key); ok {
+			return t.timeout
+		}
+	}
+	return 0
+}
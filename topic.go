@@ -0,0 +1,327 @@
+package irpc
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Topic is a typed channel of the registry's in-process event bus. Every
+// subscriber of a topic receives every event published to it, so modules
+// can react to each other's state changes without calling each other.
+//
+//	var ExamPublished = irpc.NewTopic[ExamPublishedEvent](registry, "exam.published")
+//
+//	ExamPublished.Subscribe(func(ctx context.Context, ev ExamPublishedEvent) error {
+//		return notifyStudents(ctx, ev.ExamId)
+//	})
+//	err := ExamPublished.Publish(ctx, ExamPublishedEvent{ExamId: "EX-1"})
+type Topic[T any] struct {
+	state *topicState
+}
+
+type topicState struct {
+	name      string
+	typ       reflect.Type
+	mu        sync.Mutex
+	subs      []*topicSub // copy-on-write
+	published atomic.Uint64
+	dropped   atomic.Uint64
+	closed    bool
+
+	replay       int
+	replayWindow time.Duration
+	retained     []retainedEvent
+
+	delivery    Delivery
+	deliverySet bool
+	queueSize   int
+	policy      SlowConsumerPolicy
+	onError     func(topic string, ev any, err error)
+	global      *eventQueue
+}
+
+// topicSub is a subscriber. While retained events are replayed to it
+// synchronously, live events are queued so that it sees them in order.
+// Subscribers of queued deliveries have their own queue instead.
+type topicSub struct {
+	fn func(ctx context.Context, ev any) error
+
+	mu        sync.Mutex
+	replaying bool
+	queued    []queuedEvent
+
+	queue   *eventQueue
+	workers int
+	once    sync.Once
+}
+
+type retainedEvent struct {
+	ev       any
+	at       time.Time
+	metadata Metadata
+}
+
+type queuedEvent struct {
+	ctx context.Context
+	ev  any
+	// subs are the subscribers of a DeliverGlobalOrder event when it
+	// was published.
+	subs []*topicSub
+}
+
+// TopicOption configures a Topic. Options apply to the topic whichever
+// NewTopic call passes them.
+type TopicOption func(*topicState)
+
+// WithReplay makes the topic retain its last n events and deliver them to
+// every new subscriber when it subscribes, so components initialized late
+// still observe recent state changes.
+func WithReplay(n int) TopicOption {
+	return func(s *topicState) {
+		s.replay = max(s.replay, n)
+	}
+}
+
+// WithReplayWindow makes the topic retain the events of the last d, like
+// WithReplay. Combined with WithReplay, events must satisfy both limits.
+func WithReplayWindow(d time.Duration) TopicOption {
+	return func(s *topicState) {
+		s.replayWindow = max(s.replayWindow, d)
+	}
+}
+
+// TopicInfo describes a topic of the event bus.
+type TopicInfo struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Subscribers int    `json:"subscribers"`
+	Published   uint64 `json:"published"`
+	Retained    int    `json:"retained"`
+	Delivery    string `json:"delivery"`
+	// Dropped counts the events dropped for slow consumers.
+	Dropped uint64 `json:"dropped"`
+}
+
+// NewTopic returns the topic called name, whose events are T. It panics if
+// the topic already exists with another event type, so two modules cannot
+// disagree about what a topic carries. When several modules declare a
+// topic with replay options, the largest limits apply.
+func NewTopic[T any](r *Registry, name string, opts ...TopicOption) *Topic[T] {
+	t := reflect.TypeFor[T]()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.topics[name]
+	if ok && s.typ != t {
+		panic(fmt.Sprintf("irpc: topic %s carries %s, not %s", name, s.typ, t))
+	}
+	if !ok {
+		if r.topics == nil {
+			r.topics = make(map[string]*topicState)
+		}
+		s = &topicState{name: name, typ: t, queueSize: DefaultQueueSize}
+		r.topics[name] = s
+		r.onShutdown(s.shutdown)
+	}
+
+	s.configure(opts)
+	return &Topic[T]{state: s}
+}
+
+// Name returns the name of the topic.
+func (t *Topic[T]) Name() string {
+	return t.state.name
+}
+
+// Subscribe calls fn with every event published to the topic from now on,
+// until the returned func is called. Events retained for replay are
+// delivered first, with the metadata they were published with; with
+// DeliverSync and DeliverGlobalOrder that happens before Subscribe
+// returns. Their errors are dropped, as are those of events published
+// meanwhile, which are delivered after them.
+func (t *Topic[T]) Subscribe(fn func(ctx context.Context, ev T) error, opts ...SubscribeOption) (unsubscribe func()) {
+	sub := &topicSub{fn: func(ctx context.Context, ev any) error {
+		return fn(ctx, ev.(T))
+	}}
+	t.state.add(sub, opts)
+	return func() {
+		t.state.remove(sub)
+		sub.stop(false)
+	}
+}
+
+// Publish delivers ev to every subscriber according to the topic's
+// Delivery. With DeliverSync, the default, it calls the subscribers in
+// subscription order on the calling goroutine; subscribers that fail do
+// not stop the others, and their errors are returned in a *MultiError.
+// Otherwise it only queues ev, blocking while a queue with SlowBlock is
+// full, and failures go to the OnSubscriberError handler.
+func (t *Topic[T]) Publish(ctx context.Context, ev T) error {
+	return t.state.publish(ctx, ev)
+}
+
+func (s *topicState) add(sub *topicSub, opts []SubscribeOption) {
+	s.mu.Lock()
+	replay := s.prune(time.Now())
+	if s.delivery.queued() {
+		q := subscribeOptions{queueSize: s.queueSize, policy: s.policy, workers: 1}
+		if s.delivery == DeliverConcurrent {
+			q.workers = runtime.GOMAXPROCS(0)
+		}
+		for _, opt := range opts {
+			opt(&q)
+		}
+		sub.queue = newEventQueue(q.queueSize, q.policy, &s.dropped)
+		sub.workers = q.workers
+	} else {
+		sub.replaying = len(replay) > 0
+	}
+	s.subs = append(s.subs[:len(s.subs):len(s.subs)], sub)
+	s.mu.Unlock()
+
+	if sub.queue != nil {
+		go s.serve(sub, replay)
+		return
+	}
+	if !sub.replaying {
+		return
+	}
+	for _, r := range replay {
+		_ = sub.fn(WithMetadata(context.Background(), r.metadata), r.ev)
+	}
+	for {
+		sub.mu.Lock()
+		queued := sub.queued
+		sub.queued = nil
+		if len(queued) == 0 {
+			sub.replaying = false
+		}
+		sub.mu.Unlock()
+
+		if len(queued) == 0 {
+			return
+		}
+		for _, q := range queued {
+			_ = sub.fn(q.ctx, q.ev)
+		}
+	}
+}
+
+// deliver calls sub with ev, or queues ev if sub is still replaying.
+func (sub *topicSub) deliver(ctx context.Context, ev any) error {
+	sub.mu.Lock()
+	if sub.replaying {
+		sub.queued = append(sub.queued, queuedEvent{ctx: context.WithoutCancel(ctx), ev: ev})
+		sub.mu.Unlock()
+		return nil
+	}
+	sub.mu.Unlock()
+	return sub.fn(ctx, ev)
+}
+
+// prune drops the retained events past the replay limits and returns the
+// remaining ones. The caller must hold s.mu.
+func (s *topicState) prune(now time.Time) []retainedEvent {
+	if s.replay > 0 && len(s.retained) > s.replay {
+		s.retained = s.retained[len(s.retained)-s.replay:]
+	}
+	if s.replayWindow > 0 {
+		i := 0
+		for i < len(s.retained) && now.Sub(s.retained[i].at) > s.replayWindow {
+			i++
+		}
+		s.retained = s.retained[i:]
+	}
+	return s.retained
+}
+
+func (s *topicState) remove(sub *topicSub) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, other := range s.subs {
+		if other == sub {
+			next := make([]*topicSub, 0, len(s.subs)-1)
+			s.subs = append(append(next, s.subs[:i]...), s.subs[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *topicState) subscribers() []*topicSub {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.subs
+}
+
+func (s *topicState) publish(ctx context.Context, ev any) error {
+	s.published.Add(1)
+
+	s.mu.Lock()
+	subs := s.subs
+	if s.replay > 0 || s.replayWindow > 0 {
+		now := time.Now()
+		// Appending to a pruned slice would keep its dropped prefix alive.
+		retained := append([]retainedEvent(nil), s.prune(now)...)
+		s.retained = append(retained, retainedEvent{ev: ev, at: now, metadata: MetadataFromContext(ctx)})
+		s.prune(now)
+	}
+	delivery, global, closed := s.delivery, s.global, s.closed
+	s.mu.Unlock()
+
+	if closed && delivery.queued() {
+		return Errorf(Unavailable, "topic %s is shut down", s.name)
+	}
+	switch {
+	case delivery == DeliverGlobalOrder:
+		_, err := global.push(ctx, queuedEvent{ctx: context.WithoutCancel(ctx), ev: ev, subs: subs})
+		return err
+	case delivery.queued():
+		return s.enqueue(ctx, subs, ev)
+	}
+
+	var failed []CallError
+	for i, sub := range subs {
+		if err := sub.deliver(ctx, ev); err != nil {
+			failed = append(failed, CallError{Index: i, Key: s.name, Err: err})
+		}
+	}
+	if len(failed) > 0 {
+		return &MultiError{Total: len(subs), Errors: failed}
+	}
+	return nil
+}
+
+// Topics returns the topics of the event bus, sorted by name.
+func (r *Registry) Topics() []TopicInfo {
+	r.mu.RLock()
+	states := make([]*topicState, 0, len(r.topics))
+	for _, s := range r.topics {
+		states = append(states, s)
+	}
+	r.mu.RUnlock()
+
+	out := make([]TopicInfo, 0, len(states))
+	for _, s := range states {
+		s.mu.Lock()
+		out = append(out, TopicInfo{
+			Name:        s.name,
+			Type:        s.typ.String(),
+			Subscribers: len(s.subs),
+			Published:   s.published.Load(),
+			Retained:    len(s.prune(time.Now())),
+			Delivery:    s.delivery.String(),
+			Dropped:     s.dropped.Load(),
+		})
+		s.mu.Unlock()
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
@@ -0,0 +1,66 @@
+package irpc
+
+import (
+	"context"
+	"sync"
+)
+
+// Trailer collects the metadata a handler attaches to its response with
+// SetTrailer. It is read by the caller once Call returns.
+type Trailer struct {
+	mu    sync.Mutex
+	md    Metadata
+	depth int
+}
+
+type trailerKey struct{}
+
+// WithTrailer returns a context that collects the trailers set by the
+// handler of the next Call made with it. Trailers set by handlers further
+// down the call chain are not collected.
+func WithTrailer(ctx context.Context) (context.Context, *Trailer) {
+	t := &Trailer{depth: len(ChainFromContext(ctx))}
+	return context.WithValue(ctx, trailerKey{}, t), t
+}
+
+// SetTrailer attaches a response metadata entry, e.g. a cache status or a
+// pagination cursor, to the call being served by ctx. It is a no-op if the
+// caller did not ask for trailers.
+func SetTrailer(ctx context.Context, key, value string) {
+	setTrailer(ctx, 1, key, value)
+}
+
+// markTrailer sets a trailer entry from the caller's side of a Call, i.e.
+// with the context the caller passed to it.
+func markTrailer(ctx context.Context, key, value string) {
+	setTrailer(ctx, 0, key, value)
+}
+
+func setTrailer(ctx context.Context, depth int, key, value string) {
+	t, _ := ctx.Value(trailerKey{}).(*Trailer)
+	if t == nil || len(ChainFromContext(ctx)) != t.depth+depth {
+		return
+	}
+
+	t.mu.Lock()
+	if t.md == nil {
+		t.md = make(Metadata)
+	}
+	t.md[key] = value
+	t.mu.Unlock()
+}
+
+// Get returns a single trailer entry.
+func (t *Trailer) Get(key string) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	v, ok := t.md[key]
+	return v, ok
+}
+
+// Metadata returns a copy of every trailer entry.
+func (t *Trailer) Metadata() Metadata {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.md.Copy()
+}
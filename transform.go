@@ -0,0 +1,44 @@
+package irpc
+
+import (
+	"context"
+	"path"
+)
+
+// Transformer rewrites the response of a successful call, e.g. to filter
+// fields by the caller's permissions or to inject computed fields. It must
+// not modify res in place if res may be shared, e.g. when cached.
+type Transformer func(ctx context.Context, key string, req, res any) (any, error)
+
+type scopedTransformer struct {
+	pattern string
+	fn      Transformer
+}
+
+// Transform registers fn to rewrite the responses of keys matching
+// pattern, using path.Match syntax. Transformers run after the handler and
+// all middleware, in registration order, so they also apply to cached
+// responses.
+func (r *Registry) Transform(pattern string, fn Transformer) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.transformers = append(r.transformers, scopedTransformer{pattern: pattern, fn: fn})
+	r.mu.Unlock()
+	return nil
+}
+
+func transform(ctx context.Context, transformers []scopedTransformer, key string, req, res any) (any, error) {
+	for _, t := range transformers {
+		if ok, _ := path.Match(t.pattern, key); !ok {
+			continue
+		}
+		var err error
+		if res, err = t.fn(ctx, key, req, res); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
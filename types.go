@@ -0,0 +1,81 @@
+package irpc
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sync"
+)
+
+// RequestTypeKey is the metadata key naming the registered type of a
+// serialized request, for keys whose contract does not declare one.
+const RequestTypeKey = "x-irpc-request-type"
+
+var types = struct {
+	sync.RWMutex
+	byName map[string]reflect.Type
+}{byName: make(map[string]reflect.Type)}
+
+// RegisterType registers T so that serialized requests of type T can be
+// decoded into a T, e.g. by bridges, durable calls and CallJSON, even for
+// keys registered with a plain HandlerFunc. Request types of contracts are
+// captured automatically by RegisterContract. It returns the name T is
+// registered under.
+//
+// Like gob.Register, it is typically called from an init function.
+func RegisterType[T any]() string {
+	t := reflect.TypeFor[T]()
+	name := typeName(t)
+
+	types.Lock()
+	types.byName[name] = t
+	types.Unlock()
+	return name
+}
+
+// TypeByName returns the type registered under name with RegisterType.
+func TypeByName(name string) (reflect.Type, bool) {
+	types.RLock()
+	defer types.RUnlock()
+	t, ok := types.byName[name]
+	return t, ok
+}
+
+func typeName(t reflect.Type) string {
+	if t.Name() != "" && t.PkgPath() != "" {
+		return t.PkgPath() + "." + t.Name()
+	}
+	return t.String()
+}
+
+// registeredName returns the registered name of the type of v, if any.
+func registeredName(v any) (string, bool) {
+	if v == nil {
+		return "", false
+	}
+	name := typeName(reflect.TypeOf(v))
+	_, ok := TypeByName(name)
+	return name, ok
+}
+
+// CallJSON decodes data as JSON into the request type of key and calls
+// it. The type is the one declared by the key's contract, or else the
+// registered type named by the RequestTypeKey metadata of ctx; keys with
+// neither get the raw json.RawMessage.
+func (r *Registry) CallJSON(ctx context.Context, key string, data []byte) (any, error) {
+	t := r.requestType(key)
+	if t == nil {
+		if name, ok := MetadataValue(ctx, RequestTypeKey); ok {
+			t, _ = TypeByName(name)
+		}
+	}
+	if t == nil {
+		return r.Call(ctx, key, json.RawMessage(data))
+	}
+
+	v := reflect.New(t)
+	if err := json.Unmarshal(data, v.Interface()); err != nil {
+		return nil, &Error{Code: InvalidArgument, Key: key, Message: "decode request of " + key, Err: err}
+	}
+	return r.Call(ctx, key, v.Elem().Interface())
+}
@@ -0,0 +1,47 @@
+package irpc
+
+import "context"
+
+// A unit of work, such as a *sql.Tx, can be shared by the methods taking
+// part in one business operation. It travels in the context, but only
+// reaches the handlers of keys that opted in with AcceptUnitOfWork: for
+// every other key, and for every async boundary (CallAsync, Notify,
+// durable and scheduled calls), it is stripped so that a transaction can
+// never be used after its owner committed it or from an unrelated module.
+
+type uowKey struct{}
+
+type uowValue struct {
+	uow any
+}
+
+// WithUnitOfWork returns a copy of ctx carrying uow.
+func WithUnitOfWork(ctx context.Context, uow any) context.Context {
+	return context.WithValue(ctx, uowKey{}, uowValue{uow})
+}
+
+// UnitOfWork returns the unit of work of type T carried by ctx.
+//
+//	tx, ok := irpc.UnitOfWork[*sql.Tx](ctx)
+func UnitOfWork[T any](ctx context.Context) (T, bool) {
+	v, _ := ctx.Value(uowKey{}).(uowValue)
+	uow, ok := v.uow.(T)
+	return uow, ok
+}
+
+// AcceptUnitOfWork lets the handlers of keys see the unit of work carried
+// by the caller's context.
+func (r *Registry) AcceptUnitOfWork(keys ...string) {
+	r.mu.Lock()
+	for _, key := range keys {
+		r.ensureEntry(key).uow = true
+	}
+	r.mu.Unlock()
+}
+
+func withoutUnitOfWork(ctx context.Context) context.Context {
+	if v, ok := ctx.Value(uowKey{}).(uowValue); !ok || v.uow == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, uowKey{}, uowValue{})
+}
@@ -0,0 +1,230 @@
+package irpc
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Converter migrates one call between two versions of a contract method:
+// Request upgrades a request of the older version, Response downgrades a
+// response of the newer one. A nil func passes the value through.
+type Converter struct {
+	Request  func(req any) (any, error)
+	Response func(res any) (any, error)
+}
+
+type versionStep struct {
+	to   string
+	conv Converter
+}
+
+// VersionedKey returns the key calling version of a contract method, e.g.
+// "Exam.FindExamById@v1".
+func VersionedKey(key, version string) string {
+	return key + "@" + version
+}
+
+// RegisterConverter registers the conversion of key from version from to
+// version to. Converters chain, so a v1 call can be served by v3 through
+// v1→v2 and v2→v3; the version without an outgoing converter is the one
+// the registered implementation serves. Calls to VersionedKey(key, from)
+// are converted automatically.
+func (r *Registry) RegisterConverter(key, from, to string, conv Converter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.versions == nil {
+		r.versions = make(map[string]map[string]versionStep)
+	}
+	if r.versions[key] == nil {
+		r.versions[key] = make(map[string]versionStep)
+	}
+	r.versions[key][from] = versionStep{to: to, conv: conv}
+}
+
+// Versions returns the versions of key that can be called, oldest first in
+// conversion order, ending with the implemented version.
+func (r *Registry) Versions(key string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	steps := r.versions[key]
+	targets := make(map[string]bool, len(steps))
+	for _, s := range steps {
+		targets[s.to] = true
+	}
+	var roots []string
+	for from := range steps {
+		if !targets[from] {
+			roots = append(roots, from)
+		}
+	}
+	sort.Strings(roots)
+
+	var out []string
+	seen := make(map[string]bool)
+	for _, v := range roots {
+		for v != "" && !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+			v = steps[v].to
+		}
+	}
+	return out
+}
+
+// conversions returns the converters from version to the implemented
+// version of key, and whether version is known at all.
+func (r *Registry) conversions(key, version string) ([]Converter, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	steps := r.versions[key]
+	var convs []Converter
+	for v := version; ; {
+		s, ok := steps[v]
+		if !ok {
+			break
+		}
+		convs = append(convs, s.conv)
+		v = s.to
+		if len(convs) > len(steps) {
+			return nil, false // cycle
+		}
+	}
+	if len(convs) > 0 {
+		return convs, true
+	}
+	for _, s := range steps {
+		if s.to == version {
+			return nil, true
+		}
+	}
+	return nil, false
+}
+
+func (r *Registry) callVersion(ctx context.Context, key, version string, req any) (any, error) {
+	convs, ok := r.conversions(key, version)
+	if !ok {
+		return nil, &Error{Code: NotFound, Key: key, Message: fmt.Sprintf("unknown version %s of %s", version, key)}
+	}
+
+	var err error
+	for _, c := range convs {
+		if c.Request == nil {
+			continue
+		}
+		if req, err = c.Request(req); err != nil {
+			return nil, &Error{Code: InvalidArgument, Key: key, Message: "convert request of " + VersionedKey(key, version), Err: err}
+		}
+	}
+
+	d := r.resolve(key)
+	res, err := r.callResolved(ctx, &d, req)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(convs) - 1; i >= 0; i-- {
+		if convs[i].Response == nil {
+			continue
+		}
+		if res, err = convs[i].Response(res); err != nil {
+			return nil, &Error{Code: Internal, Key: key, Message: "convert response of " + VersionedKey(key, version), Err: err}
+		}
+	}
+	return res, nil
+}
+
+func splitVersion(key string) (string, string, bool) {
+	return strings.Cut(key, "@")
+}
+
+// ContractVersionPrefix prefixes the metadata key advertising the version
+// of a service's contract a caller was compiled against, e.g.
+// "x-contract-version-Exam".
+const ContractVersionPrefix = "x-contract-version-"
+
+// WithContractVersion returns a copy of ctx advertising that its calls to
+// service use the given contract version. Such calls are served by the
+// implementation registered under the version's name if there is one, and
+// converted automatically otherwise. Calls made by service's own handlers
+// are not affected.
+func WithContractVersion(ctx context.Context, service, version string) context.Context {
+	return WithMetadataValue(ctx, ContractVersionPrefix+service, version)
+}
+
+// VersionMismatch describes a call made with a contract version that
+// differs from the implemented one.
+type VersionMismatch struct {
+	Key     string
+	Caller  string
+	Version string
+	Current string
+	// Converted reports whether converters bridged the difference. If not,
+	// the call was served by the implemented version as is.
+	Converted bool
+}
+
+// OnVersionMismatch registers fn to be called for every call advertising a
+// contract version other than the implemented one. By default
+// mismatches are logged with slog: converted calls at debug level, the
+// others as a warning, once per key and version.
+func (r *Registry) OnVersionMismatch(fn func(VersionMismatch)) {
+	r.mu.Lock()
+	r.onVersionMismatch = fn
+	r.mu.Unlock()
+}
+
+// negotiateVersion applies the contract version advertised by the caller
+// of d.key: an implementation registered under the version's name serves
+// the call directly, otherwise the returned version must be converted.
+func (r *Registry) negotiateVersion(ctx context.Context, d *dispatch) (string, bool) {
+	service, _ := SplitKey(d.key)
+	version, ok := MetadataValue(ctx, ContractVersionPrefix+service)
+	if !ok {
+		return "", false
+	}
+	// The chain of ctx may be shared by concurrent calls, so append to a
+	// copy.
+	caller := callerOf(ctx, append(slices.Clone(ChainFromContext(ctx)), d.key))
+	if caller == service {
+		return "", false
+	}
+
+	if im := findImpl(d.impls, version); im != nil {
+		d.route = func(ctx context.Context, _ []*impl) (context.Context, *impl) { return ctx, im }
+		return "", false
+	}
+
+	_, known := r.conversions(d.key, version)
+	r.versionMismatch(VersionMismatch{Key: d.key, Caller: caller, Version: version, Converted: known})
+	return version, known
+}
+
+func (r *Registry) versionMismatch(m VersionMismatch) {
+	r.mu.RLock()
+	fn := r.onVersionMismatch
+	r.mu.RUnlock()
+
+	if all := r.Versions(m.Key); len(all) > 0 {
+		m.Current = all[len(all)-1]
+	}
+	if m.Version == m.Current {
+		return
+	}
+	if fn != nil {
+		fn(m)
+		return
+	}
+
+	if m.Converted {
+		slog.Debug("irpc: contract version converted", "key", m.Key, "caller", m.Caller, "version", m.Version, "current", m.Current)
+	} else if _, warned := r.versionWarned.LoadOrStore(VersionedKey(m.Key, m.Version), true); !warned {
+		slog.Warn("irpc: unknown contract version", "key", m.Key, "caller", m.Caller, "version", m.Version, "current", m.Current)
+	}
+}
@@ -0,0 +1,62 @@
+package irpc
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestUnknownVersionWarnedOnce(t *testing.T) {
+	var mu sync.Mutex
+	var logs bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&lockedWriter{mu: &mu, w: &logs}, nil)))
+	defer slog.SetDefault(prev)
+
+	r := NewRegistry(Config{})
+	r.Register("Exam.Get", func(ctx context.Context, req any) (any, error) { return "exam", nil })
+	r.RegisterConverter("Exam.Get", "v1", "v2", Converter{})
+	r.Register("Web.Home", func(ctx context.Context, req any) (any, error) {
+		var wg sync.WaitGroup
+		for _, version := range []string{"v8", "v9", "v8", "v9", "v9"} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _ = r.Call(WithContractVersion(ctx, "Exam", version), "Exam.Get", nil)
+			}()
+		}
+		wg.Wait()
+		return nil, nil
+	})
+
+	for range 3 {
+		if _, err := r.Call(context.Background(), "Web.Home", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if n := strings.Count(logs.String(), "unknown contract version"); n != 2 {
+		t.Errorf("logged %d warnings, want one per version:\n%s", n, logs.String())
+	}
+	for _, version := range []string{"version=v8", "version=v9"} {
+		if !strings.Contains(logs.String(), version) {
+			t.Errorf("no warning for %s:\n%s", version, logs.String())
+		}
+	}
+}
+
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  *bytes.Buffer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
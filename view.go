@@ -0,0 +1,192 @@
+package irpc
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+// View is implemented by the read-only views generated by cmd/irpcgen
+// with -views. A view only has getters, which return the views of the
+// fields whose type has one and deep copies, made with DeepCopy, of the
+// fields holding pointers, slices, maps or structs, so callers cannot
+// modify a response the implementation may cache or share.
+type View interface {
+	// Unview returns a copy of the viewed value that the caller owns.
+	Unview() any
+}
+
+type viewFactory struct {
+	view reflect.Type
+	fn   func(any) any
+}
+
+var viewFactories sync.Map // reflect.Type -> viewFactory
+
+// RegisterViewFactory registers the constructor of V, the read-only view
+// of T. It is called by the init function of views generated with
+// cmd/irpcgen.
+func RegisterViewFactory[T any, V View](factory func(T) V) {
+	viewFactories.Store(reflect.TypeFor[T](), viewFactory{
+		view: reflect.TypeFor[V](),
+		fn:   func(v any) any { return factory(v.(T)) },
+	})
+}
+
+// ReturnViews makes calls to keys matching pattern, using path.Match
+// syntax, return the read-only view of their response if one was
+// generated for its type, or for the type it points to:
+//
+//	//go:generate go run github.com/khunfloat/irpc/cmd/irpcgen -type ExamContract -views ExamContractRes
+//
+//	registry.ReturnViews("Exam.*")
+//	res, _ := registry.Call(ctx, "Exam.FindExamById", req)
+//	exam := res.(contract.ExamContractResView)
+//
+// It is a Transformer, so it sees the output of the transformers
+// registered before it. Generated clients, Bind and Caller still return
+// the contract's type, as a copy of the viewed value, unless Caller is
+// asked for the view type.
+func (r *Registry) ReturnViews(pattern string) error {
+	return r.Transform(pattern, func(ctx context.Context, key string, req, res any) (any, error) {
+		return viewOf(res), nil
+	})
+}
+
+// viewOf returns the view of v, or v if no view was generated for it.
+func viewOf(v any) any {
+	if v == nil {
+		return nil
+	}
+	t := reflect.TypeOf(v)
+	if f, ok := viewFactories.Load(t); ok {
+		return f.(viewFactory).fn(v)
+	}
+	if t.Kind() == reflect.Pointer {
+		if f, ok := viewFactories.Load(t.Elem()); ok {
+			rv := reflect.ValueOf(v)
+			if rv.IsNil() {
+				return v
+			}
+			return f.(viewFactory).fn(rv.Elem().Interface())
+		}
+	}
+	return v
+}
+
+// viewType returns the type of the view of t, or of the type t points to.
+func viewType(t reflect.Type) (reflect.Type, bool) {
+	if f, ok := viewFactories.Load(t); ok {
+		return f.(viewFactory).view, true
+	}
+	if t.Kind() == reflect.Pointer {
+		return viewType(t.Elem())
+	}
+	return nil, false
+}
+
+// isViewOf reports whether v is the view type of t.
+func isViewOf(v, t reflect.Type) bool {
+	view, ok := viewType(t)
+	return ok && view == v
+}
+
+// Unview returns a copy of the value viewed by res as a T, or a pointer to
+// it if T is a pointer type. Any other res is returned unchanged. Clients
+// generated by cmd/irpcgen use it so that ReturnViews does not break them.
+func Unview[T any](res any) any {
+	return unviewAs(res, reflect.TypeFor[T]())
+}
+
+func unviewAs(res any, t reflect.Type) any {
+	v, ok := res.(View)
+	if !ok {
+		return res
+	}
+	u := v.Unview()
+	if t.Kind() == reflect.Pointer && reflect.TypeOf(u) == t.Elem() {
+		p := reflect.New(t.Elem())
+		p.Elem().Set(reflect.ValueOf(u))
+		return p.Interface()
+	}
+	return u
+}
+
+// DeepCopy returns a copy of v that shares no memory with it: pointers,
+// slices, maps and interfaces are followed and what they refer to copied,
+// and so are the exported fields of structs, recursively. Unexported
+// fields, funcs and channels are copied as they are. Views generated by
+// cmd/irpcgen use it for their getters and Unview.
+func DeepCopy[T any](v T) T {
+	var c T
+	deepCopy(reflect.ValueOf(&c).Elem(), reflect.ValueOf(&v).Elem(), make(map[copiedPointer]reflect.Value))
+	return c
+}
+
+// copiedPointer identifies a pointer already copied by deepCopy, so that
+// shared and cyclic pointers stay shared and cyclic in the copy.
+type copiedPointer struct {
+	p uintptr
+	t reflect.Type
+}
+
+// deepCopy copies src into dst, which is settable and of the same type.
+func deepCopy(dst, src reflect.Value, seen map[copiedPointer]reflect.Value) {
+	switch src.Kind() {
+	case reflect.Pointer:
+		if src.IsNil() {
+			return
+		}
+		key := copiedPointer{src.Pointer(), src.Type()}
+		if p, ok := seen[key]; ok {
+			dst.Set(p)
+			return
+		}
+		p := reflect.New(src.Type().Elem())
+		seen[key] = p
+		deepCopy(p.Elem(), src.Elem(), seen)
+		dst.Set(p)
+	case reflect.Slice:
+		if src.IsNil() {
+			return
+		}
+		s := reflect.MakeSlice(src.Type(), src.Len(), src.Len())
+		for i := range src.Len() {
+			deepCopy(s.Index(i), src.Index(i), seen)
+		}
+		dst.Set(s)
+	case reflect.Array:
+		for i := range src.Len() {
+			deepCopy(dst.Index(i), src.Index(i), seen)
+		}
+	case reflect.Map:
+		if src.IsNil() {
+			return
+		}
+		m := reflect.MakeMapWithSize(src.Type(), src.Len())
+		for it := src.MapRange(); it.Next(); {
+			k := reflect.New(src.Type().Key()).Elem()
+			deepCopy(k, it.Key(), seen)
+			e := reflect.New(src.Type().Elem()).Elem()
+			deepCopy(e, it.Value(), seen)
+			m.SetMapIndex(k, e)
+		}
+		dst.Set(m)
+	case reflect.Interface:
+		if src.IsNil() {
+			return
+		}
+		e := reflect.New(src.Elem().Type()).Elem()
+		deepCopy(e, src.Elem(), seen)
+		dst.Set(e)
+	case reflect.Struct:
+		dst.Set(src)
+		for i := range src.NumField() {
+			if f := dst.Field(i); f.CanSet() {
+				deepCopy(f, src.Field(i), seen)
+			}
+		}
+	default:
+		dst.Set(src)
+	}
+}
@@ -0,0 +1,70 @@
+package irpc
+
+import (
+	"reflect"
+	"testing"
+)
+
+type node struct {
+	Name     string
+	Tags     []string
+	Attrs    map[string][]int
+	Parent   *node
+	Children []*node
+	Any      any
+	Grid     [2][]int
+	secret   *int
+}
+
+func TestDeepCopy(t *testing.T) {
+	n := 7
+	root := &node{Name: "root", Tags: []string{"a"}, secret: &n}
+	child := &node{
+		Name:   "child",
+		Tags:   []string{"b", "c"},
+		Attrs:  map[string][]int{"k": {1, 2}},
+		Parent: root,
+		Any:    []int{3},
+		Grid:   [2][]int{{4}, {5}},
+	}
+	root.Children = []*node{child, child}
+
+	c := DeepCopy(root)
+	if !reflect.DeepEqual(c, root) {
+		t.Fatalf("copy differs: %+v", c)
+	}
+
+	c.Tags[0] = "changed"
+	cc := c.Children[0]
+	cc.Tags[0] = "changed"
+	cc.Attrs["k"][0] = 0
+	cc.Any.([]int)[0] = 0
+	cc.Grid[1][0] = 0
+	if root.Tags[0] != "a" || child.Tags[0] != "b" || child.Attrs["k"][0] != 1 || child.Any.([]int)[0] != 3 || child.Grid[1][0] != 5 {
+		t.Fatalf("modifying the copy changed the original: %+v", child)
+	}
+
+	if c.Children[1] != cc {
+		t.Error("pointers shared in the original are not shared in the copy")
+	}
+	if cc.Parent != c {
+		t.Error("cycle through Parent not preserved")
+	}
+	if c.secret != root.secret {
+		t.Error("unexported field was not copied as is")
+	}
+}
+
+func TestDeepCopyNil(t *testing.T) {
+	var n *node
+	if DeepCopy(n) != nil {
+		t.Fatal("copy of a nil pointer is not nil")
+	}
+	if c := DeepCopy(node{}); c.Tags != nil || c.Attrs != nil {
+		t.Fatalf("nil slices and maps became %+v", c)
+	}
+	var v any
+	if DeepCopy(v) != nil {
+		t.Fatal("copy of a nil interface is not nil")
+	}
+}
@@ -0,0 +1,228 @@
+package irpc
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Watchdog configures the detection of stuck calls: handlers still running
+// well after the deadline of their context, usually because they ignore
+// cancellation.
+type Watchdog struct {
+	// Grace is how long past its deadline a call may run before it is
+	// reported. Zero means one second.
+	Grace time.Duration
+	// Interval is how often running calls are checked. Zero means Grace.
+	Interval time.Duration
+	// OnStuck is called once per stuck call, from the watchdog goroutine.
+	OnStuck func(StuckCall)
+}
+
+// StuckCall describes a call running past its deadline.
+type StuckCall struct {
+	Key      string
+	Chain    Chain
+	Started  time.Time
+	Deadline time.Time
+	Overdue  time.Duration
+	// Stack is the stack of the goroutine running the handler, when it
+	// could be captured.
+	Stack string
+}
+
+type watchdog struct {
+	config Watchdog
+	stop   chan struct{}
+
+	mu    sync.Mutex
+	calls map[*watchedCall]struct{}
+}
+
+type watchedCall struct {
+	key       string
+	chain     Chain
+	started   time.Time
+	deadline  time.Time
+	goroutine string
+	reported  bool
+}
+
+// StartWatchdog starts reporting stuck calls as configured by w, replacing
+// any running watchdog. Only calls whose context has a deadline are
+// watched. It stops on Shutdown or when the returned func is called.
+func (r *Registry) StartWatchdog(w Watchdog) (stop func()) {
+	if w.Grace <= 0 {
+		w.Grace = time.Second
+	}
+	if w.Interval <= 0 {
+		w.Interval = w.Grace
+	}
+	wd := &watchdog{config: w, stop: make(chan struct{}), calls: make(map[*watchedCall]struct{})}
+
+	r.mu.Lock()
+	if r.watchdog != nil {
+		r.watchdog.close()
+	}
+	r.watchdog = wd
+	r.onShutdown(func(context.Context) error {
+		wd.close()
+		return nil
+	})
+	r.mu.Unlock()
+
+	go wd.run()
+	return func() {
+		r.mu.Lock()
+		if r.watchdog == wd {
+			r.watchdog = nil
+		}
+		r.mu.Unlock()
+		wd.close()
+	}
+}
+
+// StuckCalls returns the calls currently running past their deadline plus
+// the watchdog's grace period, e.g. to fail a health check while there
+// are any.
+func (r *Registry) StuckCalls() []StuckCall {
+	r.mu.RLock()
+	wd := r.watchdog
+	r.mu.RUnlock()
+
+	if wd == nil {
+		return nil
+	}
+	return wd.overdue(time.Now(), false)
+}
+
+func (w *watchdog) close() {
+	select {
+	case <-w.stop:
+	default:
+		close(w.stop)
+	}
+}
+
+func (w *watchdog) enter(ctx context.Context, key string) *watchedCall {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return nil
+	}
+	c := &watchedCall{
+		key:       key,
+		chain:     ChainFromContext(ctx),
+		started:   time.Now(),
+		deadline:  deadline,
+		goroutine: goroutineID(),
+	}
+	w.mu.Lock()
+	w.calls[c] = struct{}{}
+	w.mu.Unlock()
+	return c
+}
+
+func (w *watchdog) leave(c *watchedCall) {
+	if c == nil {
+		return
+	}
+	w.mu.Lock()
+	delete(w.calls, c)
+	w.mu.Unlock()
+}
+
+func (w *watchdog) run() {
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case now := <-ticker.C:
+			if w.config.OnStuck == nil {
+				continue
+			}
+			for _, c := range w.overdue(now, true) {
+				w.config.OnStuck(c)
+			}
+		}
+	}
+}
+
+// overdue returns the stuck calls. With report set, it only returns the
+// ones not reported yet, with their stacks, and marks them reported.
+func (w *watchdog) overdue(now time.Time, report bool) []StuckCall {
+	var stuck []StuckCall
+	var goroutines []string
+
+	w.mu.Lock()
+	for c := range w.calls {
+		overdue := now.Sub(c.deadline)
+		if overdue < w.config.Grace || (report && c.reported) {
+			continue
+		}
+		if report {
+			c.reported = true
+		}
+		stuck = append(stuck, StuckCall{
+			Key:      c.key,
+			Chain:    c.chain,
+			Started:  c.started,
+			Deadline: c.deadline,
+			Overdue:  overdue,
+		})
+		goroutines = append(goroutines, c.goroutine)
+	}
+	w.mu.Unlock()
+
+	if report && len(stuck) > 0 {
+		stacks := goroutineStacks()
+		for i := range stuck {
+			stuck[i].Stack = stacks[goroutines[i]]
+		}
+	}
+	return stuck
+}
+
+// goroutineID returns the ID of the calling goroutine, as printed in
+// stack traces.
+func goroutineID() string {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		return string(b[:i])
+	}
+	return ""
+}
+
+// goroutineStacks returns the stack of every goroutine, by ID.
+func goroutineStacks() map[string]string {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	stacks := make(map[string]string)
+	for _, block := range bytes.Split(buf, []byte("\n\n")) {
+		rest, ok := bytes.CutPrefix(block, []byte("goroutine "))
+		if !ok {
+			continue
+		}
+		if i := bytes.IndexByte(rest, ' '); i > 0 {
+			if _, err := strconv.Atoi(string(rest[:i])); err == nil {
+				stacks[string(rest[:i])] = string(block)
+			}
+		}
+	}
+	return stacks
+}
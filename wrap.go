@@ -0,0 +1,54 @@
+package irpc
+
+import (
+	"errors"
+	"strings"
+)
+
+// HandlerError attributes an error returned by a handler to the key that
+// produced it and the call chain that led there. It unwraps to the
+// original error, so errors.Is, errors.As and CodeOf see through it.
+//
+// An error is wrapped once, by the innermost call that returned it.
+type HandlerError struct {
+	Key   string
+	Chain Chain
+	Err   error
+	// Owner is the info of Key, as ServiceInfo returns it, so that the
+	// error says who to route it to.
+	Owner ServiceInfo
+}
+
+func (e *HandlerError) Error() string {
+	s := "irpc: " + e.Key
+	if len(e.Chain) > 1 {
+		s += " (call chain: " + e.Chain.String() + ")"
+	}
+	s += ": " + strings.TrimPrefix(e.Err.Error(), "irpc: ")
+	// A Provenance detail, as of a panic, names the owner already.
+	if _, ok := ErrorDetail[Provenance](e.Err); !ok {
+		if owner := e.Owner.owner(); owner != "" {
+			s += " (" + owner + ")"
+		}
+	}
+	return s
+}
+
+func (e *HandlerError) Unwrap() error {
+	return e.Err
+}
+
+func wrapHandlerError(key string, calls Chain, err error, owner *ServiceInfo) error {
+	if err == nil {
+		return nil
+	}
+	var he *HandlerError
+	if errors.As(err, &he) {
+		return err
+	}
+	he = &HandlerError{Key: key, Chain: calls, Err: err}
+	if owner != nil {
+		he.Owner = *owner
+	}
+	return he
+}